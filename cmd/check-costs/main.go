@@ -0,0 +1,112 @@
+// check-costs estimates the worst case CEL cost of every expression of every policy in the library against the
+// schemas of the matched kinds, prints the most expensive expressions and the total of every policy, and exits with an
+// error if an expression exceeds the fraction of the per-expression limit of the API server or a policy exceeds the
+// fraction of the per-request budget. Lists, maps and strings are bounded by -max-items and -max-length, 0 bounds them
+// by the maximum request size only, like the API server does.
+//
+//	go run ./cmd/check-costs
+//	go run ./cmd/check-costs -fraction 0.25 -max-items 500
+//	go run ./cmd/check-costs -max-items 0 -max-length 0 -top 20
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"vap-library/testutils"
+
+	"sigs.k8s.io/e2e-framework/klient/decoder"
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+)
+
+func main() {
+	root := flag.String("root", ".", "root directory of the repository")
+	fraction := flag.Float64("fraction", 0.5, "fail if a cost exceeds this fraction of the limit of the API server")
+	maxItems := flag.Int64("max-items", 100, "maximum number of elements of every list and map, 0 for no bound")
+	maxLength := flag.Int64("max-length", 1024, "maximum length of every string, 0 for no bound")
+	top := flag.Int("top", 10, "number of the most expensive expressions to print")
+	flag.Parse()
+
+	objects, err := loadObjects(*root)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	costs, err := testutils.EstimatePolicyCosts(objects, testutils.CostOptions{MaxItems: *maxItems, MaxLength: *maxLength})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if err := report(costs, *top); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if err := testutils.CheckPolicyCosts(costs, *fraction); err != nil {
+		fmt.Println()
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+// loadObjects decodes the policies, the parameter CRDs and the vendored CRDs of the custom resources
+func loadObjects(root string) ([]k8s.Object, error) {
+	var patterns = []string{
+		"policies/*/policy.yaml",
+		"policies/*/crd-parameter.yaml",
+		"vendoring/*/*.yaml",
+	}
+
+	var objects []k8s.Object
+	for _, pattern := range patterns {
+		files, err := filepath.Glob(filepath.Join(root, pattern))
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			objs, err := decoder.DecodeAllFiles(context.Background(), os.DirFS(filepath.Dir(file)), filepath.Base(file))
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode %s: %w", file, err)
+			}
+			objects = append(objects, objs...)
+		}
+	}
+	return objects, nil
+}
+
+// report prints the most expensive expressions and the total of every policy
+func report(costs []testutils.PolicyCost, top int) error {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "EXPRESSION\tKIND\tCOST\tLIMIT %d\n", testutils.ExpressionCostLimit)
+	for _, expression := range testutils.MostExpensiveExpressions(costs, top) {
+		fmt.Fprintf(tw, "%s %s\t%s\t%s\t%s\n", expression.Policy, expression.FieldRef, expression.Kind, formatCost(expression.Cost), formatFraction(expression.Fraction()))
+	}
+	fmt.Fprintln(tw)
+	fmt.Fprintf(tw, "POLICY\tKIND\tTOTAL\tBUDGET %d\n", testutils.PolicyCostLimit)
+	for _, policy := range costs {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", policy.Name, policy.Kind, formatCost(policy.Total), formatFraction(policy.Fraction()))
+	}
+	return tw.Flush()
+}
+
+// formatCost formats a cost, unbounded costs are shown as such
+func formatCost(cost uint64) string {
+	if cost == math.MaxUint64 {
+		return "unbounded"
+	}
+	return fmt.Sprintf("%d", cost)
+}
+
+// formatFraction formats a fraction of a limit as a percentage
+func formatFraction(fraction float64) string {
+	if fraction >= 100 {
+		return ">10000%"
+	}
+	return fmt.Sprintf("%.1f%%", fraction*100)
+}
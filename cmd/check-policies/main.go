@@ -0,0 +1,105 @@
+// check-policies type checks the CEL expressions of every policy in the library in one pass and exits with an error
+// if any policy has type checking warnings. By default the policies are checked in-process, with -kubeconfig (or
+// VAPLIB_KUBECONFIG) they are created on the cluster and the warnings of the API server are reported.
+//
+//	go run ./cmd/check-policies
+//	go run ./cmd/check-policies -kubeconfig /tmp/vaplibtest.kubeconfig
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"vap-library/testutils"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	"sigs.k8s.io/e2e-framework/klient/decoder"
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+)
+
+func main() {
+	root := flag.String("root", ".", "root directory of the repository")
+	kubeconfig := flag.String("kubeconfig", os.Getenv(testutils.KubeconfigEnvVar), "kubeconfig of a cluster to type check the policies on")
+	flag.Parse()
+
+	objects, err := loadObjects(*root)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	var warnings map[string][]admissionregistrationv1.ExpressionWarning
+	if *kubeconfig != "" {
+		timeout, err := testutils.ReadinessTimeout()
+		if err == nil {
+			warnings, err = testutils.TypeCheckPoliciesOnCluster(context.Background(), envconf.NewWithKubeConfig(*kubeconfig), objects, timeout)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	} else {
+		warnings, err = testutils.TypeCheckPolicies(objects)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
+	if !report(objects, warnings) {
+		os.Exit(1)
+	}
+}
+
+// loadObjects decodes the policies, the parameter CRDs and the vendored CRDs of the custom resources
+func loadObjects(root string) ([]k8s.Object, error) {
+	var patterns = []string{
+		"policies/*/policy.yaml",
+		"policies/*/crd-parameter.yaml",
+		"vendoring/*/*.yaml",
+	}
+
+	var objects []k8s.Object
+	for _, pattern := range patterns {
+		files, err := filepath.Glob(filepath.Join(root, pattern))
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			objs, err := decoder.DecodeAllFiles(context.Background(), os.DirFS(filepath.Dir(file)), filepath.Base(file))
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode %s: %w", file, err)
+			}
+			objects = append(objects, objs...)
+		}
+	}
+	return objects, nil
+}
+
+// report prints the result of every policy and returns false if any policy has warnings
+func report(objects []k8s.Object, warnings map[string][]admissionregistrationv1.ExpressionWarning) bool {
+	var names []string
+	for _, obj := range objects {
+		if obj.GetObjectKind().GroupVersionKind().Kind == "ValidatingAdmissionPolicy" {
+			names = append(names, obj.GetName())
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if len(warnings[name]) == 0 {
+			fmt.Printf("ok    %s\n", name)
+			continue
+		}
+		fmt.Printf("FAIL  %s\n", name)
+		for _, w := range warnings[name] {
+			fmt.Printf("      %s: %s\n", w.FieldRef, w.Warning)
+		}
+	}
+	fmt.Printf("%d policies, %d with warnings\n", len(names), len(warnings))
+	return len(warnings) == 0
+}
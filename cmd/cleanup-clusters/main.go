@@ -0,0 +1,41 @@
+// cleanup-clusters deletes the Kind clusters that the tests leaked, e.g. when the test binary was killed or timed out.
+// Only the clusters with the vaplibtest prefix whose run ID file is older than -older-than are deleted, the clusters of
+// other tools and of the runs in progress are kept. Clusters without a run ID file (from another machine user or an
+// older version of testutils) are only deleted with -include-unlabelled.
+//
+//	go run ./cmd/cleanup-clusters
+//	go run ./cmd/cleanup-clusters -older-than 30m -dry-run
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+	"vap-library/testutils"
+)
+
+func main() {
+	olderThan := flag.Duration("older-than", 2*time.Hour, "minimum age of the clusters to delete")
+	includeUnlabelled := flag.Bool("include-unlabelled", false, "also delete the vaplibtest clusters that have no run ID file")
+	dryRun := flag.Bool("dry-run", false, "only print the clusters that would be deleted")
+	flag.Parse()
+
+	deleted, err := testutils.CleanupClusters(context.Background(), testutils.CleanupOptions{
+		OlderThan:         *olderThan,
+		IncludeUnlabelled: *includeUnlabelled,
+		DryRun:            *dryRun,
+	})
+	for _, cluster := range deleted {
+		if *dryRun {
+			fmt.Printf("would delete %s\n", cluster)
+		} else {
+			fmt.Printf("deleted %s\n", cluster)
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
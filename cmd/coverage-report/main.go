@@ -0,0 +1,53 @@
+// coverage-report prints the CEL coverage of the policies that the in-process backend recorded with
+// VAPLIB_COVERAGE_DIR: for every validation of every policy how many test objects reached it and how many it allowed
+// and denied, followed by the validations that never denied a test object.
+//
+//	VAPLIB_TEST_BACKEND=inprocess VAPLIB_COVERAGE_DIR=/tmp/vaplib-coverage go test ./policies/...
+//	go run ./cmd/coverage-report -dir /tmp/vaplib-coverage
+//	go run ./cmd/coverage-report -dir /tmp/vaplib-coverage -format json
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"vap-library/testutils"
+)
+
+func main() {
+	dir := flag.String("dir", os.Getenv(testutils.CoverageDirEnvVar), "directory with the coverage files")
+	format := flag.String("format", "text", "output format: text or json")
+	failUnexercised := flag.Bool("fail-unexercised", false, "exit with an error if a validation never denied a test object")
+	flag.Parse()
+
+	if *dir == "" {
+		fmt.Fprintf(os.Stderr, "-dir or %s is required\n", testutils.CoverageDirEnvVar)
+		os.Exit(2)
+	}
+
+	report, err := testutils.LoadCoverage(*dir)
+	if err == nil && len(report.Policies) == 0 {
+		err = fmt.Errorf("no coverage files found in %s", *dir)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	switch *format {
+	case "text":
+		err = report.WriteText(os.Stdout)
+	case "json":
+		err = report.WriteJSON(os.Stdout)
+	default:
+		err = fmt.Errorf("unknown format %q", *format)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if *failUnexercised && len(report.Unexercised()) > 0 {
+		os.Exit(1)
+	}
+}
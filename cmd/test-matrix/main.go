@@ -0,0 +1,210 @@
+// test-matrix runs the policy tests once for every Kubernetes version of the matrix and prints a summary of the
+// results per version. Every run gets its own Kind clusters with the node image of the version.
+//
+//	go run ./cmd/test-matrix -versions v1.30.13,v1.34.0 -- -p 2 ./policies/...
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"vap-library/testutils"
+)
+
+// defaultVersions are the Kubernetes versions that the library supports, the first one is the minimum
+const defaultVersions = "v1.30.13,v1.31.9,v1.32.5,v1.33.1,v1.34.0"
+
+// testEvent is an event of `go test -json` (see `go doc test2json`)
+type testEvent struct {
+	Action  string
+	Package string
+	Test    string
+	Output  string
+	Elapsed float64
+}
+
+// packageResult is the result of the tests of a policy package on one version
+type packageResult struct {
+	passed  int
+	failed  int
+	skipped int
+	// status is the result of the package itself: pass, fail or skip
+	status  string
+	elapsed time.Duration
+}
+
+// versionResult is the result of a run of the tests on one version
+type versionResult struct {
+	version  string
+	packages map[string]*packageResult
+	err      error
+}
+
+func main() {
+	versions := flag.String("versions", envOrDefault("VAPLIB_KIND_VERSIONS", defaultVersions), "comma separated list of Kubernetes versions or Kind node images")
+	verbose := flag.Bool("v", false, "print the output of the tests")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [-- go test arguments]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	// the tests would run on the existing cluster instead of a Kind cluster of every version
+	if os.Getenv(testutils.KubeconfigEnvVar) != "" {
+		fmt.Fprintf(os.Stderr, "%s is set, unset it to test the versions on their own Kind clusters\n", testutils.KubeconfigEnvVar)
+		os.Exit(2)
+	}
+
+	testArgs := flag.Args()
+	if len(testArgs) == 0 {
+		testArgs = []string{"./policies/..."}
+	}
+
+	var results []versionResult
+	for _, version := range strings.Split(*versions, ",") {
+		version = strings.TrimSpace(version)
+		if version == "" {
+			continue
+		}
+		fmt.Printf("=== Testing against %s\n", version)
+		results = append(results, run(version, testArgs, *verbose))
+	}
+
+	if !printSummary(os.Stdout, results) {
+		os.Exit(1)
+	}
+}
+
+// run runs `go test -json` with the version and collects the results per package
+func run(version string, testArgs []string, verbose bool) versionResult {
+	result := versionResult{version: version, packages: map[string]*packageResult{}}
+
+	cmd := exec.Command("go", append([]string{"test", "-count=1", "-json"}, testArgs...)...)
+	cmd.Env = append(os.Environ(), testutils.KindVersionEnvVar+"="+version)
+	cmd.Stderr = os.Stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		result.err = err
+		return result
+	}
+	if err := cmd.Start(); err != nil {
+		result.err = err
+		return result
+	}
+
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		event := testEvent{}
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			// not an event, e.g. a build error
+			fmt.Println(scanner.Text())
+			continue
+		}
+		if verbose && event.Action == "output" {
+			fmt.Print(event.Output)
+		}
+		record(result.packages, event)
+	}
+
+	// go test exits with an error if a test failed, that is reported in the summary
+	if err := cmd.Wait(); err != nil && !failed(result.packages) {
+		result.err = err
+	}
+	return result
+}
+
+// record adds the result of the event to the package
+func record(packages map[string]*packageResult, event testEvent) {
+	if event.Package == "" {
+		return
+	}
+	pkg, ok := packages[event.Package]
+	if !ok {
+		pkg = &packageResult{}
+		packages[event.Package] = pkg
+	}
+
+	if event.Test == "" {
+		switch event.Action {
+		case "pass", "fail", "skip":
+			pkg.status = event.Action
+			pkg.elapsed = time.Duration(event.Elapsed * float64(time.Second))
+		}
+		return
+	}
+	// only the top level tests are counted, the features and assessments are subtests
+	if strings.Contains(event.Test, "/") {
+		return
+	}
+	switch event.Action {
+	case "pass":
+		pkg.passed++
+	case "fail":
+		pkg.failed++
+	case "skip":
+		pkg.skipped++
+	}
+}
+
+// failed returns true if a package failed
+func failed(packages map[string]*packageResult) bool {
+	for _, pkg := range packages {
+		if pkg.status == "fail" || pkg.failed > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// printSummary prints the results per version and package and returns false if anything failed
+func printSummary(w io.Writer, results []versionResult) bool {
+	ok := true
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "\nVERSION\tPACKAGE\tRESULT\tPASSED\tFAILED\tSKIPPED\tTIME")
+	for _, result := range results {
+		if result.err != nil {
+			ok = false
+			fmt.Fprintf(tw, "%s\t\terror: %s\t\t\t\t\n", result.version, result.err)
+		}
+
+		names := make([]string, 0, len(result.packages))
+		for name := range result.packages {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		passed := 0
+		for _, name := range names {
+			pkg := result.packages[name]
+			status := pkg.status
+			if status == "" {
+				status = "fail"
+			}
+			if status == "fail" {
+				ok = false
+			} else {
+				passed++
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\t%d\t%s\n", result.version, name, status, pkg.passed, pkg.failed, pkg.skipped, pkg.elapsed.Round(time.Second))
+		}
+		fmt.Fprintf(tw, "%s\tTOTAL\t%d/%d packages passed\t\t\t\t\n", result.version, passed, len(names))
+	}
+	_ = tw.Flush()
+	return ok
+}
+
+func envOrDefault(name string, defaultValue string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,160 @@
+// test-report renders the test reports that CreateTestEnv writes to VAPLIB_REPORT_DIR as a Markdown compatibility
+// table: one row per policy, one column per Kubernetes version, and the passed assess steps in every cell. The failed
+// steps are listed below the table.
+//
+//	VAPLIB_REPORT_DIR=/tmp/vaplib-report go run ./cmd/test-matrix
+//	go run ./cmd/test-report -dir /tmp/vaplib-report
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"vap-library/testutils"
+
+	"k8s.io/apimachinery/pkg/util/version"
+)
+
+func main() {
+	dir := flag.String("dir", os.Getenv(testutils.ReportDirEnvVar), "directory with the JSON reports")
+	flag.Parse()
+
+	if *dir == "" {
+		fmt.Fprintf(os.Stderr, "-dir or %s is required\n", testutils.ReportDirEnvVar)
+		os.Exit(2)
+	}
+
+	reports, err := testutils.LoadReports(*dir)
+	if err == nil && len(reports) == 0 {
+		err = fmt.Errorf("no reports found in %s", *dir)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if err := render(os.Stdout, reports); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// render writes the compatibility table and the failed steps
+func render(w io.Writer, reports []testutils.TestReport) error {
+	cells := map[string]map[string]testutils.TestReport{}
+	var policies, columns []string
+	for _, report := range reports {
+		column := columnName(report)
+		if _, ok := cells[report.Policy]; !ok {
+			cells[report.Policy] = map[string]testutils.TestReport{}
+			policies = append(policies, report.Policy)
+		}
+		if !contains(columns, column) {
+			columns = append(columns, column)
+		}
+		cells[report.Policy][column] = report
+	}
+	sort.Strings(policies)
+	sort.SliceStable(columns, func(i, j int) bool { return lessVersion(columns[i], columns[j]) })
+
+	fmt.Fprintf(w, "| Policy | %s |\n", strings.Join(columns, " | "))
+	fmt.Fprintf(w, "|---|%s\n", strings.Repeat("---|", len(columns)))
+	for _, policy := range policies {
+		row := []string{policy}
+		for _, column := range columns {
+			report, ok := cells[policy][column]
+			switch {
+			case !ok:
+				row = append(row, "-")
+			case report.Passed() == len(report.Steps):
+				row = append(row, fmt.Sprintf("✅ %d/%d", report.Passed(), len(report.Steps)))
+			default:
+				row = append(row, fmt.Sprintf("❌ %d/%d", report.Passed(), len(report.Steps)))
+			}
+		}
+		fmt.Fprintf(w, "| %s |\n", strings.Join(row, " | "))
+	}
+
+	var failures []string
+	for _, policy := range policies {
+		for _, column := range columns {
+			report, ok := cells[policy][column]
+			if !ok {
+				continue
+			}
+			for _, step := range report.Steps {
+				if step.Passed {
+					continue
+				}
+				name := strings.Join(nonEmpty(step.Test, step.Feature, step.Assess), "/")
+				for _, check := range step.Checks {
+					if check.Expected == check.Actual {
+						continue
+					}
+					failure := fmt.Sprintf("- %s on %s: `%s`: %s expected %s, got %s", policy, column, name, check.Kind, check.Expected, check.Actual)
+					if check.Message != "" {
+						failure += ": " + check.Message
+					}
+					failures = append(failures, failure)
+				}
+				if allMatch(step.Checks) {
+					failures = append(failures, fmt.Sprintf("- %s on %s: `%s` failed", policy, column, name))
+				}
+			}
+		}
+	}
+	if len(failures) > 0 {
+		fmt.Fprintf(w, "\nFailed steps:\n%s\n", strings.Join(failures, "\n"))
+	}
+	return nil
+}
+
+// columnName returns the column of the report, the Kubernetes version and the backend if it is not a cluster
+func columnName(report testutils.TestReport) string {
+	if report.Backend == testutils.BackendInProcess {
+		return report.KubernetesVersion + " (" + report.Backend + ")"
+	}
+	return report.KubernetesVersion
+}
+
+// lessVersion orders the columns by their Kubernetes version
+func lessVersion(a, b string) bool {
+	va, errA := version.ParseGeneric(strings.Fields(a)[0])
+	vb, errB := version.ParseGeneric(strings.Fields(b)[0])
+	if errA != nil || errB != nil || va.EqualTo(vb) {
+		return a < b
+	}
+	return va.LessThan(vb)
+}
+
+// allMatch returns true if every check has the expected outcome, i.e. the step failed for another reason
+func allMatch(checks []testutils.CheckReport) bool {
+	for _, check := range checks {
+		if check.Expected != check.Actual {
+			return false
+		}
+	}
+	return true
+}
+
+func nonEmpty(values ...string) []string {
+	var result []string
+	for _, value := range values {
+		if value != "" {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package benchmark
+
+import (
+	"log"
+	"os"
+	"testing"
+	"vap-library/testutils"
+
+	"sigs.k8s.io/e2e-framework/pkg/env"
+)
+
+// admission is the benchmark of the Pod Security Standards and resource policies, the policies can be changed with
+// VAPLIB_BENCHMARK_POLICIES
+var admission = &testutils.AdmissionBenchmark{
+	Policies: []string{
+		"pss-capabilities",
+		"pss-privilege-escalation",
+		"pss-running-as-non-root",
+		"pss-running-as-non-root-user",
+		"pss-seccomp",
+		"pss-volume-types",
+		"resource-limit-types",
+		"resource-request-types",
+	},
+	Parameters: map[string]string{
+		"resource-limit-types": `
+apiVersion: vap-library.com/v1beta1
+kind: VAPLibResourceLimitTypesParam
+metadata:
+  name: resource-limit-types.vap-library.com
+  namespace: %s
+spec:
+  enforcedResourceLimitTypes:
+  - cpu
+  - memory
+  - ephemeral-storage
+`,
+		"resource-request-types": `
+apiVersion: vap-library.com/v1beta1
+kind: VAPLibResourceRequestTypesParam
+metadata:
+  name: resource-request-types.vap-library.com
+  namespace: %s
+spec:
+  enforcedResourceRequestTypes:
+  - cpu
+  - memory
+  - ephemeral-storage
+`,
+		"service-type": `
+apiVersion: vap-library.com/v1beta1
+kind: VAPLibServiceTypeParam
+metadata:
+  name: service-type.vap-library.com
+  namespace: %s
+spec:
+  allowedTypes:
+  - ClusterIP
+`,
+	},
+	Kinds: []string{"Pod", "Deployment"},
+}
+
+var testEnv env.Environment
+
+func TestMain(m *testing.M) {
+	var err error
+	testEnv, err = testutils.CreateBenchmarkEnv(admission)
+	if err != nil {
+		log.Fatalf("Unable to create Kind cluster for the benchmark. Error msg: %s", err)
+	}
+
+	os.Exit(testEnv.Run(m))
+}
+
+func BenchmarkAdmission(b *testing.B) {
+	admission.Run(b)
+}
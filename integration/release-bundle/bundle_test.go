@@ -0,0 +1,572 @@
+package release_bundle
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"slices"
+	"testing"
+	"vap-library/testutils"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/e2e-framework/pkg/env"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/features"
+	"sigs.k8s.io/yaml"
+)
+
+// the release bundle is installed with `kubectl apply -k`, the pattern matches the files of its kustomization.yaml
+// (bindings.yaml, crds.yaml and policies.yaml) but not the kustomization.yaml itself
+const (
+	releaseDir     = "../../release-process/release/"
+	releasePattern = "*s.yaml"
+)
+
+// enabledPolicies are enabled together on the namespace of every test, like a user would enable them for a namespace
+// of workloads
+var enabledPolicies = []string{
+	"no-default-sa-rolebinding",
+	"pss-capabilities",
+	"pss-privilege-escalation",
+	"pss-running-as-non-root",
+	"pss-running-as-non-root-user",
+	"pss-seccomp",
+	"pss-volume-types",
+	"resource-limit-types",
+	"resource-request-types",
+	"service-type",
+}
+
+// parameters of the enabled policies, the release bindings deny every request if the parameter is missing
+var parameterYAMLs = []string{`
+apiVersion: vap-library.com/v1beta1
+kind: VAPLibResourceLimitTypesParam
+metadata:
+  name: resource-limit-types.vap-library.com
+  namespace: %s
+spec:
+  enforcedResourceLimitTypes:
+  - cpu
+  - memory
+`, `
+apiVersion: vap-library.com/v1beta1
+kind: VAPLibResourceRequestTypesParam
+metadata:
+  name: resource-request-types.vap-library.com
+  namespace: %s
+spec:
+  enforcedResourceRequestTypes:
+  - cpu
+  - memory
+`, `
+apiVersion: vap-library.com/v1beta1
+kind: VAPLibServiceTypeParam
+metadata:
+  name: service-type.vap-library.com
+  namespace: %s
+spec:
+  allowedTypes:
+  - ClusterIP
+`}
+
+// violation is a change to a compliant workload that exactly one of the enabled policies must report
+type violation struct {
+	description string
+	policyName  string
+	message     string
+	apply       func(spec *v1.PodSpec)
+}
+
+var violations = []violation{
+	{
+		description: "a container that does not drop ALL capabilities",
+		policyName:  "pss-capabilities",
+		message:     "securityContext.capabilities.drop must include ALL",
+		apply: func(spec *v1.PodSpec) {
+			spec.Containers[0].SecurityContext.Capabilities.Drop = []v1.Capability{"NET_RAW"}
+		},
+	},
+	{
+		description: "a container that adds a capability other than NET_BIND_SERVICE",
+		policyName:  "pss-capabilities",
+		message:     "securityContext.capabilities.add can only include NET_BIND_SERVICE",
+		apply: func(spec *v1.PodSpec) {
+			spec.Containers[0].SecurityContext.Capabilities.Add = []v1.Capability{"SYS_ADMIN"}
+		},
+	},
+	{
+		description: "a container that allows privilege escalation",
+		policyName:  "pss-privilege-escalation",
+		message:     "securityContext.allowPrivilegeEscalation must be set to false",
+		apply: func(spec *v1.PodSpec) {
+			spec.Containers[0].SecurityContext.AllowPrivilegeEscalation = ptr.To(true)
+		},
+	},
+	{
+		description: "a container that may run as root",
+		policyName:  "pss-running-as-non-root",
+		message:     "securityContext.runAsNonRoot must be set to true",
+		apply: func(spec *v1.PodSpec) {
+			spec.Containers[0].SecurityContext.RunAsNonRoot = ptr.To(false)
+		},
+	},
+	{
+		description: "a container that runs as the root user id",
+		policyName:  "pss-running-as-non-root-user",
+		message:     "securityContext.runAsUser must not equal 0",
+		apply: func(spec *v1.PodSpec) {
+			spec.Containers[0].SecurityContext.RunAsUser = ptr.To[int64](0)
+		},
+	},
+	{
+		description: "a container with an Unconfined seccomp profile",
+		policyName:  "pss-seccomp",
+		message:     "securityContext.seccompProfile.type must be set to RuntimeDefault or Localhost",
+		apply: func(spec *v1.PodSpec) {
+			spec.Containers[0].SecurityContext.SeccompProfile = &v1.SeccompProfile{Type: v1.SeccompProfileTypeUnconfined}
+		},
+	},
+	{
+		description: "a hostPath volume",
+		policyName:  "pss-volume-types",
+		message:     "Every item in a spec.volumes[*] list (if present) must set one of the following fields",
+		apply: func(spec *v1.PodSpec) {
+			spec.Volumes = append(spec.Volumes, v1.Volume{
+				Name:         "host",
+				VolumeSource: v1.VolumeSource{HostPath: &v1.HostPathVolumeSource{Path: "/var/log"}},
+			})
+		},
+	},
+	{
+		description: "a container without a memory limit",
+		policyName:  "resource-limit-types",
+		message:     "spec.resources.limits must be present and contain every item",
+		apply: func(spec *v1.PodSpec) {
+			delete(spec.Containers[0].Resources.Limits, v1.ResourceMemory)
+		},
+	},
+	{
+		description: "an initContainer without a cpu request",
+		policyName:  "resource-request-types",
+		message:     "spec.resources.requests must be present and contain every item",
+		apply: func(spec *v1.PodSpec) {
+			delete(spec.InitContainers[0].Resources.Requests, v1.ResourceCPU)
+		},
+	},
+}
+
+// hardenedSecurityContext returns a container securityContext that sets every field of the restricted Pod Security
+// Standard
+func hardenedSecurityContext() *v1.SecurityContext {
+	return &v1.SecurityContext{
+		AllowPrivilegeEscalation: ptr.To(false),
+		Capabilities: &v1.Capabilities{
+			Drop: []v1.Capability{"ALL"},
+			Add:  []v1.Capability{"NET_BIND_SERVICE"},
+		},
+		ReadOnlyRootFilesystem: ptr.To(true),
+		RunAsNonRoot:           ptr.To(true),
+		RunAsUser:              ptr.To[int64](10001),
+		RunAsGroup:             ptr.To[int64](10001),
+		SeccompProfile:         &v1.SeccompProfile{Type: v1.SeccompProfileTypeRuntimeDefault},
+	}
+}
+
+// hardenedResources returns the resources of a container with the cpu and memory requests and limits
+func hardenedResources() v1.ResourceRequirements {
+	return v1.ResourceRequirements{
+		Requests: v1.ResourceList{
+			v1.ResourceCPU:    resource.MustParse("100m"),
+			v1.ResourceMemory: resource.MustParse("64Mi"),
+		},
+		Limits: v1.ResourceList{
+			v1.ResourceCPU:    resource.MustParse("500m"),
+			v1.ResourceMemory: resource.MustParse("128Mi"),
+		},
+	}
+}
+
+// hardenedPodSpec returns a pod spec that every enabled policy admits: an initContainer and a container with the
+// restricted securityContext, resource requests and limits and only the allowed volume types
+func hardenedPodSpec() v1.PodSpec {
+	return v1.PodSpec{
+		ServiceAccountName:           "app",
+		AutomountServiceAccountToken: ptr.To(false),
+		SecurityContext: &v1.PodSecurityContext{
+			RunAsNonRoot:   ptr.To(true),
+			RunAsUser:      ptr.To[int64](10001),
+			RunAsGroup:     ptr.To[int64](10001),
+			FSGroup:        ptr.To[int64](10001),
+			SeccompProfile: &v1.SeccompProfile{Type: v1.SeccompProfileTypeRuntimeDefault},
+		},
+		InitContainers: []v1.Container{{
+			Name:            "init",
+			Image:           "public.ecr.aws/docker/library/busybox:1.36",
+			Command:         []string{"sh", "-c", "cp /config/* /work/"},
+			SecurityContext: hardenedSecurityContext(),
+			Resources:       hardenedResources(),
+			VolumeMounts: []v1.VolumeMount{
+				{Name: "config", MountPath: "/config"},
+				{Name: "work", MountPath: "/work"},
+			},
+		}},
+		Containers: []v1.Container{{
+			Name:            "app",
+			Image:           "public.ecr.aws/docker/library/busybox:1.36",
+			Command:         []string{"sh", "-c", "sleep 3600"},
+			Ports:           []v1.ContainerPort{{Name: "http", ContainerPort: 8080}},
+			SecurityContext: hardenedSecurityContext(),
+			Resources:       hardenedResources(),
+			VolumeMounts: []v1.VolumeMount{
+				{Name: "work", MountPath: "/work"},
+				{Name: "token", MountPath: "/var/run/secrets/tokens", ReadOnly: true},
+			},
+		}},
+		Volumes: []v1.Volume{
+			{Name: "config", VolumeSource: v1.VolumeSource{ConfigMap: &v1.ConfigMapVolumeSource{LocalObjectReference: v1.LocalObjectReference{Name: "app"}}}},
+			{Name: "work", VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}}},
+			{Name: "token", VolumeSource: v1.VolumeSource{Projected: &v1.ProjectedVolumeSource{Sources: []v1.VolumeProjection{{
+				ServiceAccountToken: &v1.ServiceAccountTokenProjection{Path: "token", ExpirationSeconds: ptr.To[int64](3600)},
+			}}}}},
+		},
+	}
+}
+
+var appLabels = map[string]string{"app": "hardened"}
+
+// hardenedDeployment returns a Deployment of the pod spec
+func hardenedDeployment(name string, namespace string, spec v1.PodSpec) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: appLabels},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: ptr.To[int32](2),
+			Selector: &metav1.LabelSelector{MatchLabels: appLabels},
+			Template: v1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: appLabels}, Spec: spec},
+		},
+	}
+}
+
+// hardenedStatefulSet returns a StatefulSet of the pod spec with a volume claim template
+func hardenedStatefulSet(name string, namespace string, spec v1.PodSpec) *appsv1.StatefulSet {
+	spec.Containers[0].VolumeMounts = append(spec.Containers[0].VolumeMounts, v1.VolumeMount{Name: "data", MountPath: "/data"})
+	return &appsv1.StatefulSet{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "StatefulSet"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: appLabels},
+		Spec: appsv1.StatefulSetSpec{
+			Replicas:    ptr.To[int32](1),
+			ServiceName: name,
+			Selector:    &metav1.LabelSelector{MatchLabels: appLabels},
+			Template:    v1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: appLabels}, Spec: spec},
+			VolumeClaimTemplates: []v1.PersistentVolumeClaim{{
+				ObjectMeta: metav1.ObjectMeta{Name: "data"},
+				Spec: v1.PersistentVolumeClaimSpec{
+					AccessModes: []v1.PersistentVolumeAccessMode{v1.ReadWriteOnce},
+					Resources:   v1.VolumeResourceRequirements{Requests: v1.ResourceList{v1.ResourceStorage: resource.MustParse("1Gi")}},
+				},
+			}},
+		},
+	}
+}
+
+// hardenedCronJob returns a CronJob of the pod spec
+func hardenedCronJob(name string, namespace string, spec v1.PodSpec) *batchv1.CronJob {
+	spec.RestartPolicy = v1.RestartPolicyOnFailure
+	return &batchv1.CronJob{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "batch/v1", Kind: "CronJob"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: appLabels},
+		Spec: batchv1.CronJobSpec{
+			Schedule: "0 3 * * *",
+			JobTemplate: batchv1.JobTemplateSpec{Spec: batchv1.JobSpec{
+				Template: v1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: appLabels}, Spec: spec},
+			}},
+		},
+	}
+}
+
+// appService returns the Service of the workloads with the given type
+func appService(namespace string, serviceType v1.ServiceType) *v1.Service {
+	return &v1.Service{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Service"},
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: namespace, Labels: appLabels},
+		Spec: v1.ServiceSpec{
+			Type:     serviceType,
+			Selector: appLabels,
+			Ports:    []v1.ServicePort{{Name: "http", Port: 80, TargetPort: intstr.FromString("http")}},
+		},
+	}
+}
+
+// appRoleBinding returns a RoleBinding of the service account to the view ClusterRole
+func appRoleBinding(namespace string, serviceAccountName string) *rbacv1.RoleBinding {
+	return &rbacv1.RoleBinding{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "RoleBinding"},
+		ObjectMeta: metav1.ObjectMeta{Name: "app-view", Namespace: namespace},
+		RoleRef:    rbacv1.RoleRef{APIGroup: "rbac.authorization.k8s.io", Kind: "ClusterRole", Name: "view"},
+		Subjects:   []rbacv1.Subject{{Kind: "ServiceAccount", Name: serviceAccountName, Namespace: namespace}},
+	}
+}
+
+// toYAML returns the object as YAML for ApplyK8sResourceFromYAML
+func toYAML(t *testing.T, obj any) string {
+	t.Helper()
+	data, err := yaml.Marshal(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// enablePolicies sets the labels of the enabled policies on the namespace to the given mode
+func enablePolicies(ctx context.Context, cfg *envconf.Config, namespace string, mode string) error {
+	labels := map[string]string{}
+	for _, name := range enabledPolicies {
+		labels["vap-library.com/"+name] = mode
+	}
+	return testutils.AddNamespaceLabels(ctx, cfg, namespace, labels)
+}
+
+// applyParameters applies the parameters of the enabled policies to the namespace of the test
+func applyParameters(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+	namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
+
+	for _, parameterYAML := range parameterYAMLs {
+		if err := testutils.ApplyParameterFromYAML(ctx, cfg, fmt.Sprintf(parameterYAML, namespace)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return ctx
+}
+
+// expectOnlyWarning fails the test unless the request got exactly one warning and it is from the given policy
+func expectOnlyWarning(t *testing.T, warnings []string, policyName string, messageSubstring string) {
+	t.Helper()
+	testutils.ExpectWarned(t, warnings, fmt.Sprintf("'%s.vap-library.com'", policyName))
+	testutils.ExpectWarned(t, warnings, messageSubstring)
+	if len(warnings) != 1 {
+		t.Fatalf("expected only a warning of %s, got: %q", policyName, warnings)
+	}
+}
+
+var testEnv env.Environment
+
+func TestMain(m *testing.M) {
+	var namespaceLabels = map[string]string{}
+	for _, name := range enabledPolicies {
+		namespaceLabels["vap-library.com/"+name] = testutils.ModeDeny
+	}
+	var extraResourcesFromDir = map[string]string{releaseDir: releasePattern}
+
+	var err error
+	testEnv, err = testutils.CreateTestEnv("", false, namespaceLabels, extraResourcesFromDir, nil)
+	if err != nil {
+		log.Fatalf("Unable to create Kind cluster for test. Error msg: %s", err)
+	}
+
+	os.Exit(testEnv.Run(m))
+}
+
+func TestReleaseFiles(t *testing.T) {
+	data, err := os.ReadFile(releaseDir + "kustomization.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kustomization struct {
+		Resources []string `json:"resources"`
+	}
+	if err := yaml.Unmarshal(data, &kustomization); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := fs.Glob(os.DirFS(releaseDir), releasePattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(kustomization.Resources)
+	if !slices.Equal(files, kustomization.Resources) {
+		t.Fatalf("the tests apply %q from %s, but kustomization.yaml installs %q", files, releaseDir, kustomization.Resources)
+	}
+}
+
+func TestCompliantWorkloads(t *testing.T) {
+
+	f := features.New("Compliant workloads with every policy enabled").
+		Setup(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			return applyParameters(ctx, t, cfg)
+		}).
+		Assess("Successful deployment of a hardened Deployment", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// get namespace
+			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
+
+			// this should PASS!
+			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, toYAML(t, hardenedDeployment("hardened", namespace, hardenedPodSpec())))
+			testutils.ExpectAllowed(t, err)
+
+			return ctx
+		}).
+		Assess("Successful deployment of a hardened StatefulSet", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// get namespace
+			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
+
+			// this should PASS!
+			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, toYAML(t, hardenedStatefulSet("hardened", namespace, hardenedPodSpec())))
+			testutils.ExpectAllowed(t, err)
+
+			return ctx
+		}).
+		Assess("Successful deployment of a hardened CronJob", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// get namespace
+			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
+
+			// this should PASS!
+			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, toYAML(t, hardenedCronJob("hardened", namespace, hardenedPodSpec())))
+			testutils.ExpectAllowed(t, err)
+
+			return ctx
+		}).
+		Assess("Successful deployment of the ClusterIP Service and the RoleBinding of the workloads", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// get namespace
+			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
+
+			// this should PASS!
+			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, toYAML(t, appService(namespace, v1.ServiceTypeClusterIP)))
+			testutils.ExpectAllowed(t, err)
+
+			// this should PASS!
+			err = testutils.ApplyK8sResourceFromYAML(ctx, cfg, toYAML(t, appRoleBinding(namespace, "app")))
+			testutils.ExpectAllowed(t, err)
+
+			return ctx
+		}).
+		Assess("Successful deployment of the hardened Deployment without warnings when every policy is in warn mode", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// get namespace
+			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
+
+			if err := enablePolicies(ctx, cfg, namespace, testutils.ModeWarn); err != nil {
+				t.Fatal(err)
+			}
+
+			// this should PASS without warnings!
+			warnings, err := testutils.ApplyK8sResourceFromYAMLWithWarnings(ctx, cfg, toYAML(t, hardenedDeployment("hardened-warn", namespace, hardenedPodSpec())))
+			testutils.ExpectAllowed(t, err)
+			testutils.ExpectNoWarnings(t, warnings)
+
+			return ctx
+		}).
+		Feature()
+
+	testEnv.Test(t, f)
+}
+
+func TestWithoutParameters(t *testing.T) {
+
+	f := features.New("Workloads without the parameters of the enabled policies").
+		Assess("Rejected deployment of a hardened Deployment as the resource-limit-types parameter is missing", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// get namespace
+			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
+
+			// only the parameters of the other policies are applied
+			for _, parameterYAML := range parameterYAMLs[1:] {
+				if err := testutils.ApplyParameterFromYAML(ctx, cfg, fmt.Sprintf(parameterYAML, namespace)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// this should FAIL!
+			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, toYAML(t, hardenedDeployment("hardened", namespace, hardenedPodSpec())))
+			testutils.ExpectDenied(t, err, "resource-limit-types", testutils.NoParamsMessage)
+
+			return ctx
+		}).
+		Feature()
+
+	testEnv.Test(t, f)
+}
+
+func TestViolations(t *testing.T) {
+
+	denied := features.New("Violations are denied by the right policy").
+		Setup(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			return applyParameters(ctx, t, cfg)
+		})
+	for i, v := range violations {
+		denied = denied.Assess(fmt.Sprintf("Rejected deployment of a Deployment with %s", v.description), func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// get namespace
+			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
+
+			spec := hardenedPodSpec()
+			v.apply(&spec)
+
+			// this should FAIL!
+			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, toYAML(t, hardenedDeployment(fmt.Sprintf("violation-%d", i), namespace, spec)))
+			testutils.ExpectDenied(t, err, v.policyName, v.message)
+
+			return ctx
+		})
+	}
+	denied = denied.
+		Assess("Rejected deployment of a NodePort Service", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// get namespace
+			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
+
+			// this should FAIL!
+			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, toYAML(t, appService(namespace, v1.ServiceTypeNodePort)))
+			testutils.ExpectDenied(t, err, "service-type", "spec.type must be present and must be on the spec.allowedTypes list")
+
+			return ctx
+		}).
+		Assess("Rejected deployment of a RoleBinding of the default service account", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// get namespace
+			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
+
+			// this should FAIL!
+			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, toYAML(t, appRoleBinding(namespace, "default")))
+			testutils.ExpectDenied(t, err, "no-default-sa-rolebinding", "subjects cannot include the 'default' service account")
+
+			return ctx
+		})
+
+	testEnv.Test(t, denied.Feature())
+}
+
+func TestViolationsInWarnMode(t *testing.T) {
+
+	// in warn mode every policy reports its violations, so each violation must be reported by its policy only
+	warned := features.New("Violations are reported by the right policy only").
+		Setup(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// get namespace
+			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
+
+			if err := enablePolicies(ctx, cfg, namespace, testutils.ModeWarn); err != nil {
+				t.Fatal(err)
+			}
+			return applyParameters(ctx, t, cfg)
+		})
+	for i, v := range violations {
+		warned = warned.Assess(fmt.Sprintf("Deployment with %s is accepted with a warning of %s", v.description, v.policyName), func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// get namespace
+			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
+
+			spec := hardenedPodSpec()
+			v.apply(&spec)
+
+			// this should PASS with a warning!
+			warnings, err := testutils.ApplyK8sResourceFromYAMLWithWarnings(ctx, cfg, toYAML(t, hardenedDeployment(fmt.Sprintf("violation-%d", i), namespace, spec)))
+			testutils.ExpectAllowed(t, err)
+			expectOnlyWarning(t, warnings, v.policyName, v.message)
+
+			return ctx
+		})
+	}
+
+	testEnv.Test(t, warned.Feature())
+}
@@ -0,0 +1,393 @@
+package upgrade
+
+import (
+	"context"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"vap-library/testutils"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/e2e-framework/klient/decoder"
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+	"sigs.k8s.io/e2e-framework/pkg/env"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/features"
+)
+
+// enabledPolicies are enforced on the namespace of the existing objects before and after the upgrade
+var enabledPolicies = []string{
+	"no-default-sa-rolebinding",
+	"pss-capabilities",
+	"pss-privilege-escalation",
+	"pss-running-as-non-root",
+	"pss-running-as-non-root-user",
+	"pss-seccomp",
+	"pss-volume-types",
+	"resource-limit-types",
+	"resource-request-types",
+	"service-type",
+}
+
+// parameters that exist before the upgrade, one for every parameter kind of the release
+var parameterYAMLs = []string{`
+apiVersion: vap-library.com/v1beta1
+kind: VAPLibResourceLimitTypesParam
+metadata:
+  name: resource-limit-types.vap-library.com
+  namespace: %s
+spec:
+  enforcedResourceLimitTypes:
+  - cpu
+  - memory
+`, `
+apiVersion: vap-library.com/v1beta1
+kind: VAPLibResourceRequestTypesParam
+metadata:
+  name: resource-request-types.vap-library.com
+  namespace: %s
+spec:
+  enforcedResourceRequestTypes:
+  - cpu
+  - memory
+`, `
+apiVersion: vap-library.com/v1beta1
+kind: VAPLibServiceTypeParam
+metadata:
+  name: service-type.vap-library.com
+  namespace: %s
+spec:
+  allowedTypes:
+  - ClusterIP
+  - LoadBalancer
+`, `
+apiVersion: vap-library.com/v1beta1
+kind: VAPLibHTTPRouteFieldsParam
+metadata:
+  name: httproute-fields.vap-library.com
+  namespace: %s
+spec:
+  allowedHostnames:
+  - app.example.com
+  allowedParentRefs:
+  - name: public-gateway
+    namespace: gateway
+`, `
+apiVersion: vap-library.com/v1beta1
+kind: VAPLibHelmReleaseFieldsParam
+metadata:
+  name: helmrelease-fields.vap-library.com
+  namespace: %s
+spec:
+  targetNamespace: app
+  serviceAccountName: deployer
+`, `
+apiVersion: vap-library.com/v1beta1
+kind: VAPLibKustomizationFieldsParam
+metadata:
+  name: kustomization-fields.vap-library.com
+  namespace: %s
+spec:
+  targetNamespace: app
+  serviceAccountName: deployer
+`}
+
+// workloads that exist before the upgrade
+var workloadYAMLs = []string{`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  namespace: %s
+  labels:
+    app: app
+spec:
+  replicas: 2
+  selector:
+    matchLabels:
+      app: app
+  template:
+    metadata:
+      labels:
+        app: app
+    spec:
+      serviceAccountName: app
+      securityContext:
+        runAsNonRoot: true
+        runAsUser: 10001
+        seccompProfile:
+          type: RuntimeDefault
+      containers:
+      - name: app
+        image: public.ecr.aws/docker/library/busybox:1.36
+        securityContext:
+          allowPrivilegeEscalation: false
+          capabilities:
+            drop:
+            - ALL
+          runAsNonRoot: true
+          runAsUser: 10001
+          seccompProfile:
+            type: RuntimeDefault
+        resources:
+          requests:
+            cpu: 100m
+            memory: 64Mi
+          limits:
+            cpu: 500m
+            memory: 128Mi
+        volumeMounts:
+        - name: work
+          mountPath: /work
+      volumes:
+      - name: work
+        emptyDir: {}
+`, `
+apiVersion: v1
+kind: Service
+metadata:
+  name: app
+  namespace: %s
+spec:
+  type: LoadBalancer
+  selector:
+    app: app
+  ports:
+  - port: 80
+    targetPort: 8080
+`, `
+apiVersion: rbac.authorization.k8s.io/v1
+kind: RoleBinding
+metadata:
+  name: app-view
+  namespace: %s
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: view
+subjects:
+- kind: ServiceAccount
+  name: app
+  namespace: %s
+`}
+
+// namespacedYAML fills in the namespace of the test
+func namespacedYAML(yaml string, namespace string) string {
+	return strings.ReplaceAll(yaml, "%s", namespace)
+}
+
+// decodeYAMLs decodes the objects of the test with a placeholder namespace
+func decodeYAMLs(t *testing.T, yamls []string) []k8s.Object {
+	t.Helper()
+	var objects []k8s.Object
+	for _, yaml := range yamls {
+		obj, err := decoder.DecodeAny(strings.NewReader(namespacedYAML(yaml, "upgrade")))
+		if err != nil {
+			t.Fatal(err)
+		}
+		objects = append(objects, obj)
+	}
+	return objects
+}
+
+// previousRelease is the git revision of the release that is upgraded, previousReleaseDir holds its bundle
+var (
+	previousRelease    string
+	previousReleaseDir string
+)
+
+// skipWithoutPreviousRelease skips the test when there is no release to upgrade from
+func skipWithoutPreviousRelease(t *testing.T) {
+	if previousRelease == "" {
+		t.Skipf("no previous release tag found, set %s to the git revision to upgrade from", testutils.UpgradeFromEnvVar)
+	}
+}
+
+var testEnv env.Environment
+
+func TestMain(m *testing.M) {
+	var err error
+	previousRelease, err = testutils.PreviousRelease()
+	if err != nil {
+		log.Fatalf("Unable to find the previous release. Error msg: %s", err)
+	}
+	if previousRelease == "" {
+		// a run without a previous release tests nothing, make that visible in the output
+		log.Printf("WARNING: no previous release found, ALL upgrade tests are SKIPPED. Fetch the v* tags or set %s to the git revision to upgrade from", testutils.UpgradeFromEnvVar)
+		os.Exit(m.Run())
+	}
+	if os.Getenv(testutils.UpgradeFromEnvVar) != "" {
+		log.Printf("Testing the upgrade from %s (set by %s)", previousRelease, testutils.UpgradeFromEnvVar)
+	} else {
+		log.Printf("Testing the upgrade from %s (the latest v* tag before the current version)", previousRelease)
+	}
+
+	previousReleaseDir, err = os.MkdirTemp("", "vaplib-previous-release")
+	if err != nil {
+		log.Fatalf("Unable to create a directory for the previous release. Error msg: %s", err)
+	}
+	if err := testutils.ExtractRelease(previousRelease, previousReleaseDir); err != nil {
+		log.Fatalf("Unable to extract the previous release. Error msg: %s", err)
+	}
+
+	// the previous release is installed first, the tests apply the current bundle on top of it
+	var namespaceLabels = map[string]string{}
+	for _, name := range enabledPolicies {
+		namespaceLabels["vap-library.com/"+name] = testutils.ModeDeny
+	}
+	var extraResourcesFromDir = map[string]string{previousReleaseDir: "*.yaml"}
+
+	testEnv, err = testutils.CreateTestEnv("", false, namespaceLabels, extraResourcesFromDir, nil)
+	if err != nil {
+		log.Fatalf("Unable to create Kind cluster for test. Error msg: %s", err)
+	}
+
+	code := testEnv.Run(m)
+	os.RemoveAll(previousReleaseDir)
+	os.Exit(code)
+}
+
+func TestReleaseCompatibility(t *testing.T) {
+	skipWithoutPreviousRelease(t)
+
+	ctx := context.Background()
+	oldObjects, err := decoder.DecodeAllFiles(ctx, os.DirFS(previousReleaseDir), "*.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newObjects, err := testutils.DecodeReleaseBundle(ctx, testutils.ReleaseBundleDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	problems, err := testutils.CompareReleases(oldObjects, newObjects)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, problem := range problems {
+		t.Errorf("upgrade from %s: %s", previousRelease, problem)
+	}
+}
+
+func TestParameterSchemas(t *testing.T) {
+	skipWithoutPreviousRelease(t)
+
+	ctx := context.Background()
+	oldObjects, err := decoder.DecodeAllFiles(ctx, os.DirFS(previousReleaseDir), "*.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newObjects, err := testutils.DecodeReleaseBundle(ctx, testutils.ReleaseBundleDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, param := range decodeYAMLs(t, parameterYAMLs) {
+		// parameters of kinds that the previous release does not have cannot exist before the upgrade
+		if err := testutils.ValidateCustomResource(oldObjects, param); err != nil {
+			t.Logf("skipping %s, it is not valid in %s: %s", param.GetObjectKind().GroupVersionKind().Kind, previousRelease, err)
+			continue
+		}
+		if err := testutils.ValidateCustomResource(newObjects, param); err != nil {
+			t.Errorf("upgrade from %s: an existing parameter is no longer valid: %s", previousRelease, err)
+		}
+	}
+}
+
+func TestUpgrade(t *testing.T) {
+	skipWithoutPreviousRelease(t)
+
+	// existing are the objects that the previous release admitted
+	var existing []string
+
+	f := features.New("Upgrade from the previous release").
+		Setup(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// get namespace
+			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
+
+			// parameters and workloads are persisted while the previous release is enforced
+			for _, parameterYAML := range parameterYAMLs {
+				yaml := namespacedYAML(parameterYAML, namespace)
+				if err := testutils.ApplyParameterFromYAML(ctx, cfg, yaml); err != nil {
+					t.Logf("skipping a parameter that %s does not accept: %s", previousRelease, err)
+					continue
+				}
+				existing = append(existing, yaml)
+			}
+			for _, workloadYAML := range workloadYAMLs {
+				yaml := namespacedYAML(workloadYAML, namespace)
+				if err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, yaml, testutils.WithoutDryRun); err != nil {
+					t.Logf("skipping a workload that %s denies: %s", previousRelease, err)
+					continue
+				}
+				existing = append(existing, yaml)
+			}
+			if len(existing) == 0 {
+				t.Fatalf("%s did not admit any of the objects", previousRelease)
+			}
+
+			return ctx
+		}).
+		Assess("The current bundle is applied on top of the previous release", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			objects, err := testutils.DecodeReleaseBundle(ctx, testutils.ReleaseBundleDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			timeout, err := testutils.ReadinessTimeout()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := testutils.ApplyReleaseBundle(ctx, cfg, objects, timeout); err != nil {
+				t.Fatalf("upgrade from %s failed: %s", previousRelease, err)
+			}
+
+			return ctx
+		}).
+		Assess("Existing parameters and workloads are not denied after the upgrade", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			for _, yaml := range existing {
+				obj, err := decoder.DecodeAny(strings.NewReader(yaml))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				// this should PASS!
+				if err := testutils.UpdateK8sResourceFromYAML(ctx, cfg, yaml); err != nil {
+					t.Errorf("upgrade from %s: the existing %s %s is denied: %s", previousRelease, obj.GetObjectKind().GroupVersionKind().Kind, obj.GetName(), err)
+				}
+			}
+
+			return ctx
+		}).
+		Feature()
+
+	testEnv.Test(t, f)
+}
+
+// TestParameterCoverage checks that every parameter kind of the release has an existing parameter in parameterYAMLs
+func TestParameterCoverage(t *testing.T) {
+	objects, err := testutils.DecodeReleaseBundle(context.Background(), testutils.ReleaseBundleDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kinds := map[string]bool{}
+	for _, param := range decodeYAMLs(t, parameterYAMLs) {
+		kinds[param.GetObjectKind().GroupVersionKind().Kind] = true
+	}
+	for _, obj := range objects {
+		if obj.GetObjectKind().GroupVersionKind().Kind != "CustomResourceDefinition" {
+			continue
+		}
+		crd := &apiextensionsv1.CustomResourceDefinition{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.(*unstructured.Unstructured).Object, crd); err != nil {
+			t.Fatal(err)
+		}
+		if !kinds[crd.Spec.Names.Kind] {
+			t.Errorf("no existing parameter of kind %s in parameterYAMLs", crd.Spec.Names.Kind)
+		}
+	}
+}
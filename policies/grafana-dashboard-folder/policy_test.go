@@ -6,7 +6,6 @@ import (
 	"log"
 	"os"
 	"testing"
-	"time"
 	"vap-library/testutils"
 
 	"sigs.k8s.io/e2e-framework/pkg/envconf"
@@ -15,6 +14,11 @@ import (
 	"sigs.k8s.io/e2e-framework/pkg/env"
 )
 
+// expected denial messages
+const (
+	folderMessage = "metadata.annotations.grafana_folder must be set to the namespace of the ConfigMap/Secret"
+)
+
 var dashboardCMYAML string = `
 apiVersion: v1
 kind: ConfigMap
@@ -63,9 +67,6 @@ func TestMain(m *testing.M) {
 		log.Fatal(fmt.Sprintf("Unable to create Kind cluster for test. Error msg: %s", err))
 	}
 
-	// wait for the cluster to be ready
-	time.Sleep(2 * time.Second)
-
 	os.Exit(testEnv.Run(m))
 }
 
@@ -78,9 +79,7 @@ func TestDashboard(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(dashboardCMYAML, namespace, namespace))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -90,9 +89,7 @@ func TestDashboard(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(normalCMYAML, namespace))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -102,10 +99,73 @@ func TestDashboard(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(dashboardCMWithoutAnnotationYAML, namespace))
-			if err == nil {
-				t.Fatal("A dashboard without the required annotation was accepted")
+			testutils.ExpectDenied(t, err, "grafana-dashboard-folder", folderMessage)
+
+			return ctx
+		})
+
+	_ = testEnv.Test(t, f.Feature())
+
+}
+
+func TestWarnMode(t *testing.T) {
+
+	f := features.New("Warn mode tests").
+		Setup(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// get namespace
+			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
+
+			// switch the namespace from the deny to the warn binding
+			err := testutils.AddNamespaceLabels(ctx, cfg, namespace, map[string]string{"vap-library.com/grafana-dashboard-folder": "warn"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			return ctx
+		}).
+		Assess("A dashboard ConfigMap without folder annotation is accepted with a warning", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// get namespace
+			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
+
+			// this should PASS with a warning!
+			warnings, err := testutils.ApplyK8sResourceFromYAMLWithWarnings(ctx, cfg, fmt.Sprintf(dashboardCMWithoutAnnotationYAML, namespace))
+			testutils.ExpectAllowed(t, err)
+			testutils.ExpectWarned(t, warnings, folderMessage)
+
+			return ctx
+		})
+
+	_ = testEnv.Test(t, f.Feature())
+
+}
+
+func TestAuditMode(t *testing.T) {
+
+	f := features.New("Audit mode tests").
+		Setup(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// get namespace
+			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
+
+			// switch the namespace from the deny to the audit binding
+			err := testutils.AddNamespaceLabels(ctx, cfg, namespace, map[string]string{"vap-library.com/grafana-dashboard-folder": "audit"})
+			if err != nil {
+				t.Fatal(err)
 			}
 
+			return ctx
+		}).
+		Assess("A dashboard ConfigMap without folder annotation is accepted and audited", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// get namespace
+			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
+
+			// the audit events are only available with the in-process backend or the audit log
+			testutils.SkipWithoutAuditLog(ctx, t)
+
+			// this should PASS and be audited!
+			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(dashboardCMWithoutAnnotationYAML, namespace))
+			testutils.ExpectAllowed(t, err)
+			testutils.ExpectAudited(ctx, t, namespace, "grafana-dashboard-folder", folderMessage)
+
 			return ctx
 		})
 
@@ -5,8 +5,8 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 	"testing"
-	"time"
 	"vap-library/testutils"
 
 	"sigs.k8s.io/e2e-framework/pkg/env"
@@ -14,6 +14,12 @@ import (
 	"sigs.k8s.io/e2e-framework/pkg/features"
 )
 
+// expected denial messages
+const (
+	hostnamesMessage  = "If allowedHostnames is set on the parameter, spec.hostnames must be present and each item must be on the spec.allowedHostnames list in the policy parameter"
+	parentRefsMessage = "If allowedParentRefs is set on the parameter, spec.parentRefs must be present and each item must contain all key:value pairs from the spec.allowedParentRefs list in the policy parameter"
+)
+
 // Variables for hostname tests
 var testParameterHostnameYAML string = `
 apiVersion: vap-library.com/v1beta1
@@ -167,6 +173,114 @@ spec:
   - name: with-namespace-gateway
 `
 
+// Variables for the tests of every field of an allowedParentRefs item
+var testParameterParentRefFieldsYAML string = `
+apiVersion: vap-library.com/v1beta1
+kind: VAPLibHTTPRouteFieldsParam
+metadata:
+  name: httproute-fields.vap-library.com
+  namespace: %s
+spec:
+  allowedParentRefs:
+  - group: gateway.networking.k8s.io
+    kind: Gateway
+    name: all-fields-gateway
+    namespace: gateway-namespace
+    port: 443
+    sectionName: https
+`
+
+// the parentRef matches all fields of the allowedParentRefs item, except the one replaced by the test
+var parentRefFieldsGatewayYAML string = `
+apiVersion: gateway.networking.k8s.io/v1
+kind: HTTPRoute
+metadata:
+  name: test-httproute-parentref-fields-%s
+  namespace: %s
+spec:
+  parentRefs:
+  - %s
+`
+
+// parentRefFields are the fields of the parentRef of parentRefFieldsGatewayYAML that match the parameter
+var parentRefFields = []string{
+	"group: gateway.networking.k8s.io",
+	"kind: Gateway",
+	"name: all-fields-gateway",
+	"namespace: gateway-namespace",
+	"port: 443",
+	"sectionName: https",
+}
+
+// parentRefWith returns the fields of the parentRef with the field replaced, or removed if the replacement is empty
+func parentRefWith(field string, replacement string) string {
+	var fields []string
+	for _, f := range parentRefFields {
+		if strings.HasPrefix(f, field+":") {
+			if replacement == "" {
+				continue
+			}
+			f = field + ": " + replacement
+		}
+		fields = append(fields, f)
+	}
+	return strings.Join(fields, "\n    ")
+}
+
+// validParameterYAMLs are the edge cases of the parameter schema: wildcard and single-label hostnames, the core group,
+// the shortest and the longest values and every optional field left out
+var validParameterYAMLs = []string{`
+apiVersion: vap-library.com/v1beta1
+kind: VAPLibHTTPRouteFieldsParam
+metadata:
+  name: httproute-fields-1
+  namespace: %s
+spec:
+  allowedHostnames:
+  - app.example.com
+  allowedParentRefs:
+  - group: gateway.networking.k8s.io
+    kind: Gateway
+    name: public-gateway
+    namespace: gateway
+    port: 443
+    sectionName: https
+`, `
+apiVersion: vap-library.com/v1beta1
+kind: VAPLibHTTPRouteFieldsParam
+metadata:
+  name: httproute-fields-2
+  namespace: %s
+spec:
+  allowedHostnames:
+  - "*.example.com"
+  - a
+  - localhost
+`, `
+apiVersion: vap-library.com/v1beta1
+kind: VAPLibHTTPRouteFieldsParam
+metadata:
+  name: httproute-fields-3
+  namespace: %s
+spec:
+  allowedParentRefs:
+  - group: ""
+    kind: G
+    name: g
+    namespace: aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa
+    port: 1
+    sectionName: a
+  - name: gateway
+    port: 65535
+`, `
+apiVersion: vap-library.com/v1beta1
+kind: VAPLibHTTPRouteFieldsParam
+metadata:
+  name: httproute-fields-4
+  namespace: %s
+spec: {}
+`}
+
 var testEnv env.Environment
 
 func TestMain(m *testing.M) {
@@ -180,9 +294,6 @@ func TestMain(m *testing.M) {
 		log.Fatalf("Unable to create Kind cluster for test. Error msg: %s", err)
 	}
 
-	// wait for the cluster to be ready
-	time.Sleep(2 * time.Second)
-
 	os.Exit(testEnv.Run(m))
 }
 
@@ -194,14 +305,11 @@ func TestWithParameter(t *testing.T) {
 			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
 
 			// apply parameter first
-			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(testParameterHostnameYAML, namespace))
+			err := testutils.ApplyParameterFromYAML(ctx, cfg, fmt.Sprintf(testParameterHostnameYAML, namespace))
 			if err != nil {
 				t.Fatal(err)
 			}
 
-			// wait for the parameter to be registered properly
-			time.Sleep(10 * time.Second)
-
 			return ctx
 		}).
 		Assess("A valid HTTPRoute is accepted", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
@@ -210,9 +318,7 @@ func TestWithParameter(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(validHostnameYAML, namespace))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -222,9 +328,7 @@ func TestWithParameter(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(invalidHostnameYAML, namespace))
-			if err == nil {
-				t.Fatal("An HTTPRoute with invalid hostname was accepted")
-			}
+			testutils.ExpectDenied(t, err, "httproute-fields", hostnamesMessage)
 
 			return ctx
 		}).
@@ -234,9 +338,7 @@ func TestWithParameter(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(noHostnameYAML, namespace))
-			if err == nil {
-				t.Fatal("An HTTPRoute without hostname was accepted")
-			}
+			testutils.ExpectDenied(t, err, "httproute-fields", hostnamesMessage)
 
 			return ctx
 		})
@@ -253,14 +355,11 @@ func TestWithParentRefParameter(t *testing.T) {
 			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
 
 			// apply parameter first
-			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(testParameterParentRefYAML, namespace))
+			err := testutils.ApplyParameterFromYAML(ctx, cfg, fmt.Sprintf(testParameterParentRefYAML, namespace))
 			if err != nil {
 				t.Fatal(err)
 			}
 
-			// wait for the parameter to be registered properly
-			time.Sleep(10 * time.Second)
-
 			return ctx
 		}).
 		Assess("A valid HTTPRoute with allowed name-only parentRef is accepted", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
@@ -269,9 +368,7 @@ func TestWithParentRefParameter(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(validNameGatewayYAML, namespace))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -281,9 +378,7 @@ func TestWithParentRefParameter(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(validNameAndNamespaceGatewayYAML, namespace))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -293,9 +388,7 @@ func TestWithParentRefParameter(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(wrongNamespaceGatewayYAML, namespace))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -305,9 +398,7 @@ func TestWithParentRefParameter(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(validMultiGatewayYAML, namespace))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -317,9 +408,7 @@ func TestWithParentRefParameter(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(wrongNameGatewayYAML, namespace))
-			if err == nil {
-				t.Fatal("An HTTPRoute with invalid parentRef name was accepted")
-			}
+			testutils.ExpectDenied(t, err, "httproute-fields", parentRefsMessage)
 
 			return ctx
 		}).
@@ -329,9 +418,7 @@ func TestWithParentRefParameter(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(wrongMultiGatewayYAML, namespace))
-			if err == nil {
-				t.Fatal("An HTTPRoute with a mixture of valid and invalid parentRef names was accepted")
-			}
+			testutils.ExpectDenied(t, err, "httproute-fields", parentRefsMessage)
 
 			return ctx
 		}).
@@ -341,12 +428,77 @@ func TestWithParentRefParameter(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(wrongMissingAttributeGatewayYAML, namespace))
-			if err == nil {
-				t.Fatal("An HTTPRoute which is allowed by name but doesn't include all required attributes was accepted")
+			testutils.ExpectDenied(t, err, "httproute-fields", parentRefsMessage)
+
+			return ctx
+		})
+	_ = testEnv.Test(t, f.Feature())
+
+}
+
+func TestWithParentRefFieldsParameter(t *testing.T) {
+
+	f := features.New("HTTPRoute with parameter specifying all fields of a parentRef").
+		Setup(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// get namespace
+			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
+
+			// apply parameter first
+			err := testutils.ApplyParameterFromYAML(ctx, cfg, fmt.Sprintf(testParameterParentRefFieldsYAML, namespace))
+			if err != nil {
+				t.Fatal(err)
 			}
 
+			return ctx
+		}).
+		Assess("An HTTPRoute with a parentRef where all fields match is accepted", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// get namespace
+			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
+
+			// this should PASS!
+			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(parentRefFieldsGatewayYAML, "valid", namespace, parentRefWith("", "")))
+			testutils.ExpectAllowed(t, err)
+
 			return ctx
 		})
+
+	// every field set on the parameter must match, a missing field does not match either
+	for _, c := range []struct {
+		field string
+		wrong string
+	}{
+		{field: "group", wrong: "example.com"},
+		{field: "kind", wrong: "Service"},
+		{field: "name", wrong: "other-gateway"},
+		{field: "namespace", wrong: "other-namespace"},
+		{field: "port", wrong: "8443"},
+		{field: "sectionName", wrong: "http"},
+	} {
+		f = f.Assess(fmt.Sprintf("An HTTPRoute with a parentRef with another %s is rejected", c.field), func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// get namespace
+			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
+
+			// this should FAIL!
+			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(parentRefFieldsGatewayYAML, "wrong-"+strings.ToLower(c.field), namespace, parentRefWith(c.field, c.wrong)))
+			testutils.ExpectDenied(t, err, "httproute-fields", parentRefsMessage)
+
+			return ctx
+		})
+		if c.field == "name" || c.field == "group" || c.field == "kind" {
+			// name is required on a parentRef, group and kind are defaulted by the HTTPRoute CRD
+			continue
+		}
+		f = f.Assess(fmt.Sprintf("An HTTPRoute with a parentRef without %s is rejected", c.field), func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// get namespace
+			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
+
+			// this should FAIL!
+			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(parentRefFieldsGatewayYAML, "without-"+strings.ToLower(c.field), namespace, parentRefWith(c.field, "")))
+			testutils.ExpectDenied(t, err, "httproute-fields", parentRefsMessage)
+
+			return ctx
+		})
+	}
 	_ = testEnv.Test(t, f.Feature())
 
 }
@@ -360,13 +512,92 @@ func TestWithoutParameter(t *testing.T) {
 
 			// this should FAIL as we do not have a parameter for VAP!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(validHostnameYAML, namespace))
-			if err == nil {
-				t.Fatal("An HTTPRoute without the VAP parameter was accepted")
+			testutils.ExpectDenied(t, err, "httproute-fields", testutils.NoParamsMessage)
+
+			return ctx
+		})
+
+	_ = testEnv.Test(t, f.Feature())
+
+}
+
+func TestWarnMode(t *testing.T) {
+
+	f := features.New("Warn mode tests").
+		Setup(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// get namespace
+			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
+
+			// switch the namespace from the deny to the warn binding
+			err := testutils.AddNamespaceLabels(ctx, cfg, namespace, map[string]string{"vap-library.com/httproute-fields": "warn"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// apply parameter
+			err = testutils.ApplyParameterFromYAML(ctx, cfg, fmt.Sprintf(testParameterHostnameYAML, namespace))
+			if err != nil {
+				t.Fatal(err)
 			}
 
+			return ctx
+		}).
+		Assess("A HTTPRoute with invalid hostname is accepted with a warning", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// get namespace
+			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
+
+			// this should PASS with a warning!
+			warnings, err := testutils.ApplyK8sResourceFromYAMLWithWarnings(ctx, cfg, fmt.Sprintf(invalidHostnameYAML, namespace))
+			testutils.ExpectAllowed(t, err)
+			testutils.ExpectWarned(t, warnings, hostnamesMessage)
+
 			return ctx
 		})
 
 	_ = testEnv.Test(t, f.Feature())
 
 }
+
+func TestAuditMode(t *testing.T) {
+
+	f := features.New("Audit mode tests").
+		Setup(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// get namespace
+			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
+
+			// switch the namespace from the deny to the audit binding
+			err := testutils.AddNamespaceLabels(ctx, cfg, namespace, map[string]string{"vap-library.com/httproute-fields": "audit"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// apply parameter
+			err = testutils.ApplyParameterFromYAML(ctx, cfg, fmt.Sprintf(testParameterHostnameYAML, namespace))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			return ctx
+		}).
+		Assess("A HTTPRoute with invalid hostname is accepted and audited", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// get namespace
+			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
+
+			// the audit events are only available with the in-process backend or the audit log
+			testutils.SkipWithoutAuditLog(ctx, t)
+
+			// this should PASS and be audited!
+			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(invalidHostnameYAML, namespace))
+			testutils.ExpectAllowed(t, err)
+			testutils.ExpectAudited(ctx, t, namespace, "httproute-fields", hostnamesMessage)
+
+			return ctx
+		})
+
+	_ = testEnv.Test(t, f.Feature())
+
+}
+
+func TestParameterSchema(t *testing.T) {
+	testutils.RunParameterSchemaTests(t, testEnv, "crd-parameter.yaml", validParameterYAMLs)
+}
@@ -5,9 +5,7 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"strings"
 	"testing"
-	"time"
 	"vap-library/testutils"
 
 	"sigs.k8s.io/e2e-framework/pkg/env"
@@ -86,6 +84,50 @@ spec:
   serviceAccountName: deployer
 `
 
+// validParameterYAMLs are the edge cases of the parameter schema: the shortest and the longest target namespace and
+// every field left out
+var validParameterYAMLs = []string{`
+apiVersion: vap-library.com/v1beta1
+kind: VAPLibKustomizationFieldsParam
+metadata:
+  name: kustomization-fields-1
+  namespace: %s
+spec:
+  targetNamespace: app
+  serviceAccountName: deployer
+`, `
+apiVersion: vap-library.com/v1beta1
+kind: VAPLibKustomizationFieldsParam
+metadata:
+  name: kustomization-fields-2
+  namespace: %s
+spec:
+  targetNamespace: a
+`, `
+apiVersion: vap-library.com/v1beta1
+kind: VAPLibKustomizationFieldsParam
+metadata:
+  name: kustomization-fields-3
+  namespace: %s
+spec:
+  targetNamespace: aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa
+`, `
+apiVersion: vap-library.com/v1beta1
+kind: VAPLibKustomizationFieldsParam
+metadata:
+  name: kustomization-fields-4
+  namespace: %s
+spec:
+  serviceAccountName: deployer
+`, `
+apiVersion: vap-library.com/v1beta1
+kind: VAPLibKustomizationFieldsParam
+metadata:
+  name: kustomization-fields-5
+  namespace: %s
+spec: {}
+`}
+
 var testEnv env.Environment
 
 func TestMain(m *testing.M) {
@@ -99,9 +141,6 @@ func TestMain(m *testing.M) {
 		log.Fatalf("Unable to create Kind cluster for test. Error msg: %s", err)
 	}
 
-	// wait for the cluster to be ready
-	time.Sleep(2 * time.Second)
-
 	os.Exit(testEnv.Run(m))
 }
 
@@ -114,9 +153,7 @@ func TestKustomizationNoParam(t *testing.T) {
 
 			// this should be rejected!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(kustomizationSingleYAML, namespace))
-			if err == nil {
-				t.Fatal("A Kustomization without VAP parameter was accepted")
-			}
+			testutils.ExpectDenied(t, err, "kustomization-fields", testutils.NoParamsMessage)
 
 			return ctx
 		})
@@ -133,14 +170,11 @@ func TestKustomizationFull(t *testing.T) {
 			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
 
 			// apply parameter first
-			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(testParameterFullYAML, namespace))
+			err := testutils.ApplyParameterFromYAML(ctx, cfg, fmt.Sprintf(testParameterFullYAML, namespace))
 			if err != nil {
 				t.Fatal(err)
 			}
 
-			// wait for the parameter to be registered properly
-			time.Sleep(10 * time.Second)
-
 			return ctx
 		}).
 		Assess("A valid Kustomization with all fields is accepted", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
@@ -149,9 +183,7 @@ func TestKustomizationFull(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(kustomizationFullYAML, namespace))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -161,15 +193,7 @@ func TestKustomizationFull(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(kustomizationSingleYAML, namespace))
-			// should not be nil
-			if err == nil {
-				t.Fatal("A Kustomization with missing fields got accepted")
-			}
-
-			// if nil then check if the error message is as expected
-			if !strings.HasSuffix(err.Error(), "spec.targetNamespace must be set to the namespace specified in the Validating Admission Policy parameter") {
-				t.Fatalf("Kustomization was rejected but with the following, unexpected error message: %s", err.Error())
-			}
+			testutils.ExpectDenied(t, err, "kustomization-fields", "spec.targetNamespace must be set to the namespace specified in the Validating Admission Policy parameter")
 
 			return ctx
 		}).
@@ -179,15 +203,7 @@ func TestKustomizationFull(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(kustomizationWrongSAYAML, namespace))
-			// should not be nil
-			if err == nil {
-				t.Fatal("A Kustomization with wrong field got accepted")
-			}
-
-			// if nil then check if the error message is as expected
-			if !strings.HasSuffix(err.Error(), fmt.Sprintf("spec.serviceAccountName must be set to %s. It is: %s", "deployer", "wrong")) {
-				t.Fatalf("Kustomization was rejected but with the following, unexpected error message: %s", err.Error())
-			}
+			testutils.ExpectDenied(t, err, "kustomization-fields", fmt.Sprintf("spec.serviceAccountName must be set to %s. It is: %s", "deployer", "wrong"))
 
 			return ctx
 		})
@@ -204,14 +220,11 @@ func TestKustomizationSingle(t *testing.T) {
 			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
 
 			// apply parameter first
-			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(testParameterSingleYAML, namespace))
+			err := testutils.ApplyParameterFromYAML(ctx, cfg, fmt.Sprintf(testParameterSingleYAML, namespace))
 			if err != nil {
 				t.Fatal(err)
 			}
 
-			// wait for the parameter to be registered properly
-			time.Sleep(10 * time.Second)
-
 			return ctx
 		}).
 		Assess("A valid Kustomization with single field is accepted", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
@@ -220,13 +233,92 @@ func TestKustomizationSingle(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(kustomizationSingleYAML, namespace))
+			testutils.ExpectAllowed(t, err)
+
+			return ctx
+		})
+
+	_ = testEnv.Test(t, f.Feature())
+
+}
+
+func TestWarnMode(t *testing.T) {
+
+	f := features.New("Warn mode tests").
+		Setup(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// get namespace
+			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
+
+			// switch the namespace from the deny to the warn binding
+			err := testutils.AddNamespaceLabels(ctx, cfg, namespace, map[string]string{"vap-library.com/kustomization-fields": "warn"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// apply parameter
+			err = testutils.ApplyParameterFromYAML(ctx, cfg, fmt.Sprintf(testParameterFullYAML, namespace))
 			if err != nil {
 				t.Fatal(err)
 			}
 
+			return ctx
+		}).
+		Assess("A Kustomization with missing fields is accepted with a warning", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// get namespace
+			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
+
+			// this should PASS with a warning!
+			warnings, err := testutils.ApplyK8sResourceFromYAMLWithWarnings(ctx, cfg, fmt.Sprintf(kustomizationSingleYAML, namespace))
+			testutils.ExpectAllowed(t, err)
+			testutils.ExpectWarned(t, warnings, "spec.targetNamespace must be set to the namespace specified in the Validating Admission Policy parameter")
+
 			return ctx
 		})
 
 	_ = testEnv.Test(t, f.Feature())
 
 }
+
+func TestAuditMode(t *testing.T) {
+
+	f := features.New("Audit mode tests").
+		Setup(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// get namespace
+			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
+
+			// switch the namespace from the deny to the audit binding
+			err := testutils.AddNamespaceLabels(ctx, cfg, namespace, map[string]string{"vap-library.com/kustomization-fields": "audit"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// apply parameter
+			err = testutils.ApplyParameterFromYAML(ctx, cfg, fmt.Sprintf(testParameterFullYAML, namespace))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			return ctx
+		}).
+		Assess("A Kustomization with missing fields is accepted and audited", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// get namespace
+			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
+
+			// the audit events are only available with the in-process backend or the audit log
+			testutils.SkipWithoutAuditLog(ctx, t)
+
+			// this should PASS and be audited!
+			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(kustomizationSingleYAML, namespace))
+			testutils.ExpectAllowed(t, err)
+			testutils.ExpectAudited(ctx, t, namespace, "kustomization-fields", "spec.targetNamespace must be set to the namespace specified in the Validating Admission Policy parameter")
+
+			return ctx
+		})
+
+	_ = testEnv.Test(t, f.Feature())
+
+}
+
+func TestParameterSchema(t *testing.T) {
+	testutils.RunParameterSchemaTests(t, testEnv, "crd-parameter.yaml", validParameterYAMLs)
+}
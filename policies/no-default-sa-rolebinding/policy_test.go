@@ -6,14 +6,19 @@ import (
 	"log"
 	"os"
 	"testing"
-	"time"
 	"vap-library/testutils"
 
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
 	"sigs.k8s.io/e2e-framework/pkg/env"
 	"sigs.k8s.io/e2e-framework/pkg/envconf"
 	"sigs.k8s.io/e2e-framework/pkg/features"
 )
 
+// expected denial messages
+const (
+	defaultServiceAccountMessage = "subjects cannot include the 'default' service account"
+)
+
 var roleBindingValidYAML string = `
 apiVersion: rbac.authorization.k8s.io/v1
 kind: RoleBinding
@@ -77,6 +82,50 @@ roleRef:
   name: example-role
 `
 
+var roleBindingLabelledYAML string = `
+apiVersion: rbac.authorization.k8s.io/v1
+kind: RoleBinding
+metadata:
+  name: %s
+  namespace: %s
+  labels:
+    vap-library.com/no-default-sa-rolebinding: %s
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: Role
+  name: example-role
+subjects:
+- kind: ServiceAccount
+  name: default
+  namespace: %s
+`
+
+var clusterRoleYAML string = `
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: %s
+rules:
+- apiGroups: [""]
+  resources: ["configmaps"]
+  verbs: ["get"]
+`
+
+var clusterRoleBindingDefaultSAYAML string = `
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: %s
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: %s
+subjects:
+- kind: ServiceAccount
+  name: default
+  namespace: %s
+`
+
 var testEnv env.Environment
 
 func TestMain(m *testing.M) {
@@ -89,9 +138,6 @@ func TestMain(m *testing.M) {
 		log.Fatalf("Unable to create Kind cluster for test. Error msg: %s", err)
 	}
 
-	// wait for the cluster to be ready
-	time.Sleep(2 * time.Second)
-
 	os.Exit(testEnv.Run(m))
 }
 
@@ -103,9 +149,7 @@ func TestRoleBindingNoDefaultSA(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(roleBindingValidYAML, namespace, namespace))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -114,9 +158,7 @@ func TestRoleBindingNoDefaultSA(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(roleBindingDefaultSAYAML, namespace, namespace))
-			if err == nil {
-				t.Fatal("A RoleBinding with the default service account was accepted")
-			}
+			testutils.ExpectDenied(t, err, "no-default-sa-rolebinding", defaultServiceAccountMessage)
 
 			return ctx
 		}).
@@ -125,9 +167,7 @@ func TestRoleBindingNoDefaultSA(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(roleBindingMixedSubjectsYAML, namespace, namespace, namespace))
-			if err == nil {
-				t.Fatal("A RoleBinding with mixed subjects including the default service account was accepted")
-			}
+			testutils.ExpectDenied(t, err, "no-default-sa-rolebinding", defaultServiceAccountMessage)
 
 			return ctx
 		}).
@@ -136,10 +176,165 @@ func TestRoleBindingNoDefaultSA(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(roleBindingNoSubjectsYAML, namespace))
+			testutils.ExpectAllowed(t, err)
+
+			return ctx
+		})
+
+	_ = testEnv.Test(t, f.Feature())
+
+}
+
+func TestWarnMode(t *testing.T) {
+
+	f := features.New("Warn mode tests").
+		Setup(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// get namespace
+			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
+
+			// switch the namespace from the deny to the warn binding
+			err := testutils.AddNamespaceLabels(ctx, cfg, namespace, map[string]string{"vap-library.com/no-default-sa-rolebinding": "warn"})
 			if err != nil {
 				t.Fatal(err)
 			}
 
+			return ctx
+		}).
+		Assess("A RoleBinding with the default service account is accepted with a warning", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// get namespace
+			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
+
+			// this should PASS with a warning!
+			warnings, err := testutils.ApplyK8sResourceFromYAMLWithWarnings(ctx, cfg, fmt.Sprintf(roleBindingDefaultSAYAML, namespace, namespace))
+			testutils.ExpectAllowed(t, err)
+			testutils.ExpectWarned(t, warnings, defaultServiceAccountMessage)
+
+			return ctx
+		})
+
+	_ = testEnv.Test(t, f.Feature())
+
+}
+
+func TestAuditMode(t *testing.T) {
+
+	f := features.New("Audit mode tests").
+		Setup(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// get namespace
+			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
+
+			// switch the namespace from the deny to the audit binding
+			err := testutils.AddNamespaceLabels(ctx, cfg, namespace, map[string]string{"vap-library.com/no-default-sa-rolebinding": "audit"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			return ctx
+		}).
+		Assess("A RoleBinding with the default service account is accepted and audited", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// get namespace
+			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
+
+			// the audit events are only available with the in-process backend or the audit log
+			testutils.SkipWithoutAuditLog(ctx, t)
+
+			// this should PASS and be audited!
+			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(roleBindingDefaultSAYAML, namespace, namespace))
+			testutils.ExpectAllowed(t, err)
+			testutils.ExpectAudited(ctx, t, namespace, "no-default-sa-rolebinding", defaultServiceAccountMessage)
+
+			return ctx
+		})
+
+	_ = testEnv.Test(t, f.Feature())
+
+}
+
+func TestClusterRoleBinding(t *testing.T) {
+
+	f := features.New("ClusterRoleBinding tests").
+		Setup(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// the ClusterRole is shared by all the tests of the cluster, so it gets a name of its own
+			clusterRole := testutils.ClusterScopedName(ctx, t, "reader")
+			if err := testutils.CreateClusterScopedFixtureFromYAML(ctx, cfg, t, fmt.Sprintf(clusterRoleYAML, clusterRole)); err != nil {
+				t.Fatal(err)
+			}
+
+			return ctx
+		}).
+		Assess("A ClusterRoleBinding with the default service account is accepted, the policy does not cover it", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// get namespace
+			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
+
+			// this should PASS and the ClusterRoleBinding is deleted after the test!
+			clusterRole := testutils.ClusterScopedName(ctx, t, "reader")
+			clusterRoleBinding := testutils.ClusterScopedName(ctx, t, "reader-default")
+			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(clusterRoleBindingDefaultSAYAML, clusterRoleBinding, clusterRole, namespace), testutils.WithoutDryRun)
+			testutils.ExpectAllowed(t, err)
+
+			return ctx
+		})
+
+	_ = testEnv.Test(t, f.Feature())
+
+}
+
+// bindingByObjectLabel returns a binding that denies the RoleBindings labelled with
+// vap-library.com/no-default-sa-rolebinding: objects in every namespace
+func bindingByObjectLabel() *testutils.BindingBuilder {
+	return testutils.NewBindingBuilder("no-default-sa-rolebinding", "objects").
+		SelectObjectsByLabel().
+		WithValidationActions(admissionregistrationv1.Deny)
+}
+
+func TestBindingByObjectLabel(t *testing.T) {
+
+	f := features.New("Binding that selects objects by label").
+		Setup(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// get namespace
+			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
+
+			// switch the namespace from the deny binding to no binding at all
+			err := testutils.AddNamespaceLabels(ctx, cfg, namespace, map[string]string{"vap-library.com/no-default-sa-rolebinding": "objects"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			timeout, err := testutils.ReadinessTimeout()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := bindingByObjectLabel().Apply(ctx, cfg, timeout); err != nil {
+				t.Fatal(err)
+			}
+
+			return ctx
+		}).
+		Assess("A labelled RoleBinding with the default service account is rejected", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// get namespace
+			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
+
+			// this should FAIL!
+			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(roleBindingLabelledYAML, "labelled", namespace, "objects", namespace))
+			testutils.ExpectDenied(t, err, "no-default-sa-rolebinding", defaultServiceAccountMessage)
+
+			return ctx
+		}).
+		Assess("A RoleBinding with another label value is accepted", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// get namespace
+			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
+
+			// this should PASS because of the object selector!
+			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(roleBindingLabelledYAML, "other", namespace, "other", namespace))
+			testutils.ExpectAllowed(t, err)
+
+			return ctx
+		}).
+		Teardown(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			if err := bindingByObjectLabel().Delete(ctx, cfg); err != nil {
+				t.Fatal(err)
+			}
+
 			return ctx
 		})
 
@@ -6,17 +6,21 @@ import (
 	"log"
 	"os"
 	"testing"
-	"time"
 	"vap-library/testutils"
 
-	v1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/types"
-	"sigs.k8s.io/e2e-framework/klient/k8s"
 	"sigs.k8s.io/e2e-framework/pkg/env"
 	"sigs.k8s.io/e2e-framework/pkg/envconf"
 	"sigs.k8s.io/e2e-framework/pkg/features"
 )
 
+// expected denial messages
+const (
+	podsMessage         = "securityContext.capabilities.drop must include ALL and securityContext.capabilities.add can only include NET_BIND_SERVICE on containers in Pods"
+	workloadsMessage    = "securityContext.capabilities.drop must include ALL and securityContext.capabilities.add can only include NET_BIND_SERVICE on containers in Workloads"
+	podTemplatesMessage = "securityContext.capabilities.drop must include ALL and securityContext.capabilities.add can only include NET_BIND_SERVICE on containers in PodTemplates"
+	cronJobsMessage     = "securityContext.capabilities.drop must include ALL and securityContext.capabilities.add can only include NET_BIND_SERVICE on containers in CronJobs"
+)
+
 // TEST DATA FOR POD TESTS
 
 var containerYAML string = `
@@ -1061,34 +1065,34 @@ template:
           - %s
 `
 
-// TEST DATA FOR PATCHING A POD TO ADD AN EPHEMERAL CONTAINER
-
-var containerEphemeralPatchYAML string = `
-{
-  "spec": {
-    "ephemeralContainers": [
-      {
-         "image": "public.ecr.aws/docker/library/busybox:1.36",
-         "name": "ephemeral",
-         "resources": {},
-         "securityContext": {
-           "capabilities": {
-            "drop": [
-              "%s"
-            ],
-            "add": [
-              "%s"
-            ]
-           }
-         },
-         "stdin": true,
-         "targetContainerName": "capabilities",
-         "terminationMessagePolicy": "File",
-         "tty": true
-      }
-    ]
-  }
-}
+// TEST DATA FOR ADDING AN EPHEMERAL CONTAINER THROUGH THE EPHEMERALCONTAINERS SUBRESOURCE
+
+var ephemeralContainerYAML string = `
+name: debugger
+image: public.ecr.aws/docker/library/busybox:1.36
+stdin: true
+tty: true
+targetContainerName: capabilities
+securityContext:
+  capabilities:
+    drop:
+    - %s
+    add:
+    - %s
+`
+
+// TEST DATA FOR THE WORKLOAD TESTS, THE PODSPEC IS WRAPPED INTO ALL THE WORKLOAD KINDS
+
+var podSpecYAML string = `
+containers:
+- name: capabilities
+  image: public.ecr.aws/docker/library/busybox:1.36
+  securityContext:
+    capabilities:
+      drop:
+      - %s
+      add:
+      - NET_BIND_SERVICE
 `
 
 var testEnv env.Environment
@@ -1103,9 +1107,6 @@ func TestMain(m *testing.M) {
 		log.Fatalf("Unable to create Kind cluster for test. Error msg: %s", err)
 	}
 
-	// wait for the cluster to be ready
-	time.Sleep(2 * time.Second)
-
 	os.Exit(testEnv.Run(m))
 }
 
@@ -1119,9 +1120,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerYAML, "success", namespace, "ALL", "NET_BIND_SERVICE"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1131,9 +1130,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerNoAddYAML, "success", namespace, "ALL"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1143,9 +1140,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerYAML, "success", namespace, "ALL", "NET_BIND_SERVICE", "ALL", "NET_BIND_SERVICE"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1155,9 +1150,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerNoAddYAML, "success", namespace, "ALL", "NET_BIND_SERVICE", "ALL"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1167,9 +1160,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerYAML, "rejected", namespace, "ALL", "NOT_ALLOWED"))
-			if err == nil {
-				t.Fatal("containers with invalid capabilities were accepted.")
-			}
+			testutils.ExpectDenied(t, err, "pss-capabilities", podsMessage)
 			return ctx
 		}).
 		Assess("Rejected deployment of a Pod with container as ALL capabilities not dropped", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
@@ -1178,9 +1169,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerNoAddYAML, "rejected", namespace, "NONE"))
-			if err == nil {
-				t.Fatal("containers with invalid capabilities were accepted.")
-			}
+			testutils.ExpectDenied(t, err, "pss-capabilities", podsMessage)
 			return ctx
 		}).
 		Assess("Rejected deployment of a Pod with initContainer as disallowed capability added", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
@@ -1189,9 +1178,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerYAML, "rejected", namespace, "ALL", "NET_BIND_SERVICE", "ALL", "NOT_ALLOWED"))
-			if err == nil {
-				t.Fatal("containers with invalid capabilities were accepted.")
-			}
+			testutils.ExpectDenied(t, err, "pss-capabilities", podsMessage)
 			return ctx
 		}).
 		Assess("Rejected deployment of a Pod with initContainer as ALL capabilities not dropped", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
@@ -1200,9 +1187,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerNoAddYAML, "rejected", namespace, "ALL", "NET_BIND_SERVICE", "NONE"))
-			if err == nil {
-				t.Fatal("containers with invalid capabilities were accepted.")
-			}
+			testutils.ExpectDenied(t, err, "pss-capabilities", podsMessage)
 			return ctx
 		}).
 		// DEPLOYMENT TESTS
@@ -1212,9 +1197,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerDeploymentYAML, "success", namespace, "ALL", "NET_BIND_SERVICE"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1224,9 +1207,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerDeploymentNoAddYAML, "success", namespace, "ALL"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1236,9 +1217,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerDeploymentYAML, "success", namespace, "ALL", "NET_BIND_SERVICE", "ALL", "NET_BIND_SERVICE"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1248,9 +1227,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerDeploymentNoAddYAML, "success", namespace, "ALL", "NET_BIND_SERVICE", "ALL"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1260,9 +1237,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerDeploymentYAML, "rejected", namespace, "ALL", "NOT_ALLOWED"))
-			if err == nil {
-				t.Fatal("containers with invalid capabilities were accepted.")
-			}
+			testutils.ExpectDenied(t, err, "pss-capabilities", workloadsMessage)
 			return ctx
 		}).
 		Assess("Rejected deployment of a Deployment with container as ALL capabilities not dropped", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
@@ -1271,9 +1246,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerDeploymentNoAddYAML, "rejected", namespace, "NONE"))
-			if err == nil {
-				t.Fatal("containers with invalid capabilities were accepted.")
-			}
+			testutils.ExpectDenied(t, err, "pss-capabilities", workloadsMessage)
 			return ctx
 		}).
 		Assess("Rejected deployment of a Deployment with initContainer as disallowed capability added", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
@@ -1282,9 +1255,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerDeploymentYAML, "rejected", namespace, "ALL", "NET_BIND_SERVICE", "ALL", "NOT_ALLOWED"))
-			if err == nil {
-				t.Fatal("containers with invalid capabilities were accepted.")
-			}
+			testutils.ExpectDenied(t, err, "pss-capabilities", workloadsMessage)
 			return ctx
 		}).
 		Assess("Rejected deployment of a Deployment with initContainer as ALL capabilities not dropped", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
@@ -1293,9 +1264,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerDeploymentNoAddYAML, "rejected", namespace, "ALL", "NET_BIND_SERVICE", "NONE"))
-			if err == nil {
-				t.Fatal("containers with invalid capabilities were accepted.")
-			}
+			testutils.ExpectDenied(t, err, "pss-capabilities", workloadsMessage)
 			return ctx
 		}).
 		// REPLICASET TESTS
@@ -1305,9 +1274,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerRSYAML, "success", namespace, "ALL", "NET_BIND_SERVICE"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1317,9 +1284,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerRSNoAddYAML, "success", namespace, "ALL"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1329,9 +1294,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerRSYAML, "success", namespace, "ALL", "NET_BIND_SERVICE", "ALL", "NET_BIND_SERVICE"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1341,9 +1304,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerRSNoAddYAML, "success", namespace, "ALL", "NET_BIND_SERVICE", "ALL"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1353,9 +1314,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerRSYAML, "rejected", namespace, "ALL", "NOT_ALLOWED"))
-			if err == nil {
-				t.Fatal("containers with invalid capabilities were accepted.")
-			}
+			testutils.ExpectDenied(t, err, "pss-capabilities", workloadsMessage)
 			return ctx
 		}).
 		Assess("Rejected deployment of a ReplicaSet with container as ALL capabilities not dropped", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
@@ -1364,9 +1323,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerRSNoAddYAML, "rejected", namespace, "NONE"))
-			if err == nil {
-				t.Fatal("containers with invalid capabilities were accepted.")
-			}
+			testutils.ExpectDenied(t, err, "pss-capabilities", workloadsMessage)
 			return ctx
 		}).
 		Assess("Rejected deployment of a ReplicaSet with initContainer as disallowed capability added", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
@@ -1375,9 +1332,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerRSYAML, "rejected", namespace, "ALL", "NET_BIND_SERVICE", "ALL", "NOT_ALLOWED"))
-			if err == nil {
-				t.Fatal("containers with invalid capabilities were accepted.")
-			}
+			testutils.ExpectDenied(t, err, "pss-capabilities", workloadsMessage)
 			return ctx
 		}).
 		Assess("Rejected deployment of a ReplicaSet with initContainer as ALL capabilities not dropped", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
@@ -1386,9 +1341,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerRSNoAddYAML, "rejected", namespace, "ALL", "NET_BIND_SERVICE", "NONE"))
-			if err == nil {
-				t.Fatal("containers with invalid capabilities were accepted.")
-			}
+			testutils.ExpectDenied(t, err, "pss-capabilities", workloadsMessage)
 			return ctx
 		}).
 		// DAEMONSET TESTS
@@ -1398,9 +1351,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerDSYAML, "success", namespace, "ALL", "NET_BIND_SERVICE"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1410,9 +1361,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerDSNoAddYAML, "success", namespace, "ALL"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1422,9 +1371,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerDSYAML, "success", namespace, "ALL", "NET_BIND_SERVICE", "ALL", "NET_BIND_SERVICE"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1434,9 +1381,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerDSNoAddYAML, "success", namespace, "ALL", "NET_BIND_SERVICE", "ALL"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1446,9 +1391,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerDSYAML, "rejected", namespace, "ALL", "NOT_ALLOWED"))
-			if err == nil {
-				t.Fatal("containers with invalid capabilities were accepted.")
-			}
+			testutils.ExpectDenied(t, err, "pss-capabilities", workloadsMessage)
 			return ctx
 		}).
 		Assess("Rejected deployment of a DaemonSet with container as ALL capabilities not dropped", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
@@ -1457,9 +1400,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerDSNoAddYAML, "rejected", namespace, "NONE"))
-			if err == nil {
-				t.Fatal("containers with invalid capabilities were accepted.")
-			}
+			testutils.ExpectDenied(t, err, "pss-capabilities", workloadsMessage)
 			return ctx
 		}).
 		Assess("Rejected deployment of a DaemonSet with initContainer as disallowed capability added", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
@@ -1468,9 +1409,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerDSYAML, "rejected", namespace, "ALL", "NET_BIND_SERVICE", "ALL", "NOT_ALLOWED"))
-			if err == nil {
-				t.Fatal("containers with invalid capabilities were accepted.")
-			}
+			testutils.ExpectDenied(t, err, "pss-capabilities", workloadsMessage)
 			return ctx
 		}).
 		Assess("Rejected deployment of a DaemonSet with initContainer as ALL capabilities not dropped", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
@@ -1479,9 +1418,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerDSNoAddYAML, "rejected", namespace, "ALL", "NET_BIND_SERVICE", "NONE"))
-			if err == nil {
-				t.Fatal("containers with invalid capabilities were accepted.")
-			}
+			testutils.ExpectDenied(t, err, "pss-capabilities", workloadsMessage)
 			return ctx
 		}).
 		// STATEFULSET TESTS
@@ -1491,9 +1428,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerSSYAML, "success", namespace, "ALL", "NET_BIND_SERVICE"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1503,9 +1438,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerSSNoAddYAML, "success", namespace, "ALL"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1515,9 +1448,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerSSYAML, "success", namespace, "ALL", "NET_BIND_SERVICE", "ALL", "NET_BIND_SERVICE"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1527,9 +1458,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerSSNoAddYAML, "success", namespace, "ALL", "NET_BIND_SERVICE", "ALL"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1539,9 +1468,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerSSYAML, "rejected", namespace, "ALL", "NOT_ALLOWED"))
-			if err == nil {
-				t.Fatal("containers with invalid capabilities were accepted.")
-			}
+			testutils.ExpectDenied(t, err, "pss-capabilities", workloadsMessage)
 			return ctx
 		}).
 		Assess("Rejected deployment of a StatefulSet with container as ALL capabilities not dropped", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
@@ -1550,9 +1477,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerSSNoAddYAML, "rejected", namespace, "NONE"))
-			if err == nil {
-				t.Fatal("containers with invalid capabilities were accepted.")
-			}
+			testutils.ExpectDenied(t, err, "pss-capabilities", workloadsMessage)
 			return ctx
 		}).
 		Assess("Rejected deployment of a StatefulSet with initContainer as disallowed capability added", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
@@ -1561,9 +1486,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerSSYAML, "rejected", namespace, "ALL", "NET_BIND_SERVICE", "ALL", "NOT_ALLOWED"))
-			if err == nil {
-				t.Fatal("containers with invalid capabilities were accepted.")
-			}
+			testutils.ExpectDenied(t, err, "pss-capabilities", workloadsMessage)
 			return ctx
 		}).
 		Assess("Rejected deployment of a StatefulSet with initContainer as ALL capabilities not dropped", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
@@ -1572,9 +1495,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerSSNoAddYAML, "rejected", namespace, "ALL", "NET_BIND_SERVICE", "NONE"))
-			if err == nil {
-				t.Fatal("containers with invalid capabilities were accepted.")
-			}
+			testutils.ExpectDenied(t, err, "pss-capabilities", workloadsMessage)
 			return ctx
 		}).
 		// JOB TESTS
@@ -1584,9 +1505,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerJobYAML, "success", namespace, "ALL", "NET_BIND_SERVICE"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1596,9 +1515,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerJobNoAddYAML, "success", namespace, "ALL"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1608,9 +1525,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerJobYAML, "success", namespace, "ALL", "NET_BIND_SERVICE", "ALL", "NET_BIND_SERVICE"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1620,9 +1535,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerJobNoAddYAML, "success", namespace, "ALL", "NET_BIND_SERVICE", "ALL"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1632,9 +1545,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerJobYAML, "rejected", namespace, "ALL", "NOT_ALLOWED"))
-			if err == nil {
-				t.Fatal("containers with invalid capabilities were accepted.")
-			}
+			testutils.ExpectDenied(t, err, "pss-capabilities", workloadsMessage)
 			return ctx
 		}).
 		Assess("Rejected deployment of a Job with container as ALL capabilities not dropped", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
@@ -1643,9 +1554,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerJobNoAddYAML, "rejected", namespace, "NONE"))
-			if err == nil {
-				t.Fatal("containers with invalid capabilities were accepted.")
-			}
+			testutils.ExpectDenied(t, err, "pss-capabilities", workloadsMessage)
 			return ctx
 		}).
 		Assess("Rejected deployment of a Job with initContainer as disallowed capability added", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
@@ -1654,9 +1563,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerJobYAML, "rejected", namespace, "ALL", "NET_BIND_SERVICE", "ALL", "NOT_ALLOWED"))
-			if err == nil {
-				t.Fatal("containers with invalid capabilities were accepted.")
-			}
+			testutils.ExpectDenied(t, err, "pss-capabilities", workloadsMessage)
 			return ctx
 		}).
 		Assess("Rejected deployment of a Job with initContainer as ALL capabilities not dropped", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
@@ -1665,9 +1572,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerJobNoAddYAML, "rejected", namespace, "ALL", "NET_BIND_SERVICE", "NONE"))
-			if err == nil {
-				t.Fatal("containers with invalid capabilities were accepted.")
-			}
+			testutils.ExpectDenied(t, err, "pss-capabilities", workloadsMessage)
 			return ctx
 		}).
 		// CRONJOB TESTS
@@ -1677,9 +1582,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerCronJobYAML, "success", namespace, "ALL", "NET_BIND_SERVICE"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1689,9 +1592,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerCronJobNoAddYAML, "success", namespace, "ALL"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1701,9 +1602,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerCronJobYAML, "success", namespace, "ALL", "NET_BIND_SERVICE", "ALL", "NET_BIND_SERVICE"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1713,9 +1612,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerCronJobNoAddYAML, "success", namespace, "ALL", "NET_BIND_SERVICE", "ALL"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1725,9 +1622,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerCronJobYAML, "rejected", namespace, "ALL", "NOT_ALLOWED"))
-			if err == nil {
-				t.Fatal("containers with invalid capabilities were accepted.")
-			}
+			testutils.ExpectDenied(t, err, "pss-capabilities", cronJobsMessage)
 			return ctx
 		}).
 		Assess("Rejected deployment of a CronJob with container as ALL capabilities not dropped", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
@@ -1736,9 +1631,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerCronJobNoAddYAML, "rejected", namespace, "NONE"))
-			if err == nil {
-				t.Fatal("containers with invalid capabilities were accepted.")
-			}
+			testutils.ExpectDenied(t, err, "pss-capabilities", cronJobsMessage)
 			return ctx
 		}).
 		Assess("Rejected deployment of a CronJob with initContainer as disallowed capability added", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
@@ -1747,9 +1640,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerCronJobYAML, "rejected", namespace, "ALL", "NET_BIND_SERVICE", "ALL", "NOT_ALLOWED"))
-			if err == nil {
-				t.Fatal("containers with invalid capabilities were accepted.")
-			}
+			testutils.ExpectDenied(t, err, "pss-capabilities", cronJobsMessage)
 			return ctx
 		}).
 		Assess("Rejected deployment of a CronJob with initContainer as ALL capabilities not dropped", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
@@ -1758,9 +1649,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerCronJobNoAddYAML, "rejected", namespace, "ALL", "NET_BIND_SERVICE", "NONE"))
-			if err == nil {
-				t.Fatal("containers with invalid capabilities were accepted.")
-			}
+			testutils.ExpectDenied(t, err, "pss-capabilities", cronJobsMessage)
 			return ctx
 		}).
 		// REPLICATIONCONTROLLER TESTS
@@ -1770,9 +1659,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerRCYAML, "success", namespace, "ALL", "NET_BIND_SERVICE"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1782,9 +1669,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerRCNoAddYAML, "success", namespace, "ALL"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1794,9 +1679,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerRCYAML, "success", namespace, "ALL", "NET_BIND_SERVICE", "ALL", "NET_BIND_SERVICE"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1806,9 +1689,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerRCNoAddYAML, "success", namespace, "ALL", "NET_BIND_SERVICE", "ALL"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1818,9 +1699,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerRCYAML, "rejected", namespace, "ALL", "NOT_ALLOWED"))
-			if err == nil {
-				t.Fatal("containers with invalid capabilities were accepted.")
-			}
+			testutils.ExpectDenied(t, err, "pss-capabilities", workloadsMessage)
 			return ctx
 		}).
 		Assess("Rejected deployment of a ReplicationController with container as ALL capabilities not dropped", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
@@ -1829,9 +1708,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerRCNoAddYAML, "rejected", namespace, "NONE"))
-			if err == nil {
-				t.Fatal("containers with invalid capabilities were accepted.")
-			}
+			testutils.ExpectDenied(t, err, "pss-capabilities", workloadsMessage)
 			return ctx
 		}).
 		Assess("Rejected deployment of a ReplicationController with initContainer as disallowed capability added", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
@@ -1840,9 +1717,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerRCYAML, "rejected", namespace, "ALL", "NET_BIND_SERVICE", "ALL", "NOT_ALLOWED"))
-			if err == nil {
-				t.Fatal("containers with invalid capabilities were accepted.")
-			}
+			testutils.ExpectDenied(t, err, "pss-capabilities", workloadsMessage)
 			return ctx
 		}).
 		Assess("Rejected deployment of a ReplicationController with initContainer as ALL capabilities not dropped", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
@@ -1851,9 +1726,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerRCNoAddYAML, "rejected", namespace, "ALL", "NET_BIND_SERVICE", "NONE"))
-			if err == nil {
-				t.Fatal("containers with invalid capabilities were accepted.")
-			}
+			testutils.ExpectDenied(t, err, "pss-capabilities", workloadsMessage)
 			return ctx
 		}).
 		// PODTEMPLATE TESTS
@@ -1863,9 +1736,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerPodTemplateYAML, "success", namespace, "ALL", "NET_BIND_SERVICE"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1875,9 +1746,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerPodTemplateNoAddYAML, "success", namespace, "ALL"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1887,9 +1756,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerPodTemplateYAML, "success", namespace, "ALL", "NET_BIND_SERVICE", "ALL", "NET_BIND_SERVICE"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1899,9 +1766,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerPodTemplateNoAddYAML, "success", namespace, "ALL", "NET_BIND_SERVICE", "ALL"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1911,9 +1776,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerPodTemplateYAML, "rejected", namespace, "ALL", "NOT_ALLOWED"))
-			if err == nil {
-				t.Fatal("containers with invalid capabilities were accepted.")
-			}
+			testutils.ExpectDenied(t, err, "pss-capabilities", podTemplatesMessage)
 			return ctx
 		}).
 		Assess("Rejected deployment of a PodTemplate with container as ALL capabilities not dropped", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
@@ -1922,9 +1785,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerPodTemplateNoAddYAML, "rejected", namespace, "NONE"))
-			if err == nil {
-				t.Fatal("containers with invalid capabilities were accepted.")
-			}
+			testutils.ExpectDenied(t, err, "pss-capabilities", podTemplatesMessage)
 			return ctx
 		}).
 		Assess("Rejected deployment of a PodTemplate with initContainer as disallowed capability added", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
@@ -1933,9 +1794,7 @@ func TestCapabilities(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerPodTemplateYAML, "rejected", namespace, "ALL", "NET_BIND_SERVICE", "ALL", "NOT_ALLOWED"))
-			if err == nil {
-				t.Fatal("containers with invalid capabilities were accepted.")
-			}
+			testutils.ExpectDenied(t, err, "pss-capabilities", podTemplatesMessage)
 			return ctx
 		}).
 		Assess("Rejected deployment of a PodTemplate with initContainer as ALL capabilities not dropped", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
@@ -1944,99 +1803,126 @@ func TestCapabilities(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerPodTemplateNoAddYAML, "rejected", namespace, "ALL", "NET_BIND_SERVICE", "NONE"))
-			if err == nil {
-				t.Fatal("containers with invalid capabilities were accepted.")
-			}
+			testutils.ExpectDenied(t, err, "pss-capabilities", podTemplatesMessage)
 			return ctx
 		})
 	_ = testEnv.Test(t, f.Feature())
 
 }
 
-func TestEphemeralContainers(t *testing.T) {
+func TestWarnMode(t *testing.T) {
 
-	f := features.New("Pods with ephemeral containers").
+	f := features.New("Warn mode tests").
 		Setup(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
 			// get namespace
 			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
 
-			// create a pod that will be used for ephemeral container tests
-			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerYAML, "ephemeral", namespace, "ALL", "NET_BIND_SERVICE"))
+			// switch the namespace from the deny to the warn binding
+			err := testutils.AddNamespaceLabels(ctx, cfg, namespace, map[string]string{"vap-library.com/pss-capabilities": "warn"})
 			if err != nil {
 				t.Fatal(err)
 			}
 
-			// wait for the pod
-			time.Sleep(2 * time.Second)
-
 			return ctx
 		}).
-		Assess("An invalid ephemeral container is rejected", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+		Assess("A Pod with a not allowed capability is accepted with a warning", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
 			// get namespace
 			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
 
-			// get client
-			client, err := cfg.NewClient()
-			if err != nil {
-				t.Fatal(err)
-			}
+			// this should PASS with a warning!
+			warnings, err := testutils.ApplyK8sResourceFromYAMLWithWarnings(ctx, cfg, fmt.Sprintf(containerYAML, "rejected", namespace, "ALL", "NOT_ALLOWED"))
+			testutils.ExpectAllowed(t, err)
+			testutils.ExpectWarned(t, warnings, podsMessage)
 
-			// get the pod that was created in setup to attach an ephemeral container to it
-			pod := &v1.Pod{}
-			err = client.Resources(namespace).Get(ctx, "capabilities-ephemeral", namespace, pod)
-			if err != nil {
-				t.Fatal(err)
-			}
+			return ctx
+		})
+
+	_ = testEnv.Test(t, f.Feature())
 
-			// define patch type
-			patchType := types.StrategicMergePatchType
+}
 
-			// define patch data
-			patchData := []byte(fmt.Sprintf(containerEphemeralPatchYAML, "CAP_NET_RAW", "NET_BIND_SERVICE"))
+func TestAuditMode(t *testing.T) {
 
-			patch := k8s.Patch{PatchType: patchType, Data: patchData}
+	f := features.New("Audit mode tests").
+		Setup(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// get namespace
+			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
 
-			// patch the pod, this should FAIL!
-			err = client.Resources(namespace).PatchSubresource(ctx, pod, "ephemeralcontainers", patch)
-			if err == nil {
-				t.Fatal("ephemeral container without securityContext.Capabilities.Drop['ALL'] should be rejected")
+			// switch the namespace from the deny to the audit binding
+			err := testutils.AddNamespaceLabels(ctx, cfg, namespace, map[string]string{"vap-library.com/pss-capabilities": "audit"})
+			if err != nil {
+				t.Fatal(err)
 			}
 
 			return ctx
 		}).
-		Assess("A valid ephemeral container is accepted", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+		Assess("A Pod with a not allowed capability is accepted and audited", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
 			// get namespace
 			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
 
-			// get client
-			client, err := cfg.NewClient()
-			if err != nil {
-				t.Fatal(err)
-			}
+			// the audit events are only available with the in-process backend or the audit log
+			testutils.SkipWithoutAuditLog(ctx, t)
 
-			// get the pod that was created in setup to attach an ephemeral container to it
-			pod := &v1.Pod{}
-			err = client.Resources(namespace).Get(ctx, "capabilities-ephemeral", namespace, pod)
-			if err != nil {
-				t.Fatal(err)
-			}
+			// this should PASS and be audited!
+			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerYAML, "rejected", namespace, "ALL", "NOT_ALLOWED"))
+			testutils.ExpectAllowed(t, err)
+			testutils.ExpectAudited(ctx, t, namespace, "pss-capabilities", podsMessage)
 
-			// define patch type
-			patchType := types.StrategicMergePatchType
+			return ctx
+		})
 
-			// define patch data
-			patchData := []byte(fmt.Sprintf(containerEphemeralPatchYAML, "ALL", "NET_BIND_SERVICE"))
+	_ = testEnv.Test(t, f.Feature())
 
-			patch := k8s.Patch{PatchType: patchType, Data: patchData}
+}
 
-			// patch the pod
-			err = client.Resources(namespace).PatchSubresource(ctx, pod, "ephemeralcontainers", patch)
-			if err != nil {
-				t.Fatal(err)
-			}
+func TestEphemeralContainers(t *testing.T) {
+
+	f := features.New("Ephemeral containers added through the ephemeralcontainers subresource").
+		Assess("An invalid ephemeral container is rejected", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// get namespace
+			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
+
+			// add the ephemeral container like kubectl debug does, this should FAIL!
+			err := testutils.ApplyEphemeralContainerFromYAML(ctx, cfg, fmt.Sprintf(containerYAML, "subresource", namespace, "ALL", "NET_BIND_SERVICE"), fmt.Sprintf(ephemeralContainerYAML, "CAP_NET_RAW", "NET_BIND_SERVICE"))
+			testutils.ExpectDenied(t, err, "pss-capabilities", podsMessage)
+
+			return ctx
+		}).
+		Assess("A valid ephemeral container is accepted", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// get namespace
+			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
+
+			// add the ephemeral container like kubectl debug does
+			err := testutils.ApplyEphemeralContainerFromYAML(ctx, cfg, fmt.Sprintf(containerYAML, "subresource", namespace, "ALL", "NET_BIND_SERVICE"), fmt.Sprintf(ephemeralContainerYAML, "ALL", "NET_BIND_SERVICE"))
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		})
 	_ = testEnv.Test(t, f.Feature())
 
 }
+
+func TestWorkloadKinds(t *testing.T) {
+
+	testutils.RunWorkloadTestCases(t, testEnv,
+		testutils.WorkloadTestCase{
+			Name:     "Workloads with an invalid container",
+			PodSpec:  fmt.Sprintf(podSpecYAML, "CAP_NET_RAW"),
+			Expect:   testutils.Expectation{Result: testutils.ResultDenied},
+			Messages: testutils.WorkloadMessages(podsMessage, workloadsMessage, cronJobsMessage, podTemplatesMessage),
+		},
+		testutils.WorkloadTestCase{
+			Name:    "Workloads with a valid container",
+			PodSpec: fmt.Sprintf(podSpecYAML, "ALL"),
+			Expect:  testutils.Expectation{Result: testutils.ResultAllowed},
+		},
+	)
+
+}
+
+func TestDifferential(t *testing.T) {
+
+	// compare the policy with the check of k8s.io/pod-security-admission
+	testutils.RunDifferentialTests(t, testEnv, "pss-capabilities", testutils.KnownDifferenceWindows)
+
+}
@@ -6,18 +6,22 @@ import (
 	"log"
 	"os"
 	"testing"
-	"time"
 	"vap-library/testutils"
 
-	v1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/types"
-	"sigs.k8s.io/e2e-framework/klient/k8s"
 	"sigs.k8s.io/e2e-framework/pkg/envconf"
 	"sigs.k8s.io/e2e-framework/pkg/features"
 
 	"sigs.k8s.io/e2e-framework/pkg/env"
 )
 
+// expected denial messages
+const (
+	podsMessage         = "securityContext.allowPrivilegeEscalation must be set to false on any containers, initContainers, and ephemeralContainers in Pods"
+	workloadsMessage    = "securityContext.allowPrivilegeEscalation must be set to false on containers in Workloads"
+	podTemplatesMessage = "securityContext.allowPrivilegeEscalation must be set to false on containers in PodTemplates"
+	cronJobsMessage     = "securityContext.allowPrivilegeEscalation must be set to false on containers in CronJobs"
+)
+
 // TEST DATA FOR POD TESTS
 
 var containerYAML string = `
@@ -457,27 +461,26 @@ template:
         allowPrivilegeEscalation: %s
 `
 
-// TEST DATA FOR PATCHING A POD TO ADD AN EPHEMERAL CONTAINER
-
-var containerEphemeralPatchYAML string = `
-{
-  "spec": {
-    "ephemeralContainers": [
-      {
-         "image": "public.ecr.aws/docker/library/busybox:1.36",
-         "name": "ephemeral",
-         "resources": {},
-         "securityContext": {
-           "allowPrivilegeEscalation": %s
-         },
-         "stdin": true,
-         "targetContainerName": "privilege-escalation-ephemeral",
-         "terminationMessagePolicy": "File",
-         "tty": true
-      }
-    ]
-  }
-}
+// TEST DATA FOR ADDING AN EPHEMERAL CONTAINER THROUGH THE EPHEMERALCONTAINERS SUBRESOURCE
+
+var ephemeralContainerYAML string = `
+name: debugger
+image: public.ecr.aws/docker/library/busybox:1.36
+stdin: true
+tty: true
+targetContainerName: privilege-escalation-subresource
+securityContext:
+  allowPrivilegeEscalation: %s
+`
+
+// TEST DATA FOR THE WORKLOAD TESTS, THE PODSPEC IS WRAPPED INTO ALL THE WORKLOAD KINDS
+
+var podSpecYAML string = `
+containers:
+- name: privilege-escalation
+  image: public.ecr.aws/docker/library/busybox:1.36
+  securityContext:
+    allowPrivilegeEscalation: %s
 `
 
 var testEnv env.Environment
@@ -492,9 +495,6 @@ func TestMain(m *testing.M) {
 		log.Fatalf("Unable to create Kind cluster for test. Error msg: %s", err)
 	}
 
-	// wait for the cluster to be ready
-	time.Sleep(2 * time.Second)
-
 	os.Exit(testEnv.Run(m))
 }
 
@@ -508,9 +508,7 @@ func TestPrivilegeEscalation(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerYAML, "success", namespace, "success", "false"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -520,9 +518,7 @@ func TestPrivilegeEscalation(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerYAML, "rejected", namespace, "rejected", "true"))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "pss-privilege-escalation", podsMessage)
 
 			return ctx
 		}).
@@ -532,9 +528,7 @@ func TestPrivilegeEscalation(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerYAML, "success", namespace, "success", "false", "success", "false"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -544,9 +538,7 @@ func TestPrivilegeEscalation(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerYAML, "rejected", namespace, "rejected", "false", "rejected", "true"))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "pss-privilege-escalation", podsMessage)
 
 			return ctx
 		}).
@@ -557,9 +549,7 @@ func TestPrivilegeEscalation(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerDeploymentYAML, "success", namespace, "success", "false"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -569,9 +559,7 @@ func TestPrivilegeEscalation(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerDeploymentYAML, "rejected", namespace, "rejected", "true"))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "pss-privilege-escalation", workloadsMessage)
 
 			return ctx
 		}).
@@ -581,9 +569,7 @@ func TestPrivilegeEscalation(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerDeploymentYAML, "success", namespace, "success", "false", "success", "false"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -593,9 +579,7 @@ func TestPrivilegeEscalation(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerDeploymentYAML, "rejected", namespace, "rejected", "false", "rejected", "true"))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "pss-privilege-escalation", workloadsMessage)
 
 			return ctx
 		}).
@@ -606,9 +590,7 @@ func TestPrivilegeEscalation(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerRSYAML, "success", namespace, "success", "false"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -618,9 +600,7 @@ func TestPrivilegeEscalation(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerRSYAML, "rejected", namespace, "rejected", "true"))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "pss-privilege-escalation", workloadsMessage)
 
 			return ctx
 		}).
@@ -630,9 +610,7 @@ func TestPrivilegeEscalation(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerRSYAML, "success", namespace, "success", "false", "success", "false"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -642,9 +620,7 @@ func TestPrivilegeEscalation(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerRSYAML, "rejected", namespace, "rejected", "false", "rejected", "true"))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "pss-privilege-escalation", workloadsMessage)
 
 			return ctx
 		}).
@@ -655,9 +631,7 @@ func TestPrivilegeEscalation(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerDSYAML, "success", namespace, "success", "false"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -667,9 +641,7 @@ func TestPrivilegeEscalation(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerDSYAML, "rejected", namespace, "rejected", "true"))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "pss-privilege-escalation", workloadsMessage)
 
 			return ctx
 		}).
@@ -679,9 +651,7 @@ func TestPrivilegeEscalation(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerDSYAML, "success", namespace, "success", "false", "success", "false"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -691,9 +661,7 @@ func TestPrivilegeEscalation(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerDSYAML, "rejected", namespace, "rejected", "false", "rejected", "true"))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "pss-privilege-escalation", workloadsMessage)
 
 			return ctx
 		}).
@@ -704,9 +672,7 @@ func TestPrivilegeEscalation(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerSSYAML, "success", namespace, "success", "false"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -716,9 +682,7 @@ func TestPrivilegeEscalation(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerSSYAML, "rejected", namespace, "rejected", "true"))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "pss-privilege-escalation", workloadsMessage)
 
 			return ctx
 		}).
@@ -728,9 +692,7 @@ func TestPrivilegeEscalation(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerSSYAML, "success", namespace, "success", "false", "success", "false"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -740,9 +702,7 @@ func TestPrivilegeEscalation(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerSSYAML, "rejected", namespace, "rejected", "false", "rejected", "true"))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "pss-privilege-escalation", workloadsMessage)
 
 			return ctx
 		}).
@@ -753,9 +713,7 @@ func TestPrivilegeEscalation(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerJobYAML, "success", namespace, "success", "false"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -765,9 +723,7 @@ func TestPrivilegeEscalation(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerJobYAML, "rejected", namespace, "rejected", "true"))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "pss-privilege-escalation", workloadsMessage)
 
 			return ctx
 		}).
@@ -777,9 +733,7 @@ func TestPrivilegeEscalation(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerJobYAML, "success", namespace, "success", "false", "success", "false"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -789,9 +743,7 @@ func TestPrivilegeEscalation(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerJobYAML, "rejected", namespace, "rejected", "false", "rejected", "true"))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "pss-privilege-escalation", workloadsMessage)
 
 			return ctx
 		}).
@@ -802,9 +754,7 @@ func TestPrivilegeEscalation(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerCronJobYAML, "success", namespace, "success", "false"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -814,9 +764,7 @@ func TestPrivilegeEscalation(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerCronJobYAML, "rejected", namespace, "rejected", "true"))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "pss-privilege-escalation", cronJobsMessage)
 
 			return ctx
 		}).
@@ -826,9 +774,7 @@ func TestPrivilegeEscalation(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerCronJobYAML, "success", namespace, "success", "false", "success", "false"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -838,9 +784,7 @@ func TestPrivilegeEscalation(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerCronJobYAML, "rejected", namespace, "rejected", "false", "rejected", "true"))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "pss-privilege-escalation", cronJobsMessage)
 
 			return ctx
 		}).
@@ -851,9 +795,7 @@ func TestPrivilegeEscalation(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerRCYAML, "success", namespace, "success", "false"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -863,9 +805,7 @@ func TestPrivilegeEscalation(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerRCYAML, "rejected", namespace, "rejected", "true"))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "pss-privilege-escalation", workloadsMessage)
 
 			return ctx
 		}).
@@ -875,9 +815,7 @@ func TestPrivilegeEscalation(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerRCYAML, "success", namespace, "success", "false", "success", "false"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -887,9 +825,7 @@ func TestPrivilegeEscalation(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerRCYAML, "rejected", namespace, "rejected", "false", "rejected", "true"))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "pss-privilege-escalation", workloadsMessage)
 
 			return ctx
 		}).
@@ -900,9 +836,7 @@ func TestPrivilegeEscalation(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerPodTemplateYAML, "success", namespace, "success", "false"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -912,9 +846,7 @@ func TestPrivilegeEscalation(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerPodTemplateYAML, "rejected", namespace, "rejected", "true"))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "pss-privilege-escalation", podTemplatesMessage)
 
 			return ctx
 		}).
@@ -924,9 +856,7 @@ func TestPrivilegeEscalation(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerPodTemplateYAML, "success", namespace, "success", "false", "success", "false"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -936,9 +866,7 @@ func TestPrivilegeEscalation(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerPodTemplateYAML, "rejected", namespace, "rejected", "false", "rejected", "true"))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "pss-privilege-escalation", podTemplatesMessage)
 
 			return ctx
 		})
@@ -947,91 +875,119 @@ func TestPrivilegeEscalation(t *testing.T) {
 
 }
 
-func TestEphemeralContainers(t *testing.T) {
+func TestWarnMode(t *testing.T) {
 
-	f := features.New("Pods with ephemeral containers").
+	f := features.New("Warn mode tests").
 		Setup(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
 			// get namespace
 			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
 
-			// create a pod that will be used for ephemeral container tests
-			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerYAML, "ephemeral", namespace, "ephemeral", "false"))
+			// switch the namespace from the deny to the warn binding
+			err := testutils.AddNamespaceLabels(ctx, cfg, namespace, map[string]string{"vap-library.com/pss-privilege-escalation": "warn"})
 			if err != nil {
 				t.Fatal(err)
 			}
 
-			// wait for the pod
-			time.Sleep(2 * time.Second)
-
 			return ctx
 		}).
-		Assess("An invalid ephemeral container is rejected", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+		Assess("A Pod that allows privilege escalation is accepted with a warning", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
 			// get namespace
 			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
 
-			// get client
-			client, err := cfg.NewClient()
-			if err != nil {
-				t.Fatal(err)
-			}
+			// this should PASS with a warning!
+			warnings, err := testutils.ApplyK8sResourceFromYAMLWithWarnings(ctx, cfg, fmt.Sprintf(containerYAML, "rejected", namespace, "rejected", "true"))
+			testutils.ExpectAllowed(t, err)
+			testutils.ExpectWarned(t, warnings, podsMessage)
 
-			// get the pod that was created in setup to attach an ephemeral container to it
-			pod := &v1.Pod{}
-			err = client.Resources(namespace).Get(ctx, "privilege-escalation-ephemeral", namespace, pod)
-			if err != nil {
-				t.Fatal(err)
-			}
+			return ctx
+		})
 
-			// define patch type
-			patchType := types.StrategicMergePatchType
+	_ = testEnv.Test(t, f.Feature())
 
-			// define patch data
-			patchData := []byte(fmt.Sprintf(containerEphemeralPatchYAML, "true"))
+}
 
-			patch := k8s.Patch{PatchType: patchType, Data: patchData}
+func TestAuditMode(t *testing.T) {
+
+	f := features.New("Audit mode tests").
+		Setup(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// get namespace
+			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
 
-			// patch the pod, this should FAIL!
-			err = client.Resources(namespace).PatchSubresource(ctx, pod, "ephemeralcontainers", patch)
-			if err == nil {
-				t.Fatal("ephemeral container without securityContext.allowPrivilegeEscalation field should be rejected")
+			// switch the namespace from the deny to the audit binding
+			err := testutils.AddNamespaceLabels(ctx, cfg, namespace, map[string]string{"vap-library.com/pss-privilege-escalation": "audit"})
+			if err != nil {
+				t.Fatal(err)
 			}
 
 			return ctx
 		}).
-		Assess("A valid ephemeral container is accepted", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+		Assess("A Pod that allows privilege escalation is accepted and audited", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
 			// get namespace
 			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
 
-			// get client
-			client, err := cfg.NewClient()
-			if err != nil {
-				t.Fatal(err)
-			}
+			// the audit events are only available with the in-process backend or the audit log
+			testutils.SkipWithoutAuditLog(ctx, t)
 
-			// get the pod that was created in setup to attach an ephemeral container to it
-			pod := &v1.Pod{}
-			err = client.Resources(namespace).Get(ctx, "privilege-escalation-ephemeral", namespace, pod)
-			if err != nil {
-				t.Fatal(err)
-			}
+			// this should PASS and be audited!
+			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerYAML, "rejected", namespace, "rejected", "true"))
+			testutils.ExpectAllowed(t, err)
+			testutils.ExpectAudited(ctx, t, namespace, "pss-privilege-escalation", podsMessage)
 
-			// define patch type
-			patchType := types.StrategicMergePatchType
+			return ctx
+		})
 
-			// define patch data
-			patchData := []byte(fmt.Sprintf(containerEphemeralPatchYAML, "false"))
+	_ = testEnv.Test(t, f.Feature())
 
-			patch := k8s.Patch{PatchType: patchType, Data: patchData}
+}
 
-			// patch the pod
-			err = client.Resources(namespace).PatchSubresource(ctx, pod, "ephemeralcontainers", patch)
-			if err != nil {
-				t.Fatal(err)
-			}
+func TestEphemeralContainers(t *testing.T) {
+
+	f := features.New("Ephemeral containers added through the ephemeralcontainers subresource").
+		Assess("An invalid ephemeral container is rejected", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// get namespace
+			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
+
+			// add the ephemeral container like kubectl debug does, this should FAIL!
+			err := testutils.ApplyEphemeralContainerFromYAML(ctx, cfg, fmt.Sprintf(containerYAML, "subresource", namespace, "subresource", "false"), fmt.Sprintf(ephemeralContainerYAML, "true"))
+			testutils.ExpectDenied(t, err, "pss-privilege-escalation", podsMessage)
 
 			return ctx
-		})
+		}).
+		Assess("A valid ephemeral container is accepted", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// get namespace
+			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
+
+			// add the ephemeral container like kubectl debug does
+			err := testutils.ApplyEphemeralContainerFromYAML(ctx, cfg, fmt.Sprintf(containerYAML, "subresource", namespace, "subresource", "false"), fmt.Sprintf(ephemeralContainerYAML, "false"))
+			testutils.ExpectAllowed(t, err)
 
+			return ctx
+		})
 	_ = testEnv.Test(t, f.Feature())
 
 }
+
+func TestWorkloadKinds(t *testing.T) {
+
+	testutils.RunWorkloadTestCases(t, testEnv,
+		testutils.WorkloadTestCase{
+			Name:     "Workloads with an invalid container",
+			PodSpec:  fmt.Sprintf(podSpecYAML, "true"),
+			Expect:   testutils.Expectation{Result: testutils.ResultDenied},
+			Messages: testutils.WorkloadMessages(podsMessage, workloadsMessage, cronJobsMessage, podTemplatesMessage),
+		},
+		testutils.WorkloadTestCase{
+			Name:    "Workloads with a valid container",
+			PodSpec: fmt.Sprintf(podSpecYAML, "false"),
+			Expect:  testutils.Expectation{Result: testutils.ResultAllowed},
+		},
+	)
+
+}
+
+func TestDifferential(t *testing.T) {
+
+	// compare the policy with the check of k8s.io/pod-security-admission
+	testutils.RunDifferentialTests(t, testEnv, "pss-privilege-escalation", testutils.KnownDifferenceWindows)
+
+}
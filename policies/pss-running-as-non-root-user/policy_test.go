@@ -8,17 +8,21 @@ import (
 	"log"
 	"os"
 	"testing"
-	"time"
 	"vap-library/testutils"
 
-	v1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/types"
-	"sigs.k8s.io/e2e-framework/klient/k8s"
 	"sigs.k8s.io/e2e-framework/pkg/env"
 	"sigs.k8s.io/e2e-framework/pkg/envconf"
 	"sigs.k8s.io/e2e-framework/pkg/features"
 )
 
+// expected denial messages
+const (
+	podsMessage         = "securityContext.runAsUser must not equal 0, root user id, on any containers, initContainers, and ephemeralContainers in Pods"
+	workloadsMessage    = "securityContext.runAsUser must not equal 0 (root user id) on containers in Workloads"
+	podTemplatesMessage = "securityContext.runAsUser must not equal 0 (root user id) on containers in PodTemplates"
+	cronJobsMessage     = "securityContext.runAsUser must not equal 0 (root user id) on containers in CronJobs"
+)
+
 // TEST DATA FOR POD TESTS
 
 var containerYAML string = `
@@ -838,27 +842,26 @@ template:
         runAsUser: %s
 `
 
-// TEST DATA FOR PATCHING A POD TO ADD AN EPHEMERAL CONTAINER
-
-var containerEphemeralPatchYAML string = `
-{
-  "spec": {
-    "ephemeralContainers": [
-      {
-         "image": "public.ecr.aws/docker/library/busybox:1.36",
-         "name": "ephemeral",
-         "resources": {},
-         "securityContext": {
-           "runAsUser": %s
-         },
-         "stdin": true,
-         "targetContainerName": "running-as-non-root-user-ephemeral",
-         "terminationMessagePolicy": "File",
-         "tty": true
-      }
-    ]
-  }
-}
+// TEST DATA FOR ADDING AN EPHEMERAL CONTAINER THROUGH THE EPHEMERALCONTAINERS SUBRESOURCE
+
+var ephemeralContainerYAML string = `
+name: debugger
+image: public.ecr.aws/docker/library/busybox:1.36
+stdin: true
+tty: true
+targetContainerName: running-as-non-root-user-subresource
+securityContext:
+  runAsUser: %s
+`
+
+// TEST DATA FOR THE WORKLOAD TESTS, THE PODSPEC IS WRAPPED INTO ALL THE WORKLOAD KINDS
+
+var podSpecYAML string = `
+containers:
+- name: running-as-non-root-user
+  image: public.ecr.aws/docker/library/busybox:1.36
+  securityContext:
+    runAsUser: %s
 `
 
 var testEnv env.Environment
@@ -873,9 +876,6 @@ func TestMain(m *testing.M) {
 		log.Fatalf("Unable to create Kind cluster for test. Error msg: %s", err)
 	}
 
-	// wait for the cluster to be ready
-	time.Sleep(2 * time.Second)
-
 	os.Exit(testEnv.Run(m))
 }
 
@@ -889,9 +889,7 @@ func TestRunningAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerYAML, "success", namespace, "success", "100"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -901,9 +899,7 @@ func TestRunningAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerNoRunAsUserYAML, "success", namespace, "success"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -913,9 +909,7 @@ func TestRunningAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerYAML, "rejected", namespace, "rejected", "0"))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root-user", podsMessage)
 
 			return ctx
 		}).
@@ -925,9 +919,7 @@ func TestRunningAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerWithDefaultYAML, "rejected", namespace, "0", "rejected", "100"))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root-user", podsMessage)
 
 			return ctx
 		}).
@@ -937,9 +929,7 @@ func TestRunningAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerYAML, "rejected", namespace, "rejected", "100", "rejected", "0"))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root-user", podsMessage)
 
 			return ctx
 		}).
@@ -950,9 +940,7 @@ func TestRunningAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerDeploymentYAML, "success", namespace, "success", "100"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).Assess("Successful deployment of a Deployment with container as runAsUser is not set", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
@@ -961,9 +949,7 @@ func TestRunningAsNonRoot(t *testing.T) {
 
 		// this should PASS!
 		err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerDeploymentNoRunAsUserYAML, "success", namespace, "success"))
-		if err != nil {
-			t.Fatal(err)
-		}
+		testutils.ExpectAllowed(t, err)
 
 		return ctx
 	}).
@@ -973,9 +959,7 @@ func TestRunningAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerDeploymentYAML, "rejected", namespace, "rejected", "0"))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root-user", workloadsMessage)
 
 			return ctx
 		}).
@@ -985,9 +969,7 @@ func TestRunningAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerDeploymentWithDefaultYAML, "rejected", namespace, "0", "rejected", "100"))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root-user", workloadsMessage)
 
 			return ctx
 		}).
@@ -997,9 +979,7 @@ func TestRunningAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerDeploymentYAML, "rejected", namespace, "rejected", "100", "rejected", "0"))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root-user", workloadsMessage)
 
 			return ctx
 		}).
@@ -1010,9 +990,7 @@ func TestRunningAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerRSYAML, "success", namespace, "success", "100"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).Assess("Successful deployment of a ReplicaSet with container as runAsUser is not set", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
@@ -1021,9 +999,7 @@ func TestRunningAsNonRoot(t *testing.T) {
 
 		// this should PASS!
 		err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerRSNoRunAsUserYAML, "success", namespace, "success"))
-		if err != nil {
-			t.Fatal(err)
-		}
+		testutils.ExpectAllowed(t, err)
 
 		return ctx
 	}).
@@ -1033,9 +1009,7 @@ func TestRunningAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerRSYAML, "rejected", namespace, "rejected", "0"))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root-user", workloadsMessage)
 
 			return ctx
 		}).
@@ -1045,9 +1019,7 @@ func TestRunningAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerRSWithDefaultYAML, "rejected", namespace, "0", "rejected", "100"))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root-user", workloadsMessage)
 
 			return ctx
 		}).
@@ -1057,9 +1029,7 @@ func TestRunningAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerRSYAML, "rejected", namespace, "rejected", "100", "rejected", "0"))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root-user", workloadsMessage)
 
 			return ctx
 		}).
@@ -1070,9 +1040,7 @@ func TestRunningAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerDSYAML, "success", namespace, "success", "100"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).Assess("Successful deployment of a DaemonSet with container as runAsUser is not set", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
@@ -1081,9 +1049,7 @@ func TestRunningAsNonRoot(t *testing.T) {
 
 		// this should PASS!
 		err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerDSNoRunAsUserYAML, "success", namespace, "success"))
-		if err != nil {
-			t.Fatal(err)
-		}
+		testutils.ExpectAllowed(t, err)
 
 		return ctx
 	}).
@@ -1093,9 +1059,7 @@ func TestRunningAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerDSYAML, "rejected", namespace, "rejected", "0"))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root-user", workloadsMessage)
 
 			return ctx
 		}).
@@ -1105,9 +1069,7 @@ func TestRunningAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerDSWithDefaultYAML, "rejected", namespace, "0", "rejected", "100"))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root-user", workloadsMessage)
 
 			return ctx
 		}).
@@ -1117,9 +1079,7 @@ func TestRunningAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerDSYAML, "rejected", namespace, "rejected", "100", "rejected", "0"))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root-user", workloadsMessage)
 
 			return ctx
 		}).
@@ -1130,9 +1090,7 @@ func TestRunningAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerSSYAML, "success", namespace, "success", "100"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).Assess("Successful deployment of a StatefulSet with container as runAsUser is not set", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
@@ -1141,9 +1099,7 @@ func TestRunningAsNonRoot(t *testing.T) {
 
 		// this should PASS!
 		err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerSSNoRunAsUserYAML, "success", namespace, "success"))
-		if err != nil {
-			t.Fatal(err)
-		}
+		testutils.ExpectAllowed(t, err)
 
 		return ctx
 	}).
@@ -1153,9 +1109,7 @@ func TestRunningAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerSSYAML, "rejected", namespace, "rejected", "0"))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root-user", workloadsMessage)
 
 			return ctx
 		}).
@@ -1165,9 +1119,7 @@ func TestRunningAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerSSWithDefaultYAML, "rejected", namespace, "0", "rejected", "100"))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root-user", workloadsMessage)
 
 			return ctx
 		}).
@@ -1177,9 +1129,7 @@ func TestRunningAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerSSYAML, "rejected", namespace, "rejected", "100", "rejected", "0"))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root-user", workloadsMessage)
 
 			return ctx
 		}).
@@ -1190,9 +1140,7 @@ func TestRunningAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerJobYAML, "success", namespace, "success", "100"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).Assess("Successful deployment of a Job with container as runAsUser is not set", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
@@ -1201,9 +1149,7 @@ func TestRunningAsNonRoot(t *testing.T) {
 
 		// this should PASS!
 		err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerJobNoRunAsUserYAML, "success", namespace, "success"))
-		if err != nil {
-			t.Fatal(err)
-		}
+		testutils.ExpectAllowed(t, err)
 
 		return ctx
 	}).
@@ -1213,9 +1159,7 @@ func TestRunningAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerJobYAML, "rejected", namespace, "rejected", "0"))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root-user", workloadsMessage)
 
 			return ctx
 		}).
@@ -1225,9 +1169,7 @@ func TestRunningAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerJobWithDefaultYAML, "rejected", namespace, "0", "rejected", "100"))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root-user", workloadsMessage)
 
 			return ctx
 		}).
@@ -1237,9 +1179,7 @@ func TestRunningAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerJobYAML, "rejected", namespace, "rejected", "100", "rejected", "0"))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root-user", workloadsMessage)
 
 			return ctx
 		}).
@@ -1250,9 +1190,7 @@ func TestRunningAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerCronJobYAML, "success", namespace, "success", "100"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).Assess("Successful deployment of a CronJob with container as runAsUser is not set", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
@@ -1261,9 +1199,7 @@ func TestRunningAsNonRoot(t *testing.T) {
 
 		// this should PASS!
 		err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerCronJobNoRunAsUserYAML, "success", namespace, "success"))
-		if err != nil {
-			t.Fatal(err)
-		}
+		testutils.ExpectAllowed(t, err)
 
 		return ctx
 	}).
@@ -1273,9 +1209,7 @@ func TestRunningAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerCronJobYAML, "rejected", namespace, "rejected", "0"))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root-user", cronJobsMessage)
 
 			return ctx
 		}).
@@ -1285,9 +1219,7 @@ func TestRunningAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerCronJobWithDefaultYAML, "rejected", namespace, "0", "rejected", "100"))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root-user", cronJobsMessage)
 
 			return ctx
 		}).
@@ -1297,9 +1229,7 @@ func TestRunningAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerCronJobYAML, "rejected", namespace, "rejected", "100", "rejected", "0"))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root-user", cronJobsMessage)
 
 			return ctx
 		}).
@@ -1310,9 +1240,7 @@ func TestRunningAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerRCYAML, "success", namespace, "success", "100"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).Assess("Successful deployment of a ReplicationController with container as runAsUser is not set", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
@@ -1321,9 +1249,7 @@ func TestRunningAsNonRoot(t *testing.T) {
 
 		// this should PASS!
 		err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerRCNoRunAsUserYAML, "success", namespace, "success"))
-		if err != nil {
-			t.Fatal(err)
-		}
+		testutils.ExpectAllowed(t, err)
 
 		return ctx
 	}).
@@ -1333,9 +1259,7 @@ func TestRunningAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerRCYAML, "rejected", namespace, "rejected", "0"))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root-user", workloadsMessage)
 
 			return ctx
 		}).
@@ -1345,9 +1269,7 @@ func TestRunningAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerRCWithDefaultYAML, "rejected", namespace, "0", "rejected", "100"))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root-user", workloadsMessage)
 
 			return ctx
 		}).
@@ -1357,9 +1279,7 @@ func TestRunningAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerRCYAML, "rejected", namespace, "rejected", "100", "rejected", "0"))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root-user", workloadsMessage)
 
 			return ctx
 		}).
@@ -1370,9 +1290,7 @@ func TestRunningAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerPodTemplateYAML, "success", namespace, "success", "100"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).Assess("Successful deployment of a PodTemplate with container as runAsUser is not set", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
@@ -1381,9 +1299,7 @@ func TestRunningAsNonRoot(t *testing.T) {
 
 		// this should PASS!
 		err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerPodTemplateNoRunAsUserYAML, "success", namespace, "success"))
-		if err != nil {
-			t.Fatal(err)
-		}
+		testutils.ExpectAllowed(t, err)
 
 		return ctx
 	}).
@@ -1393,9 +1309,7 @@ func TestRunningAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerPodTemplateYAML, "rejected", namespace, "rejected", "0"))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root-user", podTemplatesMessage)
 
 			return ctx
 		}).
@@ -1405,9 +1319,7 @@ func TestRunningAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerPodTemplateWithDefaultYAML, "rejected", namespace, "0", "rejected", "100"))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root-user", podTemplatesMessage)
 
 			return ctx
 		}).
@@ -1417,9 +1329,7 @@ func TestRunningAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerPodTemplateYAML, "rejected", namespace, "rejected", "100", "rejected", "0"))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root-user", podTemplatesMessage)
 
 			return ctx
 		})
@@ -1428,90 +1338,119 @@ func TestRunningAsNonRoot(t *testing.T) {
 
 }
 
-func TestEphemeralContainers(t *testing.T) {
+func TestWarnMode(t *testing.T) {
 
-	f := features.New("Pods with ephemeral containers").
+	f := features.New("Warn mode tests").
 		Setup(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
 			// get namespace
 			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
 
-			// create a pod that will be used for ephemeral container tests
-			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerYAML, "ephemeral", namespace, "ephemeral", "100"))
+			// switch the namespace from the deny to the warn binding
+			err := testutils.AddNamespaceLabels(ctx, cfg, namespace, map[string]string{"vap-library.com/pss-running-as-non-root-user": "warn"})
 			if err != nil {
 				t.Fatal(err)
 			}
 
-			// wait for the pod
-			time.Sleep(2 * time.Second)
-
 			return ctx
 		}).
-		Assess("An invalid ephemeral container is rejected", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+		Assess("A Pod running as the root user is accepted with a warning", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
 			// get namespace
 			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
 
-			// get client
-			client, err := cfg.NewClient()
-			if err != nil {
-				t.Fatal(err)
-			}
+			// this should PASS with a warning!
+			warnings, err := testutils.ApplyK8sResourceFromYAMLWithWarnings(ctx, cfg, fmt.Sprintf(containerYAML, "rejected", namespace, "rejected", "0"))
+			testutils.ExpectAllowed(t, err)
+			testutils.ExpectWarned(t, warnings, podsMessage)
 
-			// get the pod that was created in setup to attach an ephemeral container to it
-			pod := &v1.Pod{}
-			err = client.Resources(namespace).Get(ctx, "running-as-non-root-user-ephemeral", namespace, pod)
-			if err != nil {
-				t.Fatal(err)
-			}
+			return ctx
+		})
 
-			// define patch type
-			patchType := types.StrategicMergePatchType
+	_ = testEnv.Test(t, f.Feature())
 
-			// define patch data
-			patchData := []byte(fmt.Sprintf(containerEphemeralPatchYAML, "0"))
+}
 
-			patch := k8s.Patch{PatchType: patchType, Data: patchData}
+func TestAuditMode(t *testing.T) {
+
+	f := features.New("Audit mode tests").
+		Setup(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// get namespace
+			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
 
-			// patch the pod, this should FAIL!
-			err = client.Resources(namespace).PatchSubresource(ctx, pod, "ephemeralcontainers", patch)
-			if err == nil {
-				t.Fatal("ephemeral container with securityContext.runAsUser field set to 0 should be rejected")
+			// switch the namespace from the deny to the audit binding
+			err := testutils.AddNamespaceLabels(ctx, cfg, namespace, map[string]string{"vap-library.com/pss-running-as-non-root-user": "audit"})
+			if err != nil {
+				t.Fatal(err)
 			}
 
 			return ctx
 		}).
-		Assess("A valid ephemeral container is accepted", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+		Assess("A Pod running as the root user is accepted and audited", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
 			// get namespace
 			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
 
-			// get client
-			client, err := cfg.NewClient()
-			if err != nil {
-				t.Fatal(err)
-			}
+			// the audit events are only available with the in-process backend or the audit log
+			testutils.SkipWithoutAuditLog(ctx, t)
 
-			// get the pod that was created in setup to attach an ephemeral container to it
-			pod := &v1.Pod{}
-			err = client.Resources(namespace).Get(ctx, "running-as-non-root-user-ephemeral", namespace, pod)
-			if err != nil {
-				t.Fatal(err)
-			}
+			// this should PASS and be audited!
+			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerYAML, "rejected", namespace, "rejected", "0"))
+			testutils.ExpectAllowed(t, err)
+			testutils.ExpectAudited(ctx, t, namespace, "pss-running-as-non-root-user", podsMessage)
 
-			// define patch type
-			patchType := types.StrategicMergePatchType
+			return ctx
+		})
 
-			// define patch data
-			patchData := []byte(fmt.Sprintf(containerEphemeralPatchYAML, "100"))
+	_ = testEnv.Test(t, f.Feature())
 
-			patch := k8s.Patch{PatchType: patchType, Data: patchData}
+}
 
-			// patch the pod
-			err = client.Resources(namespace).PatchSubresource(ctx, pod, "ephemeralcontainers", patch)
-			if err != nil {
-				t.Fatal(err)
-			}
+func TestEphemeralContainers(t *testing.T) {
+
+	f := features.New("Ephemeral containers added through the ephemeralcontainers subresource").
+		Assess("An invalid ephemeral container is rejected", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// get namespace
+			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
+
+			// add the ephemeral container like kubectl debug does, this should FAIL!
+			err := testutils.ApplyEphemeralContainerFromYAML(ctx, cfg, fmt.Sprintf(containerYAML, "subresource", namespace, "subresource", "100"), fmt.Sprintf(ephemeralContainerYAML, "0"))
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root-user", podsMessage)
+
+			return ctx
+		}).
+		Assess("A valid ephemeral container is accepted", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// get namespace
+			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
+
+			// add the ephemeral container like kubectl debug does
+			err := testutils.ApplyEphemeralContainerFromYAML(ctx, cfg, fmt.Sprintf(containerYAML, "subresource", namespace, "subresource", "100"), fmt.Sprintf(ephemeralContainerYAML, "100"))
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		})
 	_ = testEnv.Test(t, f.Feature())
 
 }
+
+func TestWorkloadKinds(t *testing.T) {
+
+	testutils.RunWorkloadTestCases(t, testEnv,
+		testutils.WorkloadTestCase{
+			Name:     "Workloads with an invalid container",
+			PodSpec:  fmt.Sprintf(podSpecYAML, "0"),
+			Expect:   testutils.Expectation{Result: testutils.ResultDenied},
+			Messages: testutils.WorkloadMessages(podsMessage, workloadsMessage, cronJobsMessage, podTemplatesMessage),
+		},
+		testutils.WorkloadTestCase{
+			Name:    "Workloads with a valid container",
+			PodSpec: fmt.Sprintf(podSpecYAML, "100"),
+			Expect:  testutils.Expectation{Result: testutils.ResultAllowed},
+		},
+	)
+
+}
+
+func TestDifferential(t *testing.T) {
+
+	// compare the policy with the check of k8s.io/pod-security-admission
+	testutils.RunDifferentialTests(t, testEnv, "pss-running-as-non-root-user")
+
+}
@@ -6,17 +6,22 @@ import (
 	"log"
 	"os"
 	"testing"
-	"time"
 	"vap-library/testutils"
 
 	v1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/types"
-	"sigs.k8s.io/e2e-framework/klient/k8s"
 	"sigs.k8s.io/e2e-framework/pkg/env"
 	"sigs.k8s.io/e2e-framework/pkg/envconf"
 	"sigs.k8s.io/e2e-framework/pkg/features"
 )
 
+// expected denial messages
+const (
+	podsMessage         = "securityContext.runAsNonRoot must be set to true on any containers, initContainers, and ephemeralContainers in Pods"
+	workloadsMessage    = "securityContext.runAsNonRoot must be set to true on containers in Workloads"
+	podTemplatesMessage = "securityContext.runAsNonRoot must be set to true on containers in PodTemplates"
+	cronJobsMessage     = "securityContext.runAsNonRoot must be set to true on containers in CronJobs"
+)
+
 // TEST DATA FOR POD TESTS
 
 var containerYAML string = `
@@ -1536,90 +1541,34 @@ template:
       image: public.ecr.aws/docker/library/busybox:1.36
 `
 
-// TEST DATA FOR PATCHING A POD TO ADD AN EPHEMERAL CONTAINER
-
-var containerEphemeralPatchYAML string = `
-{
-  "spec": {
-    "ephemeralContainers": [
-      {
-         "image": "public.ecr.aws/docker/library/busybox:1.36",
-         "name": "ephemeral",
-         "resources": {},
-         "securityContext": {
-           "runAsNonRoot": %s
-         },
-         "stdin": true,
-         "targetContainerName": "running-as-non-root-ephemeral",
-         "terminationMessagePolicy": "File",
-         "tty": true
-      }
-    ]
-  }
-}
+// TEST DATA FOR ADDING AN EPHEMERAL CONTAINER THROUGH THE EPHEMERALCONTAINERS SUBRESOURCE
+
+var ephemeralContainerYAML string = `
+name: %s
+image: public.ecr.aws/docker/library/busybox:1.36
+stdin: true
+tty: true
+targetContainerName: running-as-non-root-subresource
+securityContext:
+  runAsNonRoot: %s
 `
 
-var twoContainersEphemeralPatchYAML string = `
-{
-  "spec": {
-    "ephemeralContainers": [
-      {
-         "image": "public.ecr.aws/docker/library/busybox:1.36",
-         "name": "ephemeral",
-         "resources": {},
-         "securityContext": {
-           "runAsNonRoot": %s
-         },
-         "stdin": true,
-         "targetContainerName": "running-as-non-root-ephemeral",
-         "terminationMessagePolicy": "File",
-         "tty": true
-      },
-      {
-        "image": "public.ecr.aws/docker/library/busybox:1.36",
-        "name": "ephemeral-two",
-        "resources": {},
-        "securityContext": {
-          "runAsNonRoot": %s
-        },
-        "stdin": true,
-        "targetContainerName": "running-as-non-root-ephemeral",
-        "terminationMessagePolicy": "File",
-        "tty": true
-     }
-    ]
-  }
-}
+var ephemeralContainerUnsetYAML string = `
+name: %s
+image: public.ecr.aws/docker/library/busybox:1.36
+stdin: true
+tty: true
+targetContainerName: running-as-non-root-subresource
 `
 
-var twoContainersEphemeralOneUnsetPatchYAML string = `
-{
-  "spec": {
-    "ephemeralContainers": [
-      {
-         "image": "public.ecr.aws/docker/library/busybox:1.36",
-         "name": "ephemeral",
-         "resources": {},
-         "securityContext": {
-           "runAsNonRoot": %s
-         },
-         "stdin": true,
-         "targetContainerName": "running-as-non-root-ephemeral",
-         "terminationMessagePolicy": "File",
-         "tty": true
-      },
-      {
-        "image": "public.ecr.aws/docker/library/busybox:1.36",
-        "name": "ephemeral-two",
-        "resources": {},
-        "stdin": true,
-        "targetContainerName": "running-as-non-root-ephemeral",
-        "terminationMessagePolicy": "File",
-        "tty": true
-     }
-    ]
-  }
-}
+// TEST DATA FOR THE WORKLOAD TESTS, THE PODSPEC IS WRAPPED INTO ALL THE WORKLOAD KINDS
+
+var podSpecYAML string = `
+containers:
+- name: running-as-non-root
+  image: public.ecr.aws/docker/library/busybox:1.36
+  securityContext:
+    runAsNonRoot: %s
 `
 
 var testEnv env.Environment
@@ -1634,9 +1583,6 @@ func TestMain(m *testing.M) {
 		log.Fatalf("Unable to create Kind cluster for test. Error msg: %s", err)
 	}
 
-	// wait for the cluster to be ready
-	time.Sleep(2 * time.Second)
-
 	os.Exit(testEnv.Run(m))
 }
 
@@ -1650,9 +1596,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerYAML, "success", namespace, "success", "true"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1662,9 +1606,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerWithDefaultYAML, "success-default-true", namespace, "true", "success", "true"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1674,9 +1616,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerWithDefaultYAML, "success-default-false", namespace, "false", "success", "true"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1686,9 +1626,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerOnlyDefaultYAML, "success-only-default-true", namespace, "true", "success"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1698,9 +1636,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(twoContainersWithDefaultOnlyOneYAML, "success", namespace, "true", "success-01", "true", "success-02"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1710,9 +1646,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerYAML, "success", namespace, "success", "true", "success", "true"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1722,9 +1656,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerWithDefaultYAML, "success-default-true", namespace, "true", "success", "true", "success", "true"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1734,9 +1666,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerWithDefaultYAML, "success-default-false", namespace, "false", "success", "true", "success", "true"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1746,9 +1676,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerOnlyDefaultYAML, "success-only-default-false", namespace, "true", "success", "success"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1758,9 +1686,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(twoInitContainersWithDefaultOnlyOneYAML, "success", namespace, "true", "success-01", "true", "success-02", "true", "success-03"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1770,9 +1696,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerYAML, "rejected", namespace, "rejected", "false"))
-			if err == nil {
-				t.Fatal("containers without securityContext.runAsNonRoot field set to true were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", podsMessage)
 
 			return ctx
 		}).
@@ -1782,9 +1706,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerWithoutYAML, "rejected", namespace, "rejected"))
-			if err == nil {
-				t.Fatal("containers without any of the runAsNonRoot fields set to true were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", podsMessage)
 
 			return ctx
 		}).
@@ -1794,9 +1716,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerWithDefaultYAML, "rejected-default-true", namespace, "true", "rejected", "false"))
-			if err == nil {
-				t.Fatal("containers with securityContext.runAsNonRoot field set to false were accepted, when the lower-priority spec.securityContext.runAsRoot was set to true")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", podsMessage)
 
 			return ctx
 		}).
@@ -1806,9 +1726,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerWithDefaultYAML, "rejected-default-false", namespace, "false", "rejected", "false"))
-			if err == nil {
-				t.Fatal("containers without securityContext.runAsNonRoot field set to true were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", podsMessage)
 
 			return ctx
 		}).
@@ -1818,9 +1736,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerOnlyDefaultYAML, "rejected-only-default-false", namespace, "false", "rejected"))
-			if err == nil {
-				t.Fatal("containers without securityContext.runAsNonRoot field set to true were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", podsMessage)
 
 			return ctx
 		}).
@@ -1830,9 +1746,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerYAML, "rejected", namespace, "rejected", "true", "rejected", "false"))
-			if err == nil {
-				t.Fatal("initContainers without securityContext.runAsNonRoot field set to true were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", podsMessage)
 
 			return ctx
 		}).
@@ -1842,9 +1756,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerWithDefaultYAML, "rejected-default-true", namespace, "true", "rejected", "true", "rejected", "false"))
-			if err == nil {
-				t.Fatal("initContainers with securityContext.runAsNonRoot field set to false were accepted, when the lower-priority spec.securityContext.runAsRoot was set to true")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", podsMessage)
 
 			return ctx
 		}).
@@ -1854,9 +1766,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerWithDefaultYAML, "rejected-default-false", namespace, "false", "rejected", "true", "rejected", "false"))
-			if err == nil {
-				t.Fatal("initContainers without securityContext.runAsNonRoot field set to true were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", podsMessage)
 
 			return ctx
 		}).
@@ -1866,9 +1776,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerOnlyDefaultYAML, "rejected-only-default-false", namespace, "false", "rejected", "rejected"))
-			if err == nil {
-				t.Fatal("containers without securityContext.runAsNonRoot field set to true were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", podsMessage)
 
 			return ctx
 		}).
@@ -1879,9 +1787,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerDeploymentYAML, "success", namespace, "success", "true"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1891,9 +1797,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerDeploymentWithDefaultYAML, "success-default-true", namespace, "true", "success", "true"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1903,9 +1807,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerDeploymentWithDefaultYAML, "success-default-false", namespace, "false", "success", "true"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1915,9 +1817,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerDeploymentOnlyDefaultYAML, "success-only-default-true", namespace, "true", "success"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1927,9 +1827,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(twoContainersDeploymentWithDefaultOnlyOneYAML, "success-two-container-default", namespace, "true", "success-01", "true", "success-02"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1939,9 +1837,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerDeploymentYAML, "success", namespace, "success", "true", "success", "true"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1951,9 +1847,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerDeploymentWithDefaultYAML, "success-default-true", namespace, "true", "success", "true", "success", "true"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1963,9 +1857,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerDeploymentWithDefaultYAML, "success-default-false", namespace, "false", "success", "true", "success", "true"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1975,9 +1867,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerDeploymentOnlyDefaultYAML, "success-only-default-false", namespace, "true", "success", "success"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1987,9 +1877,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(twoInitContainersDeploymentWithDefaultOnlyOneYAML, "success-two-container-default", namespace, "true", "success-01", "true", "success-02", "true", "success-03"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1999,9 +1887,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerDeploymentYAML, "rejected", namespace, "rejected", "false"))
-			if err == nil {
-				t.Fatal("containers without securityContext.runAsNonRoot field set to true were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", workloadsMessage)
 
 			return ctx
 		}).
@@ -2011,9 +1897,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerDeploymentWithDefaultYAML, "rejected-default-true", namespace, "true", "rejected", "false"))
-			if err == nil {
-				t.Fatal("containers with securityContext.runAsNonRoot field set to false were accepted, when the lower-priority spec.securityContext.runAsRoot was set to true")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", workloadsMessage)
 
 			return ctx
 		}).
@@ -2023,9 +1907,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerDeploymentWithDefaultYAML, "rejected-default-false", namespace, "false", "rejected", "false"))
-			if err == nil {
-				t.Fatal("containers without securityContext.runAsNonRoot field set to true were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", workloadsMessage)
 
 			return ctx
 		}).
@@ -2035,9 +1917,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerDeploymentOnlyDefaultYAML, "rejected-only-default-false", namespace, "false", "rejected"))
-			if err == nil {
-				t.Fatal("containers without securityContext.runAsNonRoot field set to true were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", workloadsMessage)
 
 			return ctx
 		}).
@@ -2047,9 +1927,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerDeploymentYAML, "rejected", namespace, "rejected", "true", "rejected", "false"))
-			if err == nil {
-				t.Fatal("initContainers without securityContext.runAsNonRoot field set to true were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", workloadsMessage)
 
 			return ctx
 		}).
@@ -2059,9 +1937,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerDeploymentWithDefaultYAML, "rejected-default-true", namespace, "true", "rejected", "true", "rejected", "false"))
-			if err == nil {
-				t.Fatal("initContainers with securityContext.runAsNonRoot field set to false were accepted, when the lower-priority spec.securityContext.runAsRoot was set to true")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", workloadsMessage)
 
 			return ctx
 		}).
@@ -2071,9 +1947,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerDeploymentWithDefaultYAML, "rejected-default-false", namespace, "false", "rejected", "true", "rejected", "false"))
-			if err == nil {
-				t.Fatal("initContainers without securityContext.runAsNonRoot field set to true were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", workloadsMessage)
 
 			return ctx
 		}).
@@ -2083,9 +1957,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerDeploymentOnlyDefaultYAML, "rejected-only-default-false", namespace, "false", "rejected", "rejected"))
-			if err == nil {
-				t.Fatal("containers without securityContext.runAsNonRoot field set to true were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", workloadsMessage)
 
 			return ctx
 		}).
@@ -2096,9 +1968,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerRSYAML, "success", namespace, "success", "true"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2108,9 +1978,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerRSWithDefaultYAML, "success-default-true", namespace, "true", "success", "true"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2120,9 +1988,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerRSWithDefaultYAML, "success-default-false", namespace, "false", "success", "true"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2132,9 +1998,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerRSOnlyDefaultYAML, "success-only-default-true", namespace, "true", "success"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2144,9 +2008,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerRSYAML, "success", namespace, "success", "true", "success", "true"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2156,9 +2018,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerRSWithDefaultYAML, "success-default-true", namespace, "true", "success", "true", "success", "true"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2168,9 +2028,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerRSWithDefaultYAML, "success-default-false", namespace, "false", "success", "true", "success", "true"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2180,9 +2038,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerRSOnlyDefaultYAML, "success-only-default-false", namespace, "true", "success", "success"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2192,9 +2048,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerRSYAML, "rejected", namespace, "rejected", "false"))
-			if err == nil {
-				t.Fatal("containers without securityContext.runAsNonRoot field set to true were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", workloadsMessage)
 
 			return ctx
 		}).
@@ -2204,9 +2058,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerRSWithDefaultYAML, "rejected-default-true", namespace, "true", "rejected", "false"))
-			if err == nil {
-				t.Fatal("containers with securityContext.runAsNonRoot field set to false were accepted, when the lower-priority spec.securityContext.runAsRoot was set to true")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", workloadsMessage)
 
 			return ctx
 		}).
@@ -2216,9 +2068,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerRSWithDefaultYAML, "rejected-default-false", namespace, "false", "rejected", "false"))
-			if err == nil {
-				t.Fatal("containers without securityContext.runAsNonRoot field set to true were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", workloadsMessage)
 
 			return ctx
 		}).
@@ -2228,9 +2078,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerRSOnlyDefaultYAML, "rejected-only-default-false", namespace, "false", "rejected"))
-			if err == nil {
-				t.Fatal("containers without securityContext.runAsNonRoot field set to true were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", workloadsMessage)
 
 			return ctx
 		}).
@@ -2240,9 +2088,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerRSYAML, "rejected", namespace, "rejected", "true", "rejected", "false"))
-			if err == nil {
-				t.Fatal("initContainers without securityContext.runAsNonRoot field set to true were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", workloadsMessage)
 
 			return ctx
 		}).
@@ -2252,9 +2098,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerRSWithDefaultYAML, "rejected-default-true", namespace, "true", "rejected", "true", "rejected", "false"))
-			if err == nil {
-				t.Fatal("initContainers with securityContext.runAsNonRoot field set to false were accepted, when the lower-priority spec.securityContext.runAsRoot was set to true")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", workloadsMessage)
 
 			return ctx
 		}).
@@ -2264,9 +2108,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerRSWithDefaultYAML, "rejected-default-false", namespace, "false", "rejected", "true", "rejected", "false"))
-			if err == nil {
-				t.Fatal("initContainers without securityContext.runAsNonRoot field set to true were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", workloadsMessage)
 
 			return ctx
 		}).
@@ -2276,9 +2118,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerRSOnlyDefaultYAML, "rejected-only-default-false", namespace, "false", "rejected", "rejected"))
-			if err == nil {
-				t.Fatal("containers without securityContext.runAsNonRoot field set to true were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", workloadsMessage)
 
 			return ctx
 		}).
@@ -2289,9 +2129,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerDSYAML, "success", namespace, "success", "true"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2301,9 +2139,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerDSWithDefaultYAML, "success-default-true", namespace, "true", "success", "true"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2313,9 +2149,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerDSWithDefaultYAML, "success-default-false", namespace, "false", "success", "true"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2325,9 +2159,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerDSOnlyDefaultYAML, "success-only-default-true", namespace, "true", "success"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2337,9 +2169,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerDSYAML, "success", namespace, "success", "true", "success", "true"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2349,9 +2179,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerDSWithDefaultYAML, "success-default-true", namespace, "true", "success", "true", "success", "true"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2361,9 +2189,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerDSWithDefaultYAML, "success-default-false", namespace, "false", "success", "true", "success", "true"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2373,9 +2199,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerDSOnlyDefaultYAML, "success-only-default-false", namespace, "true", "success", "success"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2385,9 +2209,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerDSYAML, "rejected", namespace, "rejected", "false"))
-			if err == nil {
-				t.Fatal("containers without securityContext.runAsNonRoot field set to true were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", workloadsMessage)
 
 			return ctx
 		}).
@@ -2397,9 +2219,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerDSWithDefaultYAML, "rejected-default-true", namespace, "true", "rejected", "false"))
-			if err == nil {
-				t.Fatal("containers with securityContext.runAsNonRoot field set to false were accepted, when the lower-priority spec.securityContext.runAsRoot was set to true")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", workloadsMessage)
 
 			return ctx
 		}).
@@ -2409,9 +2229,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerDSWithDefaultYAML, "rejected-default-false", namespace, "false", "rejected", "false"))
-			if err == nil {
-				t.Fatal("containers without securityContext.runAsNonRoot field set to true were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", workloadsMessage)
 
 			return ctx
 		}).
@@ -2421,9 +2239,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerDSOnlyDefaultYAML, "rejected-only-default-false", namespace, "false", "rejected"))
-			if err == nil {
-				t.Fatal("containers without securityContext.runAsNonRoot field set to true were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", workloadsMessage)
 
 			return ctx
 		}).
@@ -2433,9 +2249,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerDSYAML, "rejected", namespace, "rejected", "true", "rejected", "false"))
-			if err == nil {
-				t.Fatal("initContainers without securityContext.runAsNonRoot field set to true were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", workloadsMessage)
 
 			return ctx
 		}).
@@ -2445,9 +2259,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerDSWithDefaultYAML, "rejected-default-true", namespace, "true", "rejected", "true", "rejected", "false"))
-			if err == nil {
-				t.Fatal("initContainers with securityContext.runAsNonRoot field set to false were accepted, when the lower-priority spec.securityContext.runAsRoot was set to true")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", workloadsMessage)
 
 			return ctx
 		}).
@@ -2457,9 +2269,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerDSWithDefaultYAML, "rejected-default-false", namespace, "false", "rejected", "true", "rejected", "false"))
-			if err == nil {
-				t.Fatal("initContainers without securityContext.runAsNonRoot field set to true were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", workloadsMessage)
 
 			return ctx
 		}).
@@ -2469,9 +2279,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerDSOnlyDefaultYAML, "rejected-only-default-false", namespace, "false", "rejected", "rejected"))
-			if err == nil {
-				t.Fatal("containers without securityContext.runAsNonRoot field set to true were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", workloadsMessage)
 
 			return ctx
 		}).
@@ -2482,9 +2290,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerSSYAML, "success", namespace, "success", "true"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2494,9 +2300,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerSSWithDefaultYAML, "success-default-true", namespace, "true", "success", "true"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2506,9 +2310,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerSSWithDefaultYAML, "success-default-false", namespace, "false", "success", "true"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2518,9 +2320,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerSSOnlyDefaultYAML, "success-only-default-true", namespace, "true", "success"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2530,9 +2330,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerSSYAML, "success", namespace, "success", "true", "success", "true"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2542,9 +2340,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerSSWithDefaultYAML, "success-default-true", namespace, "true", "success", "true", "success", "true"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2554,9 +2350,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerSSWithDefaultYAML, "success-default-false", namespace, "false", "success", "true", "success", "true"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2566,9 +2360,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerSSOnlyDefaultYAML, "success-only-default-false", namespace, "true", "success", "success"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2578,9 +2370,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerSSYAML, "rejected", namespace, "rejected", "false"))
-			if err == nil {
-				t.Fatal("containers without securityContext.runAsNonRoot field set to true were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", workloadsMessage)
 
 			return ctx
 		}).
@@ -2590,9 +2380,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerSSWithDefaultYAML, "rejected-default-true", namespace, "true", "rejected", "false"))
-			if err == nil {
-				t.Fatal("containers with securityContext.runAsNonRoot field set to false were accepted, when the lower-priority spec.securityContext.runAsRoot was set to true")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", workloadsMessage)
 
 			return ctx
 		}).
@@ -2602,9 +2390,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerSSWithDefaultYAML, "rejected-default-false", namespace, "false", "rejected", "false"))
-			if err == nil {
-				t.Fatal("containers without securityContext.runAsNonRoot field set to true were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", workloadsMessage)
 
 			return ctx
 		}).
@@ -2614,9 +2400,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerSSOnlyDefaultYAML, "rejected-only-default-false", namespace, "false", "rejected"))
-			if err == nil {
-				t.Fatal("containers without securityContext.runAsNonRoot field set to true were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", workloadsMessage)
 
 			return ctx
 		}).
@@ -2626,9 +2410,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerSSYAML, "rejected", namespace, "rejected", "true", "rejected", "false"))
-			if err == nil {
-				t.Fatal("initContainers without securityContext.runAsNonRoot field set to true were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", workloadsMessage)
 
 			return ctx
 		}).
@@ -2638,9 +2420,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerSSWithDefaultYAML, "rejected-default-true", namespace, "true", "rejected", "true", "rejected", "false"))
-			if err == nil {
-				t.Fatal("initContainers with securityContext.runAsNonRoot field set to false were accepted, when the lower-priority spec.securityContext.runAsRoot was set to true")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", workloadsMessage)
 
 			return ctx
 		}).
@@ -2650,9 +2430,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerSSWithDefaultYAML, "rejected-default-false", namespace, "false", "rejected", "true", "rejected", "false"))
-			if err == nil {
-				t.Fatal("initContainers without securityContext.runAsNonRoot field set to true were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", workloadsMessage)
 
 			return ctx
 		}).
@@ -2662,9 +2440,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerSSOnlyDefaultYAML, "rejected-only-default-false", namespace, "false", "rejected", "rejected"))
-			if err == nil {
-				t.Fatal("containers without securityContext.runAsNonRoot field set to true were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", workloadsMessage)
 
 			return ctx
 		}).
@@ -2675,9 +2451,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerJobYAML, "success", namespace, "success", "true"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2687,9 +2461,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerJobWithDefaultYAML, "success-default-true", namespace, "true", "success", "true"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2699,9 +2471,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerJobWithDefaultYAML, "success-default-false", namespace, "false", "success", "true"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2711,9 +2481,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerJobOnlyDefaultYAML, "success-only-default-true", namespace, "true", "success"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2723,9 +2491,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerJobYAML, "success", namespace, "success", "true", "success", "true"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2735,9 +2501,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerJobWithDefaultYAML, "success-default-true", namespace, "true", "success", "true", "success", "true"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2747,9 +2511,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerJobWithDefaultYAML, "success-default-false", namespace, "false", "success", "true", "success", "true"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2759,9 +2521,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerJobOnlyDefaultYAML, "success-only-default-false", namespace, "true", "success", "success"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2771,9 +2531,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerJobYAML, "rejected", namespace, "rejected", "false"))
-			if err == nil {
-				t.Fatal("containers without securityContext.runAsNonRoot field set to true were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", workloadsMessage)
 
 			return ctx
 		}).
@@ -2783,9 +2541,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerJobWithDefaultYAML, "rejected-default-true", namespace, "true", "rejected", "false"))
-			if err == nil {
-				t.Fatal("containers with securityContext.runAsNonRoot field set to false were accepted, when the lower-priority spec.securityContext.runAsRoot was set to true")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", workloadsMessage)
 
 			return ctx
 		}).
@@ -2795,9 +2551,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerJobWithDefaultYAML, "rejected-default-false", namespace, "false", "rejected", "false"))
-			if err == nil {
-				t.Fatal("containers without securityContext.runAsNonRoot field set to true were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", workloadsMessage)
 
 			return ctx
 		}).
@@ -2807,9 +2561,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerJobOnlyDefaultYAML, "rejected-only-default-false", namespace, "false", "rejected"))
-			if err == nil {
-				t.Fatal("containers without securityContext.runAsNonRoot field set to true were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", workloadsMessage)
 
 			return ctx
 		}).
@@ -2819,9 +2571,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerJobYAML, "rejected", namespace, "rejected", "true", "rejected", "false"))
-			if err == nil {
-				t.Fatal("initContainers without securityContext.runAsNonRoot field set to true were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", workloadsMessage)
 
 			return ctx
 		}).
@@ -2831,9 +2581,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerJobWithDefaultYAML, "rejected-default-true", namespace, "true", "rejected", "true", "rejected", "false"))
-			if err == nil {
-				t.Fatal("initContainers with securityContext.runAsNonRoot field set to false were accepted, when the lower-priority spec.securityContext.runAsRoot was set to true")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", workloadsMessage)
 
 			return ctx
 		}).
@@ -2843,9 +2591,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerJobWithDefaultYAML, "rejected-default-false", namespace, "false", "rejected", "true", "rejected", "false"))
-			if err == nil {
-				t.Fatal("initContainers without securityContext.runAsNonRoot field set to true were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", workloadsMessage)
 
 			return ctx
 		}).
@@ -2855,9 +2601,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerJobOnlyDefaultYAML, "rejected-only-default-false", namespace, "false", "rejected", "rejected"))
-			if err == nil {
-				t.Fatal("containers without securityContext.runAsNonRoot field set to true were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", workloadsMessage)
 
 			return ctx
 		}).
@@ -2868,9 +2612,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerCronJobYAML, "success", namespace, "success", "true"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2880,9 +2622,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerCronJobWithDefaultYAML, "success-default-true", namespace, "true", "success", "true"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2892,9 +2632,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerCronJobWithDefaultYAML, "success-default-false", namespace, "false", "success", "true"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2904,9 +2642,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerCronJobOnlyDefaultYAML, "success-only-default-true", namespace, "true", "success"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2916,9 +2652,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(twoContainersCronJobWithDefaultOnlyOneYAML, "success-two-container-default", namespace, "true", "success-01", "true", "success-02"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2928,9 +2662,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerCronJobYAML, "success", namespace, "success", "true", "success", "true"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2940,9 +2672,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerCronJobWithDefaultYAML, "success-default-true", namespace, "true", "success", "true", "success", "true"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2952,9 +2682,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerCronJobWithDefaultYAML, "success-default-false", namespace, "false", "success", "true", "success", "true"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2964,9 +2692,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerCronJobOnlyDefaultYAML, "success-only-default-false", namespace, "true", "success", "success"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2976,9 +2702,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(twoInitContainersCronJobWithDefaultOnlyOneYAML, "success-two-container-default", namespace, "true", "success-01", "true", "success-02", "true", "success-03"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2988,9 +2712,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerCronJobYAML, "rejected", namespace, "rejected", "false"))
-			if err == nil {
-				t.Fatal("containers without securityContext.runAsNonRoot field set to true were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", cronJobsMessage)
 
 			return ctx
 		}).
@@ -3000,9 +2722,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerCronJobWithDefaultYAML, "rejected-default-true", namespace, "true", "rejected", "false"))
-			if err == nil {
-				t.Fatal("containers with securityContext.runAsNonRoot field set to false were accepted, when the lower-priority spec.securityContext.runAsRoot was set to true")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", cronJobsMessage)
 
 			return ctx
 		}).
@@ -3012,9 +2732,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerCronJobWithDefaultYAML, "rejected-default-false", namespace, "false", "rejected", "false"))
-			if err == nil {
-				t.Fatal("containers without securityContext.runAsNonRoot field set to true were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", cronJobsMessage)
 
 			return ctx
 		}).
@@ -3024,9 +2742,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerCronJobOnlyDefaultYAML, "rejected-only-default-false", namespace, "false", "rejected"))
-			if err == nil {
-				t.Fatal("containers without securityContext.runAsNonRoot field set to true were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", cronJobsMessage)
 
 			return ctx
 		}).
@@ -3036,9 +2752,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerCronJobYAML, "rejected", namespace, "rejected", "true", "rejected", "false"))
-			if err == nil {
-				t.Fatal("initContainers without securityContext.runAsNonRoot field set to true were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", cronJobsMessage)
 
 			return ctx
 		}).
@@ -3048,9 +2762,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerCronJobWithDefaultYAML, "rejected-default-true", namespace, "true", "rejected", "true", "rejected", "false"))
-			if err == nil {
-				t.Fatal("initContainers with securityContext.runAsNonRoot field set to false were accepted, when the lower-priority spec.securityContext.runAsRoot was set to true")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", cronJobsMessage)
 
 			return ctx
 		}).
@@ -3060,9 +2772,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerCronJobWithDefaultYAML, "rejected-default-false", namespace, "false", "rejected", "true", "rejected", "false"))
-			if err == nil {
-				t.Fatal("initContainers without securityContext.runAsNonRoot field set to true were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", cronJobsMessage)
 
 			return ctx
 		}).
@@ -3072,9 +2782,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerCronJobOnlyDefaultYAML, "rejected-only-default-false", namespace, "false", "rejected", "rejected"))
-			if err == nil {
-				t.Fatal("containers without securityContext.runAsNonRoot field set to true were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", cronJobsMessage)
 
 			return ctx
 		}).
@@ -3085,9 +2793,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerRCYAML, "success", namespace, "success", "true"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -3097,9 +2803,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerRCWithDefaultYAML, "success-default-true", namespace, "true", "success", "true"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -3109,9 +2813,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerRCWithDefaultYAML, "success-default-false", namespace, "false", "success", "true"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -3121,9 +2823,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerRCOnlyDefaultYAML, "success-only-default-true", namespace, "true", "success"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -3133,9 +2833,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerRCYAML, "success", namespace, "success", "true", "success", "true"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -3145,9 +2843,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerRCWithDefaultYAML, "success-default-true", namespace, "true", "success", "true", "success", "true"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -3157,9 +2853,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerRCWithDefaultYAML, "success-default-false", namespace, "false", "success", "true", "success", "true"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -3169,9 +2863,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerRCOnlyDefaultYAML, "success-only-default-false", namespace, "true", "success", "success"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -3181,9 +2873,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerRCYAML, "rejected", namespace, "rejected", "false"))
-			if err == nil {
-				t.Fatal("containers without securityContext.runAsNonRoot field set to true were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", workloadsMessage)
 
 			return ctx
 		}).
@@ -3193,9 +2883,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerRCWithDefaultYAML, "rejected-default-true", namespace, "true", "rejected", "false"))
-			if err == nil {
-				t.Fatal("containers with securityContext.runAsNonRoot field set to false were accepted, when the lower-priority spec.securityContext.runAsRoot was set to true")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", workloadsMessage)
 
 			return ctx
 		}).
@@ -3205,9 +2893,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerRCWithDefaultYAML, "rejected-default-false", namespace, "false", "rejected", "false"))
-			if err == nil {
-				t.Fatal("containers without securityContext.runAsNonRoot field set to true were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", workloadsMessage)
 
 			return ctx
 		}).
@@ -3217,9 +2903,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerRCOnlyDefaultYAML, "rejected-only-default-false", namespace, "false", "rejected"))
-			if err == nil {
-				t.Fatal("containers without securityContext.runAsNonRoot field set to true were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", workloadsMessage)
 
 			return ctx
 		}).
@@ -3229,9 +2913,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerRCYAML, "rejected", namespace, "rejected", "true", "rejected", "false"))
-			if err == nil {
-				t.Fatal("initContainers without securityContext.runAsNonRoot field set to true were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", workloadsMessage)
 
 			return ctx
 		}).
@@ -3241,9 +2923,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerRCWithDefaultYAML, "rejected-default-true", namespace, "true", "rejected", "true", "rejected", "false"))
-			if err == nil {
-				t.Fatal("initContainers with securityContext.runAsNonRoot field set to false were accepted, when the lower-priority spec.securityContext.runAsRoot was set to true")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", workloadsMessage)
 
 			return ctx
 		}).
@@ -3253,9 +2933,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerRCWithDefaultYAML, "rejected-default-false", namespace, "false", "rejected", "true", "rejected", "false"))
-			if err == nil {
-				t.Fatal("initContainers without securityContext.runAsNonRoot field set to true were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", workloadsMessage)
 
 			return ctx
 		}).
@@ -3265,9 +2943,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerRCOnlyDefaultYAML, "rejected-only-default-false", namespace, "false", "rejected", "rejected"))
-			if err == nil {
-				t.Fatal("containers without securityContext.runAsNonRoot field set to true were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", workloadsMessage)
 
 			return ctx
 		}).
@@ -3278,9 +2954,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerPodTemplateYAML, "success", namespace, "success", "true"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -3290,9 +2964,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerPodTemplateWithDefaultYAML, "success-default-true", namespace, "true", "success", "true"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -3302,9 +2974,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerPodTemplateWithDefaultYAML, "success-default-false", namespace, "false", "success", "true"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -3314,9 +2984,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerPodTemplateOnlyDefaultYAML, "success-only-default-true", namespace, "true", "success"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -3326,9 +2994,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(twoContainersPodTemplateWithDefaultOnlyOneYAML, "success-two-container-default", namespace, "true", "success-01", "true", "success-02"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -3338,9 +3004,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerPodTemplateYAML, "success", namespace, "success", "true", "success", "true"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -3350,9 +3014,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerPodTemplateWithDefaultYAML, "success-default-true", namespace, "true", "success", "true", "success", "true"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -3362,9 +3024,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerPodTemplateWithDefaultYAML, "success-default-false", namespace, "false", "success", "true", "success", "true"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -3374,9 +3034,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerPodTemplateOnlyDefaultYAML, "success-only-default-false", namespace, "true", "success", "success"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -3386,9 +3044,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(twoInitContainersPodTemplateWithDefaultOnlyOneYAML, "success-two-container-default", namespace, "true", "success-01", "true", "success-02", "true", "success-03"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -3398,9 +3054,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerPodTemplateYAML, "rejected", namespace, "rejected", "false"))
-			if err == nil {
-				t.Fatal("containers without securityContext.runAsNonRoot field set to true were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", podTemplatesMessage)
 
 			return ctx
 		}).
@@ -3410,9 +3064,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerPodTemplateWithDefaultYAML, "rejected-default-true", namespace, "true", "rejected", "false"))
-			if err == nil {
-				t.Fatal("containers with securityContext.runAsNonRoot field set to false were accepted, when the lower-priority spec.securityContext.runAsRoot was set to true")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", podTemplatesMessage)
 
 			return ctx
 		}).
@@ -3422,9 +3074,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerPodTemplateWithDefaultYAML, "rejected-default-false", namespace, "false", "rejected", "false"))
-			if err == nil {
-				t.Fatal("containers without securityContext.runAsNonRoot field set to true were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", podTemplatesMessage)
 
 			return ctx
 		}).
@@ -3434,9 +3084,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerPodTemplateOnlyDefaultYAML, "rejected-only-default-false", namespace, "false", "rejected"))
-			if err == nil {
-				t.Fatal("containers without securityContext.runAsNonRoot field set to true were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", podTemplatesMessage)
 
 			return ctx
 		}).
@@ -3446,9 +3094,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerPodTemplateYAML, "rejected", namespace, "rejected", "true", "rejected", "false"))
-			if err == nil {
-				t.Fatal("initContainers without securityContext.runAsNonRoot field set to true were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", podTemplatesMessage)
 
 			return ctx
 		}).
@@ -3458,9 +3104,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerPodTemplateWithDefaultYAML, "rejected-default-true", namespace, "true", "rejected", "true", "rejected", "false"))
-			if err == nil {
-				t.Fatal("initContainers with securityContext.runAsNonRoot field set to false were accepted, when the lower-priority spec.securityContext.runAsRoot was set to true")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", podTemplatesMessage)
 
 			return ctx
 		}).
@@ -3470,9 +3114,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerPodTemplateWithDefaultYAML, "rejected-default-false", namespace, "false", "rejected", "true", "rejected", "false"))
-			if err == nil {
-				t.Fatal("initContainers without securityContext.runAsNonRoot field set to true were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", podTemplatesMessage)
 
 			return ctx
 		}).
@@ -3482,9 +3124,7 @@ func TestRunAsNonRoot(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerPodTemplateOnlyDefaultYAML, "rejected-only-default-false", namespace, "false", "rejected", "rejected"))
-			if err == nil {
-				t.Fatal("containers without securityContext.runAsNonRoot field set to true were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", podTemplatesMessage)
 
 			return ctx
 		})
@@ -3492,129 +3132,101 @@ func TestRunAsNonRoot(t *testing.T) {
 
 }
 
-func TestEphemeralContainers(t *testing.T) {
+func TestWarnMode(t *testing.T) {
 
-	f := features.New("Pods with ephemeral containers").
+	f := features.New("Warn mode tests").
 		Setup(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
 			// get namespace
 			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
 
-			// create a pod that will be used for certain ephemeral container tests
-			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerYAML, "ephemeral", namespace, "ephemeral", "true"))
+			// switch the namespace from the deny to the warn binding
+			err := testutils.AddNamespaceLabels(ctx, cfg, namespace, map[string]string{"vap-library.com/pss-running-as-non-root": "warn"})
 			if err != nil {
 				t.Fatal(err)
 			}
 
-			// wait for the pod
-			time.Sleep(2 * time.Second)
-
-			// create a pod with spec.securityContext.runAsNonRoot that will be used for certain ephemeral container tests
-			err = testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerOnlyDefaultYAML, "ephemeral", namespace, "true", "ephemeral"))
-			if err != nil {
-				t.Fatal(err)
-			}
-
-			// wait for the pod
-			time.Sleep(2 * time.Second)
-
 			return ctx
 		}).
-		Assess("An invalid ephemeral container is rejected", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+		Assess("A Pod with runAsNonRoot set to false is accepted with a warning", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
 			// get namespace
 			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
 
-			// get client
-			client, err := cfg.NewClient()
-			if err != nil {
-				t.Fatal(err)
-			}
+			// this should PASS with a warning!
+			warnings, err := testutils.ApplyK8sResourceFromYAMLWithWarnings(ctx, cfg, fmt.Sprintf(containerYAML, "rejected", namespace, "rejected", "false"))
+			testutils.ExpectAllowed(t, err)
+			testutils.ExpectWarned(t, warnings, podsMessage)
 
-			// get the pod that was created in setup to attach an ephemeral container to it
-			pod := &v1.Pod{}
-			err = client.Resources(namespace).Get(ctx, "running-as-non-root-ephemeral", namespace, pod)
-			if err != nil {
-				t.Fatal(err)
-			}
+			return ctx
+		})
 
-			// define patch type
-			patchType := types.StrategicMergePatchType
+	_ = testEnv.Test(t, f.Feature())
 
-			// define patch data
-			patchData := []byte(fmt.Sprintf(containerEphemeralPatchYAML, "false"))
+}
 
-			patch := k8s.Patch{PatchType: patchType, Data: patchData}
+func TestAuditMode(t *testing.T) {
 
-			// patch the pod, this should FAIL!
-			err = client.Resources(namespace).PatchSubresource(ctx, pod, "ephemeralcontainers", patch)
-			if err == nil {
-				t.Fatal("ephemeral container without securityContext.runAsNonRoot field should be rejected")
+	f := features.New("Audit mode tests").
+		Setup(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// get namespace
+			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
+
+			// switch the namespace from the deny to the audit binding
+			err := testutils.AddNamespaceLabels(ctx, cfg, namespace, map[string]string{"vap-library.com/pss-running-as-non-root": "audit"})
+			if err != nil {
+				t.Fatal(err)
 			}
 
 			return ctx
 		}).
-		Assess("A valid ephemeral container is accepted", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+		Assess("A Pod with runAsNonRoot set to false is accepted and audited", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
 			// get namespace
 			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
 
-			// get client
-			client, err := cfg.NewClient()
-			if err != nil {
-				t.Fatal(err)
-			}
+			// the audit events are only available with the in-process backend or the audit log
+			testutils.SkipWithoutAuditLog(ctx, t)
 
-			// get the pod that was created in setup to attach an ephemeral container to it
-			pod := &v1.Pod{}
-			err = client.Resources(namespace).Get(ctx, "running-as-non-root-ephemeral", namespace, pod)
-			if err != nil {
-				t.Fatal(err)
-			}
+			// this should PASS and be audited!
+			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerYAML, "rejected", namespace, "rejected", "false"))
+			testutils.ExpectAllowed(t, err)
+			testutils.ExpectAudited(ctx, t, namespace, "pss-running-as-non-root", podsMessage)
 
-			// define patch type
-			patchType := types.StrategicMergePatchType
+			return ctx
+		})
 
-			// define patch data
-			patchData := []byte(fmt.Sprintf(containerEphemeralPatchYAML, "true"))
+	_ = testEnv.Test(t, f.Feature())
 
-			patch := k8s.Patch{PatchType: patchType, Data: patchData}
+}
 
-			// patch the pod
-			err = client.Resources(namespace).PatchSubresource(ctx, pod, "ephemeralcontainers", patch)
-			if err != nil {
-				t.Fatal(err)
-			}
+func TestEphemeralContainers(t *testing.T) {
 
-			return ctx
-		}).
-		Assess("Two valid ephemeral containers are accepted", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+	f := features.New("Ephemeral containers added through the ephemeralcontainers subresource").
+		Assess("An invalid ephemeral container is rejected", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
 			// get namespace
 			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
 
-			// get client
-			client, err := cfg.NewClient()
-			if err != nil {
-				t.Fatal(err)
-			}
-
-			// get the pod that was created in setup to attach an ephemeral container to it
-			pod := &v1.Pod{}
-			err = client.Resources(namespace).Get(ctx, "running-as-non-root-ephemeral", namespace, pod)
-			if err != nil {
-				t.Fatal(err)
-			}
+			// add the ephemeral container like kubectl debug does, this should FAIL!
+			err := testutils.ApplyEphemeralContainerFromYAML(ctx, cfg, fmt.Sprintf(containerYAML, "subresource", namespace, "subresource", "true"), fmt.Sprintf(ephemeralContainerYAML, "debugger", "false"))
+			testutils.ExpectDenied(t, err, "pss-running-as-non-root", podsMessage)
 
-			// define patch type
-			patchType := types.StrategicMergePatchType
+			return ctx
+		}).
+		Assess("A valid ephemeral container is accepted", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// get namespace
+			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
 
-			// define patch data
-			patchData := []byte(fmt.Sprintf(twoContainersEphemeralPatchYAML, "true", "true"))
+			// add the ephemeral container like kubectl debug does
+			err := testutils.ApplyEphemeralContainerFromYAML(ctx, cfg, fmt.Sprintf(containerYAML, "subresource", namespace, "subresource", "true"), fmt.Sprintf(ephemeralContainerYAML, "debugger", "true"))
+			testutils.ExpectAllowed(t, err)
 
-			patch := k8s.Patch{PatchType: patchType, Data: patchData}
+			return ctx
+		}).
+		Assess("Two valid ephemeral containers are accepted", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// get namespace
+			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
 
-			// patch the pod
-			err = client.Resources(namespace).PatchSubresource(ctx, pod, "ephemeralcontainers", patch)
-			if err != nil {
-				t.Fatal(err)
-			}
+			// add both ephemeral containers in one update
+			err := testutils.ApplyEphemeralContainerFromYAML(ctx, cfg, fmt.Sprintf(containerYAML, "subresource", namespace, "subresource", "true"), fmt.Sprintf(ephemeralContainerYAML, "debugger", "true"), fmt.Sprintf(ephemeralContainerYAML, "debugger-two", "true"))
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -3622,35 +3234,45 @@ func TestEphemeralContainers(t *testing.T) {
 			// get namespace
 			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
 
-			// get client
-			client, err := cfg.NewClient()
-			if err != nil {
-				t.Fatal(err)
-			}
+			// the Pod only sets spec.securityContext.runAsNonRoot, the unset ephemeral container inherits it
+			err := testutils.ApplyEphemeralContainerFromYAML(ctx, cfg, fmt.Sprintf(containerOnlyDefaultYAML, "subresource", namespace, "true", "subresource"), fmt.Sprintf(ephemeralContainerYAML, "debugger", "true"), fmt.Sprintf(ephemeralContainerUnsetYAML, "debugger-two"))
+			testutils.ExpectAllowed(t, err)
 
-			// get the pod that was created in setup to attach an ephemeral container to it
-			pod := &v1.Pod{}
-			err = client.Resources(namespace).Get(ctx, "running-as-non-root-only-default-ephemeral", namespace, pod)
-			if err != nil {
-				t.Fatal(err)
-			}
+			return ctx
+		})
+	_ = testEnv.Test(t, f.Feature())
 
-			// define patch type
-			patchType := types.StrategicMergePatchType
+}
 
-			// define patch data
-			patchData := []byte(fmt.Sprintf(twoContainersEphemeralOneUnsetPatchYAML, "true"))
+func TestWorkloadKinds(t *testing.T) {
+
+	testutils.RunWorkloadTestCases(t, testEnv,
+		testutils.WorkloadTestCase{
+			Name:     "Workloads with an invalid container",
+			PodSpec:  fmt.Sprintf(podSpecYAML, "false"),
+			Expect:   testutils.Expectation{Result: testutils.ResultDenied},
+			Messages: testutils.WorkloadMessages(podsMessage, workloadsMessage, cronJobsMessage, podTemplatesMessage),
+		},
+		testutils.WorkloadTestCase{
+			Name:    "Workloads with a valid container",
+			PodSpec: fmt.Sprintf(podSpecYAML, "true"),
+			Expect:  testutils.Expectation{Result: testutils.ResultAllowed},
+		},
+	)
 
-			patch := k8s.Patch{PatchType: patchType, Data: patchData}
+}
 
-			// patch the pod
-			err = client.Resources(namespace).PatchSubresource(ctx, pod, "ephemeralcontainers", patch)
-			if err != nil {
-				t.Fatal(err)
-			}
+func TestDifferential(t *testing.T) {
 
-			return ctx
-		})
-	_ = testEnv.Test(t, f.Feature())
+	// pod-security-admission also rejects spec.securityContext.runAsNonRoot: false if every container overrides it
+	podLevelFalse := testutils.KnownDifference{
+		Reason: "the policy allows spec.securityContext.runAsNonRoot false if every container sets it to true",
+		Matches: func(spec *v1.PodSpec) bool {
+			return spec.SecurityContext != nil && spec.SecurityContext.RunAsNonRoot != nil && !*spec.SecurityContext.RunAsNonRoot
+		},
+	}
+
+	// compare the policy with the check of k8s.io/pod-security-admission
+	testutils.RunDifferentialTests(t, testEnv, "pss-running-as-non-root", podLevelFalse)
 
 }
@@ -5,18 +5,25 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 	"testing"
-	"time"
 	"vap-library/testutils"
 
-	v1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/types"
-	"sigs.k8s.io/e2e-framework/klient/k8s"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	"sigs.k8s.io/e2e-framework/klient/decoder"
 	"sigs.k8s.io/e2e-framework/pkg/env"
 	"sigs.k8s.io/e2e-framework/pkg/envconf"
 	"sigs.k8s.io/e2e-framework/pkg/features"
 )
 
+// expected denial messages
+const (
+	podsMessage         = "securityContext.seccompProfile.type must be set to RuntimeDefault or Localhost on any containers, initContainers, and ephemeralContainers in Pods"
+	workloadsMessage    = "securityContext.seccompProfile.type must be set to RuntimeDefault or Localhost on containers in Workloads"
+	podTemplatesMessage = "securityContext.seccompProfile.type must be set to RuntimeDefault or Localhost on containers in PodTemplates"
+	cronJobsMessage     = "securityContext.seccompProfile.type must be set to RuntimeDefault or Localhost on containers in CronJobs"
+)
+
 // TEST DATA FOR POD TESTS
 
 var containerYAML string = `
@@ -1651,29 +1658,28 @@ template:
       image: public.ecr.aws/docker/library/busybox:1.36
 `
 
-// TEST DATA FOR PATCHING A POD TO ADD AN EPHEMERAL CONTAINER
-
-var containerEphemeralPatchYAML string = `
-{
-  "spec": {
-    "ephemeralContainers": [
-      {
-         "image": "public.ecr.aws/docker/library/busybox:1.36",
-         "name": "ephemeral",
-         "resources": {},
-         "securityContext": {
-           "seccompProfile": {
-             "type": "%s"
-           } 
-         },
-         "stdin": true,
-         "targetContainerName": "seccomp-ephemeral",
-         "terminationMessagePolicy": "File",
-         "tty": true
-      }
-    ]
-  }
-}
+// TEST DATA FOR ADDING AN EPHEMERAL CONTAINER THROUGH THE EPHEMERALCONTAINERS SUBRESOURCE
+
+var ephemeralContainerYAML string = `
+name: debugger
+image: public.ecr.aws/docker/library/busybox:1.36
+stdin: true
+tty: true
+targetContainerName: seccomp-subresource
+securityContext:
+  seccompProfile:
+    type: %s
+`
+
+// TEST DATA FOR THE WORKLOAD TESTS, THE PODSPEC IS WRAPPED INTO ALL THE WORKLOAD KINDS
+
+var podSpecYAML string = `
+containers:
+- name: seccomp
+  image: public.ecr.aws/docker/library/busybox:1.36
+  securityContext:
+    seccompProfile:
+      type: %s
 `
 
 var testEnv env.Environment
@@ -1688,9 +1694,6 @@ func TestMain(m *testing.M) {
 		log.Fatalf("Unable to create Kind cluster for test. Error msg: %s", err)
 	}
 
-	// wait for the cluster to be ready
-	time.Sleep(2 * time.Second)
-
 	os.Exit(testEnv.Run(m))
 }
 
@@ -1704,9 +1707,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerYAML, "success", namespace, "success", "RuntimeDefault"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1716,9 +1717,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerWithDefaultYAML, "success-default-rd", namespace, "RuntimeDefault", "success", "RuntimeDefault"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1728,9 +1727,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerOnlyDefaultYAML, "success-only-default-rd", namespace, "RuntimeDefault", "success"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1740,9 +1737,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerOnlyDefaultWithOtherSecurityContextYAML, "success-only-default-with-other-rd", namespace, "RuntimeDefault", "success"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1752,9 +1747,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(twoContainersWithDefaultOnlyOneYAML, "success", namespace, "RuntimeDefault", "success-01", "RuntimeDefault", "success-02"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1764,9 +1757,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerYAML, "success", namespace, "success", "RuntimeDefault", "success", "RuntimeDefault"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1776,9 +1767,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerWithDefaultYAML, "success-default-rd", namespace, "RuntimeDefault", "success", "RuntimeDefault", "success", "RuntimeDefault"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1788,9 +1777,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerOnlyDefaultYAML, "success-only-default-rd", namespace, "RuntimeDefault", "success", "success"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1800,9 +1787,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(twoInitContainersWithDefaultOnlyOneYAML, "success", namespace, "RuntimeDefault", "success-01", "RuntimeDefault", "success-02", "RuntimeDefault", "success-03"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1812,9 +1797,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerYAML, "rejected", namespace, "rejected", "Unconfined"))
-			if err == nil {
-				t.Fatal("containers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", podsMessage)
 
 			return ctx
 		}).
@@ -1824,9 +1807,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerWithDefaultYAML, "rejected-default-rd", namespace, "RuntimeDefault", "rejected", "Unconfined"))
-			if err == nil {
-				t.Fatal("containers with securityContext.seccompProfile.type field set to Unconfined were accepted, when the lower-priority spec.securityContext.seccompProfile.type was set to RuntimeDefault or Localhost")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", podsMessage)
 
 			return ctx
 		}).
@@ -1836,9 +1817,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerWithDefaultYAML, "rejected-default-unconfined", namespace, "Unconfined", "rejected", "Unconfined"))
-			if err == nil {
-				t.Fatal("containers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", podsMessage)
 
 			return ctx
 		}).
@@ -1848,9 +1827,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerOnlyDefaultYAML, "rejected-only-default-unconfined", namespace, "Unconfined", "rejected"))
-			if err == nil {
-				t.Fatal("containers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", podsMessage)
 
 			return ctx
 		}).
@@ -1860,9 +1837,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerOnlyDefaultYAML, "rejected-only-default-unconfined", namespace, "SomethingElseThanAllowed", "rejected"))
-			if err == nil {
-				t.Fatal("containers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", podsMessage)
 
 			return ctx
 		}).
@@ -1872,9 +1847,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerWithDefaultYAML, "rejected-default-unconfined", namespace, "Unconfined", "rejected", "RuntimeDefault"))
-			if err == nil {
-				t.Fatal("containers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", podsMessage)
 
 			return ctx
 		}).
@@ -1884,9 +1857,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerYAML, "rejected", namespace, "rejected", "RuntimeDefault", "rejected", "Unconfined"))
-			if err == nil {
-				t.Fatal("initContainers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", podsMessage)
 
 			return ctx
 		}).
@@ -1896,9 +1867,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerWithDefaultYAML, "rejected-default-rd", namespace, "RuntimeDefault", "rejected", "RuntimeDefault", "rejected", "Unconfined"))
-			if err == nil {
-				t.Fatal("initContainers with securityContext.seccompProfile.type field set to Unconfined were accepted, when the lower-priority spec.securityContext.seccompProfile.type was set to RuntimeDefault or Localhost")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", podsMessage)
 
 			return ctx
 		}).
@@ -1908,9 +1877,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerWithDefaultYAML, "rejected-default-unconfined", namespace, "Unconfined", "rejected", "RuntimeDefault", "rejected", "Unconfined"))
-			if err == nil {
-				t.Fatal("initContainers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", podsMessage)
 
 			return ctx
 		}).
@@ -1920,9 +1887,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerOnlyDefaultYAML, "rejected-only-default-unconfined", namespace, "Unconfined", "rejected", "rejected"))
-			if err == nil {
-				t.Fatal("initContainers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", podsMessage)
 
 			return ctx
 		}).
@@ -1932,9 +1897,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerWithDefaultYAML, "success-default-unconfined", namespace, "Unconfined", "rejected", "RuntimeDefault", "rejected", "RuntimeDefault"))
-			if err == nil {
-				t.Fatal("initContainers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", podsMessage)
 
 			return ctx
 		}).
@@ -1945,9 +1908,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerDeploymentYAML, "success", namespace, "success", "RuntimeDefault"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1957,9 +1918,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerDeploymentWithDefaultYAML, "success-default-rd", namespace, "RuntimeDefault", "success", "RuntimeDefault"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1969,9 +1928,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerDeploymentOnlyDefaultYAML, "success-only-default-rd", namespace, "RuntimeDefault", "success"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1981,9 +1938,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(twoContainersDeploymentWithDefaultOnlyOneYAML, "success-two-container-default", namespace, "RuntimeDefault", "success-01", "RuntimeDefault", "success-02"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -1993,9 +1948,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerDeploymentYAML, "success", namespace, "success", "RuntimeDefault", "success", "RuntimeDefault"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2005,9 +1958,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerDeploymentWithDefaultYAML, "success-default-rd", namespace, "RuntimeDefault", "success", "RuntimeDefault", "success", "RuntimeDefault"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2017,9 +1968,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerDeploymentOnlyDefaultYAML, "success-only-default-rd", namespace, "RuntimeDefault", "success", "success"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2029,9 +1978,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(twoInitContainersDeploymentWithDefaultOnlyOneYAML, "success-two-container-default", namespace, "RuntimeDefault", "success-01", "RuntimeDefault", "success-02", "RuntimeDefault", "success-03"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2041,9 +1988,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerDeploymentYAML, "rejected", namespace, "rejected", "Unconfined"))
-			if err == nil {
-				t.Fatal("containers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", workloadsMessage)
 
 			return ctx
 		}).
@@ -2053,9 +1998,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerDeploymentWithDefaultYAML, "rejected-default-rd", namespace, "RuntimeDefault", "rejected", "Unconfined"))
-			if err == nil {
-				t.Fatal("containers with securityContext.seccompProfile.type field set to Unconfined were accepted, when the lower-priority spec.securityContext.seccompProfile.type was set to RuntimeDefault or Localhost")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", workloadsMessage)
 
 			return ctx
 		}).
@@ -2065,9 +2008,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerDeploymentWithDefaultYAML, "rejected-default-unconfined", namespace, "Unconfined", "rejected", "Unconfined"))
-			if err == nil {
-				t.Fatal("containers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", workloadsMessage)
 
 			return ctx
 		}).
@@ -2077,9 +2018,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerDeploymentOnlyDefaultYAML, "rejected-only-default-unconfined", namespace, "Unconfined", "rejected"))
-			if err == nil {
-				t.Fatal("containers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", workloadsMessage)
 
 			return ctx
 		}).
@@ -2089,9 +2028,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerDeploymentWithDefaultYAML, "rejected-default-unconfined", namespace, "Unconfined", "rejected", "RuntimeDefault"))
-			if err == nil {
-				t.Fatal("containers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", workloadsMessage)
 
 			return ctx
 		}).
@@ -2101,9 +2038,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerDeploymentYAML, "rejected", namespace, "rejected", "RuntimeDefault", "rejected", "Unconfined"))
-			if err == nil {
-				t.Fatal("initContainers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", workloadsMessage)
 
 			return ctx
 		}).
@@ -2113,9 +2048,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerDeploymentWithDefaultYAML, "rejected-default-rd", namespace, "RuntimeDefault", "rejected", "RuntimeDefault", "rejected", "Unconfined"))
-			if err == nil {
-				t.Fatal("initContainers with securityContext.seccompProfile.type field set to Unconfined were accepted, when the lower-priority spec.securityContext.seccompProfile.type was set to RuntimeDefault or Localhost")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", workloadsMessage)
 
 			return ctx
 		}).
@@ -2125,9 +2058,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerDeploymentWithDefaultYAML, "rejected-default-unconfined", namespace, "Unconfined", "rejected", "RuntimeDefault", "rejected", "Unconfined"))
-			if err == nil {
-				t.Fatal("initContainers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", workloadsMessage)
 
 			return ctx
 		}).
@@ -2137,9 +2068,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerDeploymentOnlyDefaultYAML, "rejected-only-default-unconfined", namespace, "Unconfined", "rejected", "rejected"))
-			if err == nil {
-				t.Fatal("containers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", workloadsMessage)
 
 			return ctx
 		}).
@@ -2149,9 +2078,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerDeploymentWithDefaultYAML, "success-default-unconfined", namespace, "Unconfined", "success", "RuntimeDefault", "success", "RuntimeDefault"))
-			if err == nil {
-				t.Fatal("containers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", workloadsMessage)
 
 			return ctx
 		}).
@@ -2162,9 +2089,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerRSYAML, "success", namespace, "success", "RuntimeDefault"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2174,9 +2099,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerRSWithDefaultYAML, "success-default-rd", namespace, "RuntimeDefault", "success", "RuntimeDefault"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2186,9 +2109,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerRSOnlyDefaultYAML, "success-only-default-rd", namespace, "RuntimeDefault", "success"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2198,9 +2119,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerRSYAML, "success", namespace, "success", "RuntimeDefault", "success", "RuntimeDefault"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2210,9 +2129,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerRSWithDefaultYAML, "success-default-rd", namespace, "RuntimeDefault", "success", "RuntimeDefault", "success", "RuntimeDefault"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2222,9 +2139,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerRSOnlyDefaultYAML, "success-only-default-rd", namespace, "RuntimeDefault", "success", "success"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2234,9 +2149,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerRSYAML, "rejected", namespace, "rejected", "Unconfined"))
-			if err == nil {
-				t.Fatal("containers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", workloadsMessage)
 
 			return ctx
 		}).
@@ -2246,9 +2159,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerRSWithDefaultYAML, "rejected-default-rd", namespace, "RuntimeDefault", "rejected", "Unconfined"))
-			if err == nil {
-				t.Fatal("containers with securityContext.seccompProfile.type field set to Unconfined were accepted, when the lower-priority spec.securityContext.seccompProfile.type was set to RuntimeDefault or Localhost")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", workloadsMessage)
 
 			return ctx
 		}).
@@ -2258,9 +2169,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerRSWithDefaultYAML, "rejected-default-unconfined", namespace, "Unconfined", "rejected", "Unconfined"))
-			if err == nil {
-				t.Fatal("containers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", workloadsMessage)
 
 			return ctx
 		}).
@@ -2270,9 +2179,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerRSOnlyDefaultYAML, "rejected-only-default-unconfined", namespace, "Unconfined", "rejected"))
-			if err == nil {
-				t.Fatal("containers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", workloadsMessage)
 
 			return ctx
 		}).
@@ -2282,9 +2189,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerRSWithDefaultYAML, "rejected-default-unconfined", namespace, "Unconfined", "rejected", "RuntimeDefault"))
-			if err == nil {
-				t.Fatal("containers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", workloadsMessage)
 
 			return ctx
 		}).
@@ -2294,9 +2199,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerRSYAML, "rejected", namespace, "rejected", "RuntimeDefault", "rejected", "Unconfined"))
-			if err == nil {
-				t.Fatal("initContainers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", workloadsMessage)
 
 			return ctx
 		}).
@@ -2306,9 +2209,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerRSWithDefaultYAML, "rejected-default-rd", namespace, "RuntimeDefault", "rejected", "RuntimeDefault", "rejected", "Unconfined"))
-			if err == nil {
-				t.Fatal("initContainers with securityContext.seccompProfile.type field set to Unconfined were accepted, when the lower-priority spec.securityContext.seccompProfile.type was set to RuntimeDefault or Localhost")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", workloadsMessage)
 
 			return ctx
 		}).
@@ -2318,9 +2219,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerRSWithDefaultYAML, "rejected-default-unconfined", namespace, "Unconfined", "rejected", "RuntimeDefault", "rejected", "Unconfined"))
-			if err == nil {
-				t.Fatal("initContainers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", workloadsMessage)
 
 			return ctx
 		}).
@@ -2330,9 +2229,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerRSOnlyDefaultYAML, "rejected-only-default-unconfined", namespace, "Unconfined", "rejected", "rejected"))
-			if err == nil {
-				t.Fatal("containers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", workloadsMessage)
 
 			return ctx
 		}).
@@ -2342,9 +2239,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerRSWithDefaultYAML, "rejected-default-unconfined", namespace, "Unconfined", "rejected", "RuntimeDefault", "rejected", "RuntimeDefault"))
-			if err == nil {
-				t.Fatal("containers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", workloadsMessage)
 
 			return ctx
 		}).
@@ -2355,9 +2250,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerDSYAML, "success", namespace, "success", "RuntimeDefault"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2367,9 +2260,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerDSWithDefaultYAML, "success-default-rd", namespace, "RuntimeDefault", "success", "RuntimeDefault"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2379,9 +2270,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerDSOnlyDefaultYAML, "success-only-default-rd", namespace, "RuntimeDefault", "success"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2391,9 +2280,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerDSYAML, "success", namespace, "success", "RuntimeDefault", "success", "RuntimeDefault"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2403,9 +2290,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerDSWithDefaultYAML, "success-default-rd", namespace, "RuntimeDefault", "success", "RuntimeDefault", "success", "RuntimeDefault"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2415,9 +2300,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerDSOnlyDefaultYAML, "success-only-default-rd", namespace, "RuntimeDefault", "success", "success"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2427,9 +2310,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerDSYAML, "rejected", namespace, "rejected", "Unconfined"))
-			if err == nil {
-				t.Fatal("containers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", workloadsMessage)
 
 			return ctx
 		}).
@@ -2439,9 +2320,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerDSWithDefaultYAML, "rejected-default-rd", namespace, "RuntimeDefault", "rejected", "Unconfined"))
-			if err == nil {
-				t.Fatal("containers with securityContext.seccompProfile.type field set to Unconfined were accepted, when the lower-priority spec.securityContext.seccompProfile.type was set to RuntimeDefault or Localhost")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", workloadsMessage)
 
 			return ctx
 		}).
@@ -2451,9 +2330,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerDSWithDefaultYAML, "rejected-default-unconfined", namespace, "Unconfined", "rejected", "Unconfined"))
-			if err == nil {
-				t.Fatal("containers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", workloadsMessage)
 
 			return ctx
 		}).
@@ -2463,9 +2340,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerDSOnlyDefaultYAML, "rejected-only-default-unconfined", namespace, "Unconfined", "rejected"))
-			if err == nil {
-				t.Fatal("containers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", workloadsMessage)
 
 			return ctx
 		}).
@@ -2475,9 +2350,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerDSWithDefaultYAML, "rejected-default-unconfined", namespace, "Unconfined", "rejected", "RuntimeDefault"))
-			if err == nil {
-				t.Fatal("containers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", workloadsMessage)
 
 			return ctx
 		}).
@@ -2487,9 +2360,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerDSYAML, "rejected", namespace, "rejected", "RuntimeDefault", "rejected", "Unconfined"))
-			if err == nil {
-				t.Fatal("initContainers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", workloadsMessage)
 
 			return ctx
 		}).
@@ -2499,9 +2370,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerDSWithDefaultYAML, "rejected-default-rd", namespace, "RuntimeDefault", "rejected", "RuntimeDefault", "rejected", "Unconfined"))
-			if err == nil {
-				t.Fatal("initContainers with securityContext.seccompProfile.type field set to Unconfined were accepted, when the lower-priority spec.securityContext.seccompProfile.type was set to RuntimeDefault or Localhost")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", workloadsMessage)
 
 			return ctx
 		}).
@@ -2511,9 +2380,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerDSWithDefaultYAML, "rejected-default-unconfined", namespace, "Unconfined", "rejected", "RuntimeDefault", "rejected", "Unconfined"))
-			if err == nil {
-				t.Fatal("initContainers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", workloadsMessage)
 
 			return ctx
 		}).
@@ -2523,9 +2390,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerDSOnlyDefaultYAML, "rejected-only-default-unconfined", namespace, "Unconfined", "rejected", "rejected"))
-			if err == nil {
-				t.Fatal("containers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", workloadsMessage)
 
 			return ctx
 		}).
@@ -2535,9 +2400,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerDSWithDefaultYAML, "rejected-default-unconfined", namespace, "Unconfined", "rejected", "RuntimeDefault", "rejected", "RuntimeDefault"))
-			if err == nil {
-				t.Fatal("containers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", workloadsMessage)
 
 			return ctx
 		}).
@@ -2548,9 +2411,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerSSYAML, "success", namespace, "success", "RuntimeDefault"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2560,9 +2421,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerSSWithDefaultYAML, "success-default-rd", namespace, "RuntimeDefault", "success", "RuntimeDefault"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2572,9 +2431,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerSSOnlyDefaultYAML, "success-only-default-rd", namespace, "RuntimeDefault", "success"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2584,9 +2441,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerSSYAML, "success", namespace, "success", "RuntimeDefault", "success", "RuntimeDefault"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2596,9 +2451,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerSSWithDefaultYAML, "success-default-rd", namespace, "RuntimeDefault", "success", "RuntimeDefault", "success", "RuntimeDefault"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2608,9 +2461,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerSSOnlyDefaultYAML, "success-only-default-rd", namespace, "RuntimeDefault", "success", "success"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2620,9 +2471,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerSSYAML, "rejected", namespace, "rejected", "Unconfined"))
-			if err == nil {
-				t.Fatal("containers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", workloadsMessage)
 
 			return ctx
 		}).
@@ -2632,9 +2481,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerSSWithDefaultYAML, "rejected-default-rd", namespace, "RuntimeDefault", "rejected", "Unconfined"))
-			if err == nil {
-				t.Fatal("containers with securityContext.seccompProfile.type field set to Unconfined were accepted, when the lower-priority spec.securityContext.seccompProfile.type was set to RuntimeDefault or Localhost")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", workloadsMessage)
 
 			return ctx
 		}).
@@ -2644,9 +2491,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerSSWithDefaultYAML, "rejected-default-unconfined", namespace, "Unconfined", "rejected", "Unconfined"))
-			if err == nil {
-				t.Fatal("containers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", workloadsMessage)
 
 			return ctx
 		}).
@@ -2656,9 +2501,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerSSOnlyDefaultYAML, "rejected-only-default-unconfined", namespace, "Unconfined", "rejected"))
-			if err == nil {
-				t.Fatal("containers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", workloadsMessage)
 
 			return ctx
 		}).
@@ -2668,9 +2511,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerSSWithDefaultYAML, "rejected-default-unconfined", namespace, "Unconfined", "rejected", "RuntimeDefault"))
-			if err == nil {
-				t.Fatal("containers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", workloadsMessage)
 
 			return ctx
 		}).
@@ -2680,9 +2521,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerSSYAML, "rejected", namespace, "rejected", "RuntimeDefault", "rejected", "Unconfined"))
-			if err == nil {
-				t.Fatal("initContainers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", workloadsMessage)
 
 			return ctx
 		}).
@@ -2692,9 +2531,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerSSWithDefaultYAML, "rejected-default-rd", namespace, "RuntimeDefault", "rejected", "RuntimeDefault", "rejected", "Unconfined"))
-			if err == nil {
-				t.Fatal("initContainers with securityContext.seccompProfile.type field set to Unconfined were accepted, when the lower-priority spec.securityContext.seccompProfile.type was set to RuntimeDefault or Localhost")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", workloadsMessage)
 
 			return ctx
 		}).
@@ -2704,9 +2541,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerSSWithDefaultYAML, "rejected-default-unconfined", namespace, "Unconfined", "rejected", "RuntimeDefault", "rejected", "Unconfined"))
-			if err == nil {
-				t.Fatal("initContainers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", workloadsMessage)
 
 			return ctx
 		}).
@@ -2716,9 +2551,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerSSOnlyDefaultYAML, "rejected-only-default-unconfined", namespace, "Unconfined", "rejected", "rejected"))
-			if err == nil {
-				t.Fatal("containers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", workloadsMessage)
 
 			return ctx
 		}).
@@ -2728,9 +2561,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerSSWithDefaultYAML, "rejected-default-unconfined", namespace, "Unconfined", "rejected", "RuntimeDefault", "rejected", "RuntimeDefault"))
-			if err == nil {
-				t.Fatal("containers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", workloadsMessage)
 
 			return ctx
 		}).
@@ -2741,9 +2572,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerJobYAML, "success", namespace, "success", "RuntimeDefault"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2753,9 +2582,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerJobWithDefaultYAML, "success-default-rd", namespace, "RuntimeDefault", "success", "RuntimeDefault"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2765,9 +2592,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerJobOnlyDefaultYAML, "success-only-default-rd", namespace, "RuntimeDefault", "success"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2777,9 +2602,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerJobYAML, "success", namespace, "success", "RuntimeDefault", "success", "RuntimeDefault"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2789,9 +2612,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerJobWithDefaultYAML, "success-default-rd", namespace, "RuntimeDefault", "success", "RuntimeDefault", "success", "RuntimeDefault"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2801,9 +2622,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerJobOnlyDefaultYAML, "success-only-default-rd", namespace, "RuntimeDefault", "success", "success"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2813,9 +2632,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerJobYAML, "rejected", namespace, "rejected", "Unconfined"))
-			if err == nil {
-				t.Fatal("containers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", workloadsMessage)
 
 			return ctx
 		}).
@@ -2825,9 +2642,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerJobWithDefaultYAML, "rejected-default-rd", namespace, "RuntimeDefault", "rejected", "Unconfined"))
-			if err == nil {
-				t.Fatal("containers with securityContext.seccompProfile.type field set to Unconfined were accepted, when the lower-priority spec.securityContext.seccompProfile.type was set to RuntimeDefault or Localhost")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", workloadsMessage)
 
 			return ctx
 		}).
@@ -2837,9 +2652,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerJobWithDefaultYAML, "rejected-default-unconfined", namespace, "Unconfined", "rejected", "Unconfined"))
-			if err == nil {
-				t.Fatal("containers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", workloadsMessage)
 
 			return ctx
 		}).
@@ -2849,9 +2662,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerJobOnlyDefaultYAML, "rejected-only-default-unconfined", namespace, "Unconfined", "rejected"))
-			if err == nil {
-				t.Fatal("containers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", workloadsMessage)
 
 			return ctx
 		}).
@@ -2861,9 +2672,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerJobWithDefaultYAML, "rejected-default-unconfined", namespace, "Unconfined", "rejected", "RuntimeDefault"))
-			if err == nil {
-				t.Fatal("containers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", workloadsMessage)
 
 			return ctx
 		}).
@@ -2873,9 +2682,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerJobYAML, "rejected", namespace, "rejected", "RuntimeDefault", "rejected", "Unconfined"))
-			if err == nil {
-				t.Fatal("initContainers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", workloadsMessage)
 
 			return ctx
 		}).
@@ -2885,9 +2692,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerJobWithDefaultYAML, "rejected-default-rd", namespace, "RuntimeDefault", "rejected", "RuntimeDefault", "rejected", "Unconfined"))
-			if err == nil {
-				t.Fatal("initContainers with securityContext.seccompProfile.type field set to Unconfined were accepted, when the lower-priority spec.securityContext.seccompProfile.type was set to RuntimeDefault or Localhost")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", workloadsMessage)
 
 			return ctx
 		}).
@@ -2897,9 +2702,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerJobWithDefaultYAML, "rejected-default-unconfined", namespace, "Unconfined", "rejected", "RuntimeDefault", "rejected", "Unconfined"))
-			if err == nil {
-				t.Fatal("initContainers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", workloadsMessage)
 
 			return ctx
 		}).
@@ -2909,9 +2712,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerJobOnlyDefaultYAML, "rejected-only-default-unconfined", namespace, "Unconfined", "rejected", "rejected"))
-			if err == nil {
-				t.Fatal("containers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", workloadsMessage)
 
 			return ctx
 		}).
@@ -2921,9 +2722,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerJobWithDefaultYAML, "rejected-default-unconfined", namespace, "Unconfined", "rejected", "RuntimeDefault", "rejected", "RuntimeDefault"))
-			if err == nil {
-				t.Fatal("containers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", workloadsMessage)
 
 			return ctx
 		}).
@@ -2934,9 +2733,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerCronJobYAML, "success", namespace, "success", "RuntimeDefault"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2946,9 +2743,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerCronJobWithDefaultYAML, "success-default-rd", namespace, "RuntimeDefault", "success", "RuntimeDefault"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2958,9 +2753,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerCronJobOnlyDefaultYAML, "success-only-default-rd", namespace, "RuntimeDefault", "success"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2970,9 +2763,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(twoContainersCronJobWithDefaultOnlyOneYAML, "success-two-container-default", namespace, "RuntimeDefault", "success-01", "RuntimeDefault", "success-02"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2982,9 +2773,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerCronJobYAML, "success", namespace, "success", "RuntimeDefault", "success", "RuntimeDefault"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -2994,9 +2783,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerCronJobWithDefaultYAML, "success-default-rd", namespace, "RuntimeDefault", "success", "RuntimeDefault", "success", "RuntimeDefault"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -3006,9 +2793,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerCronJobOnlyDefaultYAML, "success-only-default-rd", namespace, "RuntimeDefault", "success", "success"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -3018,9 +2803,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(twoInitContainersCronJobWithDefaultOnlyOneYAML, "success-two-container-default", namespace, "RuntimeDefault", "success-01", "RuntimeDefault", "success-02", "RuntimeDefault", "success-03"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -3030,9 +2813,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerCronJobYAML, "rejected", namespace, "rejected", "Unconfined"))
-			if err == nil {
-				t.Fatal("containers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", cronJobsMessage)
 
 			return ctx
 		}).
@@ -3042,9 +2823,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerCronJobWithDefaultYAML, "rejected-default-rd", namespace, "RuntimeDefault", "rejected", "Unconfined"))
-			if err == nil {
-				t.Fatal("containers with securityContext.seccompProfile.type field set to Unconfined were accepted, when the lower-priority spec.securityContext.seccompProfile.type was set to RuntimeDefault or Localhost")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", cronJobsMessage)
 
 			return ctx
 		}).
@@ -3054,9 +2833,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerCronJobWithDefaultYAML, "rejected-default-unconfined", namespace, "Unconfined", "rejected", "Unconfined"))
-			if err == nil {
-				t.Fatal("containers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", cronJobsMessage)
 
 			return ctx
 		}).
@@ -3066,9 +2843,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerCronJobOnlyDefaultYAML, "rejected-only-default-unconfined", namespace, "Unconfined", "rejected"))
-			if err == nil {
-				t.Fatal("containers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", cronJobsMessage)
 
 			return ctx
 		}).
@@ -3078,9 +2853,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerCronJobWithDefaultYAML, "rejected-default-unconfined", namespace, "Unconfined", "rejected", "RuntimeDefault"))
-			if err == nil {
-				t.Fatal("containers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", cronJobsMessage)
 
 			return ctx
 		}).
@@ -3090,9 +2863,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerCronJobYAML, "rejected", namespace, "rejected", "RuntimeDefault", "rejected", "Unconfined"))
-			if err == nil {
-				t.Fatal("containers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", cronJobsMessage)
 
 			return ctx
 		}).
@@ -3102,9 +2873,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerCronJobWithDefaultYAML, "rejected-default-rd", namespace, "RuntimeDefault", "rejected", "RuntimeDefault", "rejected", "Unconfined"))
-			if err == nil {
-				t.Fatal("initContainers with securityContext.seccompProfile.type field set to Unconfined were accepted, when the lower-priority spec.securityContext.seccompProfile.type was set to RuntimeDefault or Localhost")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", cronJobsMessage)
 
 			return ctx
 		}).
@@ -3114,9 +2883,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerCronJobWithDefaultYAML, "rejected-default-unconfined", namespace, "Unconfined", "rejected", "RuntimeDefault", "rejected", "Unconfined"))
-			if err == nil {
-				t.Fatal("initContainers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", cronJobsMessage)
 
 			return ctx
 		}).
@@ -3126,9 +2893,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerCronJobOnlyDefaultYAML, "rejected-only-default-unconfined", namespace, "Unconfined", "rejected", "rejected"))
-			if err == nil {
-				t.Fatal("containers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", cronJobsMessage)
 
 			return ctx
 		}).
@@ -3138,9 +2903,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerCronJobWithDefaultYAML, "rejected-default-unconfined", namespace, "Unconfined", "rejected", "RuntimeDefault", "rejected", "RuntimeDefault"))
-			if err == nil {
-				t.Fatal("containers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", cronJobsMessage)
 
 			return ctx
 		}).
@@ -3150,9 +2913,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerCronJobWithDefaultYAML, "rejected-default-unconfined", namespace, "Unconfined", "rejected", "RuntimeDefault", "rejected", "RuntimeDefault"))
-			if err == nil {
-				t.Fatal("containers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", cronJobsMessage)
 
 			return ctx
 		}).
@@ -3163,9 +2924,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerRCYAML, "success", namespace, "success", "RuntimeDefault"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -3175,9 +2934,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerRCWithDefaultYAML, "success-default-rd", namespace, "RuntimeDefault", "success", "RuntimeDefault"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -3187,9 +2944,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerRCOnlyDefaultYAML, "success-only-default-rd", namespace, "RuntimeDefault", "success"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -3199,9 +2954,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerRCYAML, "success", namespace, "success", "RuntimeDefault", "success", "RuntimeDefault"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -3211,9 +2964,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerRCWithDefaultYAML, "success-default-rd", namespace, "RuntimeDefault", "success", "RuntimeDefault", "success", "RuntimeDefault"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -3223,9 +2974,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerRCOnlyDefaultYAML, "success-only-default-rd", namespace, "RuntimeDefault", "success", "success"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -3235,9 +2984,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerRCYAML, "rejected", namespace, "rejected", "Unconfined"))
-			if err == nil {
-				t.Fatal("containers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", workloadsMessage)
 
 			return ctx
 		}).
@@ -3247,9 +2994,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerRCWithDefaultYAML, "rejected-default-rd", namespace, "RuntimeDefault", "rejected", "Unconfined"))
-			if err == nil {
-				t.Fatal("containers with securityContext.seccompProfile.type field set to Unconfined were accepted, when the lower-priority spec.securityContext.seccompProfile.type was set to RuntimeDefault or Localhost")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", workloadsMessage)
 
 			return ctx
 		}).
@@ -3259,9 +3004,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerRCWithDefaultYAML, "rejected-default-unconfined", namespace, "Unconfined", "rejected", "Unconfined"))
-			if err == nil {
-				t.Fatal("containers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", workloadsMessage)
 
 			return ctx
 		}).
@@ -3271,9 +3014,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerRCOnlyDefaultYAML, "rejected-only-default-unconfined", namespace, "Unconfined", "rejected"))
-			if err == nil {
-				t.Fatal("containers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", workloadsMessage)
 
 			return ctx
 		}).
@@ -3283,9 +3024,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerRCWithDefaultYAML, "rejected-default-unconfined", namespace, "Unconfined", "rejected", "RuntimeDefault"))
-			if err == nil {
-				t.Fatal("containers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", workloadsMessage)
 
 			return ctx
 		}).
@@ -3295,9 +3034,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerRCYAML, "rejected", namespace, "rejected", "RuntimeDefault", "rejected", "Unconfined"))
-			if err == nil {
-				t.Fatal("initContainers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", workloadsMessage)
 
 			return ctx
 		}).
@@ -3307,9 +3044,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerRCWithDefaultYAML, "rejected-default-rd", namespace, "RuntimeDefault", "rejected", "RuntimeDefault", "rejected", "Unconfined"))
-			if err == nil {
-				t.Fatal("initContainers with securityContext.seccompProfile.type field set to Unconfined were accepted, when the lower-priority spec.securityContext.seccompProfile.type was set to RuntimeDefault or Localhost")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", workloadsMessage)
 
 			return ctx
 		}).
@@ -3319,9 +3054,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerRCWithDefaultYAML, "rejected-default-unconfined", namespace, "Unconfined", "rejected", "RuntimeDefault", "rejected", "Unconfined"))
-			if err == nil {
-				t.Fatal("initContainers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", workloadsMessage)
 
 			return ctx
 		}).
@@ -3331,9 +3064,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerRCOnlyDefaultYAML, "rejected-only-default-unconfined", namespace, "Unconfined", "rejected", "rejected"))
-			if err == nil {
-				t.Fatal("containers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", workloadsMessage)
 
 			return ctx
 		}).
@@ -3343,9 +3074,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerRCWithDefaultYAML, "rejected-default-unconfined", namespace, "Unconfined", "rejected", "RuntimeDefault", "rejected", "RuntimeDefault"))
-			if err == nil {
-				t.Fatal("containers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", workloadsMessage)
 
 			return ctx
 		}).
@@ -3356,9 +3085,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerPodTemplateYAML, "success", namespace, "success", "RuntimeDefault"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -3368,9 +3095,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerPodTemplateWithDefaultYAML, "success-default-rd", namespace, "RuntimeDefault", "success", "RuntimeDefault"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -3380,9 +3105,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerPodTemplateOnlyDefaultYAML, "success-only-default-rd", namespace, "RuntimeDefault", "success"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -3392,9 +3115,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(twoContainersPodTemplateWithDefaultOnlyOneYAML, "success-two-container-default", namespace, "RuntimeDefault", "success-01", "RuntimeDefault", "success-02"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -3404,9 +3125,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerPodTemplateYAML, "success", namespace, "success", "RuntimeDefault", "success", "RuntimeDefault"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -3416,9 +3135,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerPodTemplateWithDefaultYAML, "success-default-rd", namespace, "RuntimeDefault", "success", "RuntimeDefault", "success", "RuntimeDefault"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -3428,9 +3145,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerPodTemplateOnlyDefaultYAML, "success-only-default-rd", namespace, "RuntimeDefault", "success", "success"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -3440,9 +3155,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(twoInitContainersPodTemplateWithDefaultOnlyOneYAML, "success-two-container-default", namespace, "RuntimeDefault", "success-01", "RuntimeDefault", "success-02", "RuntimeDefault", "success-03"))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -3452,9 +3165,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerPodTemplateYAML, "rejected", namespace, "rejected", "Unconfined"))
-			if err == nil {
-				t.Fatal("containers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", podTemplatesMessage)
 
 			return ctx
 		}).
@@ -3464,9 +3175,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerPodTemplateWithDefaultYAML, "rejected-default-rd", namespace, "RuntimeDefault", "rejected", "Unconfined"))
-			if err == nil {
-				t.Fatal("containers with securityContext.seccompProfile.type field set to Unconfined were accepted, when the lower-priority spec.securityContext.seccompProfile.type was set to RuntimeDefault or Localhost")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", podTemplatesMessage)
 
 			return ctx
 		}).
@@ -3476,9 +3185,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerPodTemplateWithDefaultYAML, "rejected-default-unconfined", namespace, "Unconfined", "rejected", "Unconfined"))
-			if err == nil {
-				t.Fatal("containers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", podTemplatesMessage)
 
 			return ctx
 		}).
@@ -3488,9 +3195,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerPodTemplateOnlyDefaultYAML, "rejected-only-default-unconfined", namespace, "Unconfined", "rejected"))
-			if err == nil {
-				t.Fatal("containers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", podTemplatesMessage)
 
 			return ctx
 		}).
@@ -3500,9 +3205,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerPodTemplateWithDefaultYAML, "rejected-default-unconfined", namespace, "Unconfined", "rejected", "RuntimeDefault"))
-			if err == nil {
-				t.Fatal("containers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", podTemplatesMessage)
 
 			return ctx
 		}).
@@ -3512,9 +3215,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerPodTemplateYAML, "rejected", namespace, "rejected", "RuntimeDefault", "rejected", "Unconfined"))
-			if err == nil {
-				t.Fatal("initContainers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", podTemplatesMessage)
 
 			return ctx
 		}).
@@ -3524,9 +3225,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerPodTemplateWithDefaultYAML, "rejected-default-rd", namespace, "RuntimeDefault", "rejected", "RuntimeDefault", "rejected", "Unconfined"))
-			if err == nil {
-				t.Fatal("initContainers with securityContext.seccompProfile.type field set to Unconfined were accepted, when the lower-priority spec.securityContext.seccompProfile.type was set to RuntimeDefault or Localhost")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", podTemplatesMessage)
 
 			return ctx
 		}).
@@ -3536,9 +3235,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerPodTemplateWithDefaultYAML, "rejected-default-unconfined", namespace, "Unconfined", "rejected", "RuntimeDefault", "rejected", "Unconfined"))
-			if err == nil {
-				t.Fatal("initContainers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", podTemplatesMessage)
 
 			return ctx
 		}).
@@ -3548,9 +3245,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerPodTemplateOnlyDefaultYAML, "rejected-only-default-unconfined", namespace, "Unconfined", "rejected", "rejected"))
-			if err == nil {
-				t.Fatal("containers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", podTemplatesMessage)
 
 			return ctx
 		}).
@@ -3560,9 +3255,7 @@ func TestSeccomp(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerPodTemplateWithDefaultYAML, "rejected-default-unconfined", namespace, "Unconfined", "rejected", "RuntimeDefault", "rejected", "RuntimeDefault"))
-			if err == nil {
-				t.Fatal("containers without securityContext.seccompProfile.type field set to RuntimeDefault or Localhost were accepted")
-			}
+			testutils.ExpectDenied(t, err, "pss-seccomp", podTemplatesMessage)
 
 			return ctx
 		})
@@ -3570,90 +3263,146 @@ func TestSeccomp(t *testing.T) {
 
 }
 
-func TestEphemeralContainers(t *testing.T) {
+func TestWarnMode(t *testing.T) {
 
-	f := features.New("Pods with ephemeral containers").
+	f := features.New("Warn mode tests").
 		Setup(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
 			// get namespace
 			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
 
-			// create a pod that will be used for ephemeral container tests
-			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerYAML, "ephemeral", namespace, "ephemeral", "RuntimeDefault"))
+			// switch the namespace from the deny to the warn binding
+			err := testutils.AddNamespaceLabels(ctx, cfg, namespace, map[string]string{"vap-library.com/pss-seccomp": "warn"})
 			if err != nil {
 				t.Fatal(err)
 			}
 
-			// wait for the pod
-			time.Sleep(2 * time.Second)
-
 			return ctx
 		}).
-		Assess("An invalid ephemeral container is rejected", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+		Assess("A Pod with Unconfined seccomp profile is accepted with a warning", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
 			// get namespace
 			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
 
-			// get client
-			client, err := cfg.NewClient()
-			if err != nil {
-				t.Fatal(err)
-			}
+			// this should PASS with a warning!
+			warnings, err := testutils.ApplyK8sResourceFromYAMLWithWarnings(ctx, cfg, fmt.Sprintf(containerYAML, "rejected", namespace, "rejected", "Unconfined"))
+			testutils.ExpectAllowed(t, err)
+			testutils.ExpectWarned(t, warnings, podsMessage)
 
-			// get the pod that was created in setup to attach an ephemeral container to it
-			pod := &v1.Pod{}
-			err = client.Resources(namespace).Get(ctx, "seccomp-ephemeral", namespace, pod)
-			if err != nil {
-				t.Fatal(err)
-			}
+			return ctx
+		})
+
+	_ = testEnv.Test(t, f.Feature())
 
-			// define patch type
-			patchType := types.StrategicMergePatchType
+}
 
-			// define patch data
-			patchData := []byte(fmt.Sprintf(containerEphemeralPatchYAML, "Unconfined"))
+func TestAuditMode(t *testing.T) {
 
-			patch := k8s.Patch{PatchType: patchType, Data: patchData}
+	f := features.New("Audit mode tests").
+		Setup(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// get namespace
+			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
 
-			// patch the pod, this should FAIL!
-			err = client.Resources(namespace).PatchSubresource(ctx, pod, "ephemeralcontainers", patch)
-			if err == nil {
-				t.Fatal("ephemeral container without securityContext.seccompProfile.type field should be rejected")
+			// switch the namespace from the deny to the audit binding
+			err := testutils.AddNamespaceLabels(ctx, cfg, namespace, map[string]string{"vap-library.com/pss-seccomp": "audit"})
+			if err != nil {
+				t.Fatal(err)
 			}
 
 			return ctx
 		}).
-		Assess("A valid ephemeral container is accepted", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+		Assess("A Pod with Unconfined seccomp profile is accepted and audited", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
 			// get namespace
 			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
 
-			// get client
-			client, err := cfg.NewClient()
-			if err != nil {
-				t.Fatal(err)
-			}
+			// the audit events are only available with the in-process backend or the audit log
+			testutils.SkipWithoutAuditLog(ctx, t)
 
-			// get the pod that was created in setup to attach an ephemeral container to it
-			pod := &v1.Pod{}
-			err = client.Resources(namespace).Get(ctx, "seccomp-ephemeral", namespace, pod)
-			if err != nil {
-				t.Fatal(err)
-			}
+			// this should PASS and be audited!
+			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerYAML, "rejected", namespace, "rejected", "Unconfined"))
+			testutils.ExpectAllowed(t, err)
+			testutils.ExpectAudited(ctx, t, namespace, "pss-seccomp", podsMessage)
 
-			// define patch type
-			patchType := types.StrategicMergePatchType
+			return ctx
+		})
 
-			// define patch data
-			patchData := []byte(fmt.Sprintf(containerEphemeralPatchYAML, "RuntimeDefault"))
+	_ = testEnv.Test(t, f.Feature())
 
-			patch := k8s.Patch{PatchType: patchType, Data: patchData}
+}
 
-			// patch the pod
-			err = client.Resources(namespace).PatchSubresource(ctx, pod, "ephemeralcontainers", patch)
-			if err != nil {
-				t.Fatal(err)
-			}
+func TestEphemeralContainers(t *testing.T) {
+
+	f := features.New("Ephemeral containers added through the ephemeralcontainers subresource").
+		Assess("An invalid ephemeral container is rejected", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// get namespace
+			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
+
+			// add the ephemeral container like kubectl debug does, this should FAIL!
+			err := testutils.ApplyEphemeralContainerFromYAML(ctx, cfg, fmt.Sprintf(containerYAML, "subresource", namespace, "subresource", "RuntimeDefault"), fmt.Sprintf(ephemeralContainerYAML, "Unconfined"))
+			testutils.ExpectDenied(t, err, "pss-seccomp", podsMessage)
+
+			return ctx
+		}).
+		Assess("A valid ephemeral container is accepted", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// get namespace
+			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
+
+			// add the ephemeral container like kubectl debug does
+			err := testutils.ApplyEphemeralContainerFromYAML(ctx, cfg, fmt.Sprintf(containerYAML, "subresource", namespace, "subresource", "RuntimeDefault"), fmt.Sprintf(ephemeralContainerYAML, "RuntimeDefault"))
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		})
 	_ = testEnv.Test(t, f.Feature())
 
 }
+
+func TestWorkloadKinds(t *testing.T) {
+
+	testutils.RunWorkloadTestCases(t, testEnv,
+		testutils.WorkloadTestCase{
+			Name:     "Workloads with an invalid container",
+			PodSpec:  fmt.Sprintf(podSpecYAML, "Unconfined"),
+			Expect:   testutils.Expectation{Result: testutils.ResultDenied},
+			Messages: testutils.WorkloadMessages(podsMessage, workloadsMessage, cronJobsMessage, podTemplatesMessage),
+		},
+		testutils.WorkloadTestCase{
+			Name:    "Workloads with a valid container",
+			PodSpec: fmt.Sprintf(podSpecYAML, "RuntimeDefault"),
+			Expect:  testutils.Expectation{Result: testutils.ResultAllowed},
+		},
+	)
+
+}
+
+func TestDifferential(t *testing.T) {
+
+	// compare the policy with the check of k8s.io/pod-security-admission
+	testutils.RunDifferentialTests(t, testEnv, "pss-seccomp", testutils.KnownDifferenceWindows)
+
+}
+
+func TestTypeChecking(t *testing.T) {
+
+	// the expressions are guarded by the kind, a misspelled field path must still be reported for every kind
+	objects, err := decoder.DecodeAllFiles(context.Background(), os.DirFS("."), "policy.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	warnings, err := testutils.TypeCheckPolicies(objects)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 0 {
+		t.Fatalf("expected no type checking warnings, got %v", warnings)
+	}
+
+	policy := objects[0].(*admissionregistrationv1.ValidatingAdmissionPolicy)
+	policy.Spec.Validations[0].Expression = strings.Replace(policy.Spec.Validations[0].Expression, "object.spec.containers.all", "object.spec.container.all", 1)
+	warnings, err = testutils.TypeCheckPolicies(objects)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := warnings[policy.Name]; len(w) != 1 || w[0].FieldRef != "spec.validations[0].expression" || !strings.Contains(w[0].Warning, "undefined field 'container'") {
+		t.Fatalf("expected a type checking warning for the misspelled field path of spec.validations[0].expression, got %v", warnings)
+	}
+
+}
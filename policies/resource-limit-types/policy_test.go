@@ -6,7 +6,6 @@ import (
 	"log"
 	"os"
 	"testing"
-	"time"
 	"vap-library/testutils"
 
 	"sigs.k8s.io/e2e-framework/pkg/envconf"
@@ -15,6 +14,14 @@ import (
 	"sigs.k8s.io/e2e-framework/pkg/env"
 )
 
+// expected denial messages
+const (
+	podsMessage         = "If enforcedResourceLimitTypes is set on the parameter, for every container and initContainer in Pods, spec.resources.limits must be present and contain every item from the spec.enforcedResourceLimitTypes list in the policy parameter"
+	workloadsMessage    = "If enforcedResourceLimitTypes is set on the parameter, for every container and initContainer in Workloads, spec.resources.limits must be present and contain every item from the spec.enforcedResourceLimitTypes list in the policy parameter"
+	podTemplatesMessage = "If enforcedResourceLimitTypes is set on the parameter, for every container and initContainer in PodTemplates, spec.resources.limits must be present and contain every item from the spec.enforcedResourceLimitTypes list in the policy parameter"
+	cronJobsMessage     = "If enforcedResourceLimitTypes is set on the parameter, for every container and initContainer in CronJobs, spec.resources.limits must be present and contain every item from the spec.enforcedResourceLimitTypes list in the policy parameter"
+)
+
 // TEST DATA FOR POD TESTS
 
 var testParameterYAML string = `
@@ -522,6 +529,47 @@ var matchingResourceLimitsPodTemplate string = `cpu: 500m
 var nonMatchingResourceLimitsPodTemplate string = `cpu: 500m
           memory: 128Mi`
 
+// validParameterYAMLs are the edge cases of the parameter schema: every resource type alone and all of them
+var validParameterYAMLs = []string{`
+apiVersion: vap-library.com/v1beta1
+kind: VAPLibResourceLimitTypesParam
+metadata:
+  name: resource-limit-types-1
+  namespace: %s
+spec:
+  enforcedResourceLimitTypes:
+  - cpu
+`, `
+apiVersion: vap-library.com/v1beta1
+kind: VAPLibResourceLimitTypesParam
+metadata:
+  name: resource-limit-types-2
+  namespace: %s
+spec:
+  enforcedResourceLimitTypes:
+  - memory
+`, `
+apiVersion: vap-library.com/v1beta1
+kind: VAPLibResourceLimitTypesParam
+metadata:
+  name: resource-limit-types-3
+  namespace: %s
+spec:
+  enforcedResourceLimitTypes:
+  - ephemeral-storage
+`, `
+apiVersion: vap-library.com/v1beta1
+kind: VAPLibResourceLimitTypesParam
+metadata:
+  name: resource-limit-types-4
+  namespace: %s
+spec:
+  enforcedResourceLimitTypes:
+  - cpu
+  - memory
+  - ephemeral-storage
+`}
+
 var testEnv env.Environment
 
 func TestMain(m *testing.M) {
@@ -534,9 +582,6 @@ func TestMain(m *testing.M) {
 		log.Fatalf("Unable to create Kind cluster for test. Error msg: %s", err)
 	}
 
-	// wait for the cluster to be ready
-	time.Sleep(2 * time.Second)
-
 	os.Exit(testEnv.Run(m))
 }
 
@@ -548,14 +593,11 @@ func TestResourceLimits(t *testing.T) {
 			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
 
 			// apply parameter first
-			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(testParameterYAML, namespace))
+			err := testutils.ApplyParameterFromYAML(ctx, cfg, fmt.Sprintf(testParameterYAML, namespace))
 			if err != nil {
 				t.Fatal(err)
 			}
 
-			// wait for the parameter to be registered properly
-			time.Sleep(10 * time.Second)
-
 			return ctx
 		}).
 		// POD TESTS
@@ -565,9 +607,7 @@ func TestResourceLimits(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerYAML, "success", namespace, "success", matchingResourceLimits))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -577,9 +617,7 @@ func TestResourceLimits(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerYAML, "rejected", namespace, "rejected", nonMatchingResourceLimits))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "resource-limit-types", podsMessage)
 
 			return ctx
 		}).
@@ -589,9 +627,7 @@ func TestResourceLimits(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerYAML, "success", namespace, "success", matchingResourceLimits, "success", matchingResourceLimits))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -601,9 +637,7 @@ func TestResourceLimits(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerYAML, "rejected", namespace, "rejected", matchingResourceLimits, "rejected", nonMatchingResourceLimits))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "resource-limit-types", podsMessage)
 
 			return ctx
 		}).
@@ -614,9 +648,7 @@ func TestResourceLimits(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerDeploymentYAML, "success", namespace, "success", matchingResourceLimitsWorkload))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -626,9 +658,7 @@ func TestResourceLimits(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerDeploymentYAML, "rejected", namespace, "rejected", nonMatchingResourceLimitsWorkload))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "resource-limit-types", workloadsMessage)
 
 			return ctx
 		}).
@@ -638,9 +668,7 @@ func TestResourceLimits(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerDeploymentYAML, "success", namespace, "success", matchingResourceLimitsWorkload, "success", matchingResourceLimitsWorkload))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -650,9 +678,7 @@ func TestResourceLimits(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerDeploymentYAML, "rejected", namespace, "rejected", matchingResourceLimitsWorkload, "rejected", nonMatchingResourceLimitsWorkload))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "resource-limit-types", workloadsMessage)
 
 			return ctx
 		}).
@@ -663,9 +689,7 @@ func TestResourceLimits(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerRSYAML, "success", namespace, "success", matchingResourceLimitsWorkload))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -675,9 +699,7 @@ func TestResourceLimits(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerRSYAML, "rejected", namespace, "rejected", nonMatchingResourceLimitsWorkload))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "resource-limit-types", workloadsMessage)
 
 			return ctx
 		}).
@@ -687,9 +709,7 @@ func TestResourceLimits(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerRSYAML, "success", namespace, "success", matchingResourceLimitsWorkload, "success", matchingResourceLimitsWorkload))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -699,9 +719,7 @@ func TestResourceLimits(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerRSYAML, "rejected", namespace, "rejected", matchingResourceLimitsWorkload, "rejected", nonMatchingResourceLimitsWorkload))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "resource-limit-types", workloadsMessage)
 
 			return ctx
 		}).
@@ -712,9 +730,7 @@ func TestResourceLimits(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerDSYAML, "success", namespace, "success", matchingResourceLimitsWorkload))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -724,9 +740,7 @@ func TestResourceLimits(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerDSYAML, "rejected", namespace, "rejected", nonMatchingResourceLimitsWorkload))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "resource-limit-types", workloadsMessage)
 
 			return ctx
 		}).
@@ -736,9 +750,7 @@ func TestResourceLimits(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerDSYAML, "success", namespace, "success", matchingResourceLimitsWorkload, "success", matchingResourceLimitsWorkload))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -748,9 +760,7 @@ func TestResourceLimits(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerDSYAML, "rejected", namespace, "rejected", matchingResourceLimitsWorkload, "rejected", nonMatchingResourceLimitsWorkload))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "resource-limit-types", workloadsMessage)
 
 			return ctx
 		}).
@@ -761,9 +771,7 @@ func TestResourceLimits(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerSSYAML, "success", namespace, "success", matchingResourceLimitsWorkload))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -773,9 +781,7 @@ func TestResourceLimits(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerSSYAML, "rejected", namespace, "rejected", nonMatchingResourceLimitsWorkload))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "resource-limit-types", workloadsMessage)
 
 			return ctx
 		}).
@@ -785,9 +791,7 @@ func TestResourceLimits(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerSSYAML, "success", namespace, "success", matchingResourceLimitsWorkload, "success", matchingResourceLimitsWorkload))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -797,9 +801,7 @@ func TestResourceLimits(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerSSYAML, "rejected", namespace, "rejected", matchingResourceLimitsWorkload, "rejected", nonMatchingResourceLimitsWorkload))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "resource-limit-types", workloadsMessage)
 
 			return ctx
 		}).
@@ -810,9 +812,7 @@ func TestResourceLimits(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerJobYAML, "success", namespace, "success", matchingResourceLimitsWorkload))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -822,9 +822,7 @@ func TestResourceLimits(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerJobYAML, "rejected", namespace, "rejected", nonMatchingResourceLimitsWorkload))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "resource-limit-types", workloadsMessage)
 
 			return ctx
 		}).
@@ -834,9 +832,7 @@ func TestResourceLimits(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerJobYAML, "success", namespace, "success", matchingResourceLimitsWorkload, "success", matchingResourceLimitsWorkload))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -846,9 +842,7 @@ func TestResourceLimits(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerJobYAML, "rejected", namespace, "rejected", matchingResourceLimitsWorkload, "rejected", nonMatchingResourceLimitsWorkload))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "resource-limit-types", workloadsMessage)
 
 			return ctx
 		}).
@@ -859,9 +853,7 @@ func TestResourceLimits(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerCronJobYAML, "success", namespace, "success", matchingResourceLimitsCronjob))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -871,9 +863,7 @@ func TestResourceLimits(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerCronJobYAML, "rejected", namespace, "rejected", nonMatchingResourceLimitsCronjob))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "resource-limit-types", cronJobsMessage)
 
 			return ctx
 		}).
@@ -883,9 +873,7 @@ func TestResourceLimits(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerCronJobYAML, "success", namespace, "success", matchingResourceLimitsCronjob, "success", matchingResourceLimitsCronjob))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -895,9 +883,7 @@ func TestResourceLimits(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerCronJobYAML, "rejected", namespace, "rejected", matchingResourceLimitsCronjob, "rejected", nonMatchingResourceLimitsCronjob))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "resource-limit-types", cronJobsMessage)
 
 			return ctx
 		}).
@@ -908,9 +894,7 @@ func TestResourceLimits(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerRCYAML, "success", namespace, "success", matchingResourceLimitsWorkload))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -920,9 +904,7 @@ func TestResourceLimits(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerRCYAML, "rejected", namespace, "rejected", nonMatchingResourceLimitsWorkload))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "resource-limit-types", workloadsMessage)
 
 			return ctx
 		}).
@@ -932,9 +914,7 @@ func TestResourceLimits(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerRCYAML, "success", namespace, "success", matchingResourceLimitsWorkload, "success", matchingResourceLimitsWorkload))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -944,9 +924,7 @@ func TestResourceLimits(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerRCYAML, "rejected", namespace, "rejected", matchingResourceLimitsWorkload, "rejected", nonMatchingResourceLimitsWorkload))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "resource-limit-types", workloadsMessage)
 
 			return ctx
 		}).
@@ -957,9 +935,7 @@ func TestResourceLimits(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerPodTemplateYAML, "success", namespace, "success", matchingResourceLimitsPodTemplate))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -969,9 +945,7 @@ func TestResourceLimits(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerPodTemplateYAML, "rejected", namespace, "rejected", nonMatchingResourceLimitsPodTemplate))
-			if err == nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectDenied(t, err, "resource-limit-types", podTemplatesMessage)
 
 			return ctx
 		}).
@@ -981,9 +955,7 @@ func TestResourceLimits(t *testing.T) {
 
 			// this should PASS!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerPodTemplateYAML, "success", namespace, "success", matchingResourceLimitsPodTemplate, "success", matchingResourceLimitsPodTemplate))
-			if err != nil {
-				t.Fatal(err)
-			}
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
@@ -993,13 +965,92 @@ func TestResourceLimits(t *testing.T) {
 
 			// this should FAIL!
 			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(initContainerPodTemplateYAML, "rejected", namespace, "rejected", matchingResourceLimitsPodTemplate, "rejected", nonMatchingResourceLimitsPodTemplate))
-			if err == nil {
+			testutils.ExpectDenied(t, err, "resource-limit-types", podTemplatesMessage)
+
+			return ctx
+		})
+
+	_ = testEnv.Test(t, f.Feature())
+
+}
+
+func TestWarnMode(t *testing.T) {
+
+	f := features.New("Warn mode tests").
+		Setup(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// get namespace
+			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
+
+			// switch the namespace from the deny to the warn binding
+			err := testutils.AddNamespaceLabels(ctx, cfg, namespace, map[string]string{"vap-library.com/resource-limit-types": "warn"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// apply parameter
+			err = testutils.ApplyParameterFromYAML(ctx, cfg, fmt.Sprintf(testParameterYAML, namespace))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			return ctx
+		}).
+		Assess("A Pod without the enforced resource limits is accepted with a warning", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// get namespace
+			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
+
+			// this should PASS with a warning!
+			warnings, err := testutils.ApplyK8sResourceFromYAMLWithWarnings(ctx, cfg, fmt.Sprintf(containerYAML, "rejected", namespace, "rejected", nonMatchingResourceLimits))
+			testutils.ExpectAllowed(t, err)
+			testutils.ExpectWarned(t, warnings, podsMessage)
+
+			return ctx
+		})
+
+	_ = testEnv.Test(t, f.Feature())
+
+}
+
+func TestAuditMode(t *testing.T) {
+
+	f := features.New("Audit mode tests").
+		Setup(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// get namespace
+			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
+
+			// switch the namespace from the deny to the audit binding
+			err := testutils.AddNamespaceLabels(ctx, cfg, namespace, map[string]string{"vap-library.com/resource-limit-types": "audit"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// apply parameter
+			err = testutils.ApplyParameterFromYAML(ctx, cfg, fmt.Sprintf(testParameterYAML, namespace))
+			if err != nil {
 				t.Fatal(err)
 			}
 
+			return ctx
+		}).
+		Assess("A Pod without the enforced resource limits is accepted and audited", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// get namespace
+			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
+
+			// the audit events are only available with the in-process backend or the audit log
+			testutils.SkipWithoutAuditLog(ctx, t)
+
+			// this should PASS and be audited!
+			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(containerYAML, "rejected", namespace, "rejected", nonMatchingResourceLimits))
+			testutils.ExpectAllowed(t, err)
+			testutils.ExpectAudited(ctx, t, namespace, "resource-limit-types", podsMessage)
+
 			return ctx
 		})
 
 	_ = testEnv.Test(t, f.Feature())
 
 }
+
+func TestParameterSchema(t *testing.T) {
+	testutils.RunParameterSchemaTests(t, testEnv, "crd-parameter.yaml", validParameterYAMLs)
+}
@@ -6,14 +6,22 @@ import (
 	"log"
 	"os"
 	"testing"
-	"time"
 	"vap-library/testutils"
 
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/e2e-framework/klient/k8s"
 	"sigs.k8s.io/e2e-framework/pkg/env"
 	"sigs.k8s.io/e2e-framework/pkg/envconf"
 	"sigs.k8s.io/e2e-framework/pkg/features"
 )
 
+// expected denial messages
+const (
+	serviceTypeMessage = "spec.type must be present and must be on the spec.allowedTypes list"
+)
+
 var testParameterYAML string = `
 apiVersion: vap-library.com/v1beta1
 kind: VAPLibServiceTypeParam
@@ -23,66 +31,105 @@ metadata:
 spec:
   allowedTypes:
   - ClusterIP
-  - NodePort
 `
 
-var testParameterNoCIPYAML string = `
+var testParameterSelectedYAML string = `
 apiVersion: vap-library.com/v1beta1
 kind: VAPLibServiceTypeParam
 metadata:
-  name: service-type.vap-library.com
+  name: selected-parameter
   namespace: %s
+  labels:
+    vap-library.com/parameter-set: selected
 spec:
   allowedTypes:
-  - NodePort
+  - ClusterIP
 `
 
-var serviceClusterIPYAML string = `
+var loadBalancerServiceYAML string = `
 apiVersion: v1
 kind: Service
 metadata:
-  name: test-clusterip
+  name: %s
   namespace: %s
+  labels:
+    vap-library.com/service-type-test: %s
 spec:
   ports:
-  - appProtocol: http
-    port: 8080
+  - port: 8080
     targetPort: 8080
   selector:
     app: myapp
-  type: ClusterIP
+  type: LoadBalancer
 `
 
-var serviceNoTypeYAML string = `
+var clusterIPServiceYAML string = `
 apiVersion: v1
 kind: Service
 metadata:
-  name: test-notype
+  name: %s
   namespace: %s
+  labels:
+    vap-library.com/service-type-test: %s
 spec:
   ports:
-  - appProtocol: http
-    port: 8080
+  - port: 8080
     targetPort: 8080
   selector:
     app: myapp
+  type: ClusterIP
 `
 
-var serviceLBYAML string = `
-apiVersion: v1
-kind: Service
+// validParameterYAMLs are the edge cases of the parameter schema: every allowed type alone and all of them
+var validParameterYAMLs = []string{`
+apiVersion: vap-library.com/v1beta1
+kind: VAPLibServiceTypeParam
 metadata:
-  name: test-clusterip
+  name: service-type-1
   namespace: %s
 spec:
-  ports:
-  - appProtocol: http
-    port: 8080
-    targetPort: 8080
-  selector:
-    app: myapp
-  type: LoadBalancer
-`
+  allowedTypes:
+  - ClusterIP
+`, `
+apiVersion: vap-library.com/v1beta1
+kind: VAPLibServiceTypeParam
+metadata:
+  name: service-type-2
+  namespace: %s
+spec:
+  allowedTypes:
+  - NodePort
+`, `
+apiVersion: vap-library.com/v1beta1
+kind: VAPLibServiceTypeParam
+metadata:
+  name: service-type-3
+  namespace: %s
+spec:
+  allowedTypes:
+  - LoadBalancer
+`, `
+apiVersion: vap-library.com/v1beta1
+kind: VAPLibServiceTypeParam
+metadata:
+  name: service-type-4
+  namespace: %s
+spec:
+  allowedTypes:
+  - ExternalName
+`, `
+apiVersion: vap-library.com/v1beta1
+kind: VAPLibServiceTypeParam
+metadata:
+  name: service-type-5
+  namespace: %s
+spec:
+  allowedTypes:
+  - ClusterIP
+  - NodePort
+  - LoadBalancer
+  - ExternalName
+`}
 
 var testEnv env.Environment
 
@@ -96,61 +143,84 @@ func TestMain(m *testing.M) {
 		log.Fatalf("Unable to create Kind cluster for test. Error msg: %s", err)
 	}
 
-	// wait for the cluster to be ready
-	time.Sleep(2 * time.Second)
-
 	os.Exit(testEnv.Run(m))
 }
 
-func TestWithFullParameter(t *testing.T) {
+// TestServiceType runs the declarative test cases from the tests directory
+func TestServiceType(t *testing.T) {
+	testutils.RunTestCasesFromDir(t, testEnv, "tests")
+}
+
+// bindingWithSelectors returns a binding that only selects the labelled Services and looks up the parameters by a label
+// selector, the requests are allowed if there is no parameter
+func bindingWithSelectors() *testutils.BindingBuilder {
+	return testutils.NewBindingBuilder("service-type", "selectors").
+		WithValidationActions(admissionregistrationv1.Deny).
+		WithObjectSelector(&metav1.LabelSelector{MatchLabels: map[string]string{"vap-library.com/service-type-test": "selected"}}).
+		WithParamSelector(&metav1.LabelSelector{MatchLabels: map[string]string{"vap-library.com/parameter-set": "selected"}}).
+		WithParameterNotFoundAction(admissionregistrationv1.AllowAction)
+}
+
+func TestBindingWithSelectors(t *testing.T) {
 
-	f := features.New("Service with full parameter").
+	f := features.New("Binding with object and parameter selectors").
 		Setup(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
 			// get namespace
 			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
 
-			// apply parameter first
-			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(testParameterYAML, namespace))
+			// switch the namespace from the deny binding to the binding with selectors
+			err := testutils.AddNamespaceLabels(ctx, cfg, namespace, map[string]string{"vap-library.com/service-type": "selectors"})
 			if err != nil {
 				t.Fatal(err)
 			}
 
-			// wait for the parameter to be registered properly
-			time.Sleep(10 * time.Second)
+			timeout, err := testutils.ReadinessTimeout()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := bindingWithSelectors().Apply(ctx, cfg, timeout); err != nil {
+				t.Fatal(err)
+			}
 
 			return ctx
 		}).
-		Assess("A valid Service is accepted", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+		Assess("A selected Service is accepted without a parameter", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
 			// get namespace
 			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
 
-			// this should PASS!
-			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(serviceClusterIPYAML, namespace))
-			if err != nil {
-				t.Fatal(err)
-			}
+			// this should PASS because of parameterNotFoundAction: Allow!
+			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(loadBalancerServiceYAML, "no-parameter", namespace, "selected"))
+			testutils.ExpectAllowed(t, err)
 
 			return ctx
 		}).
-		Assess("A Service with invalid type is rejected", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+		Assess("A selected Service is rejected with a selected parameter", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
 			// get namespace
 			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
 
-			// this should FAIL!
-			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(serviceLBYAML, namespace))
-			if err == nil {
-				t.Fatal("A Service with invalid type was accepted")
+			err := testutils.ApplyParameterFromYAML(ctx, cfg, fmt.Sprintf(testParameterSelectedYAML, namespace))
+			if err != nil {
+				t.Fatal(err)
 			}
 
+			// this should FAIL!
+			err = testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(loadBalancerServiceYAML, "selected", namespace, "selected"))
+			testutils.ExpectDenied(t, err, "service-type", serviceTypeMessage)
+
 			return ctx
 		}).
-		Assess("A Service which does not contain any type is accepted when ClusterIP is allowed", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+		Assess("A Service that is not selected is accepted", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
 			// get namespace
 			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
 
-			// this should PASS!
-			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(serviceNoTypeYAML, namespace))
-			if err != nil {
+			// this should PASS because of the object selector!
+			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(loadBalancerServiceYAML, "not-selected", namespace, "other"))
+			testutils.ExpectAllowed(t, err)
+
+			return ctx
+		}).
+		Teardown(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			if err := bindingWithSelectors().Delete(ctx, cfg); err != nil {
 				t.Fatal(err)
 			}
 
@@ -161,65 +231,75 @@ func TestWithFullParameter(t *testing.T) {
 
 }
 
-func TestWithNoCIPParameter(t *testing.T) {
+func TestUpdate(t *testing.T) {
 
-	f := features.New("Service with parameter without ClusterIP").
+	f := features.New("Updates of existing Services").
 		Setup(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
 			// get namespace
 			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
 
-			// apply parameter first
-			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(testParameterNoCIPYAML, namespace))
+			err := testutils.ApplyParameterFromYAML(ctx, cfg, fmt.Sprintf(testParameterYAML, namespace))
 			if err != nil {
 				t.Fatal(err)
 			}
 
-			// wait for the parameter to be registered properly
-			time.Sleep(10 * time.Second)
+			// create a Service that existed before the policy was enforced
+			err = testutils.ApplyK8sResourceFromYAMLBeforeEnforcement(ctx, cfg, "service-type", fmt.Sprintf(loadBalancerServiceYAML, "legacy", namespace, "legacy"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// create a compliant Service
+			err = testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(clusterIPServiceYAML, "compliant", namespace, "compliant"), testutils.WithoutDryRun)
+			if err != nil {
+				t.Fatal(err)
+			}
 
 			return ctx
 		}).
-		Assess("A Service with invalid type is rejected", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+		Assess("An existing non-compliant Service cannot be updated", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
 			// get namespace
 			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
 
 			// this should FAIL!
-			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(serviceLBYAML, namespace))
-			if err == nil {
-				t.Fatal("A Service with invalid type was accepted")
-			}
+			err := testutils.UpdateK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(loadBalancerServiceYAML, "legacy", namespace, "updated"))
+			testutils.ExpectDenied(t, err, "service-type", serviceTypeMessage)
 
 			return ctx
 		}).
-		Assess("A Service which does not contain any type is rejected when ClusterIP is allowed", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+		Assess("An existing non-compliant Service cannot be patched", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
 			// get namespace
 			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
 
+			// define patch
+			patch := k8s.Patch{PatchType: types.MergePatchType, Data: []byte(`{"metadata":{"labels":{"vap-library.com/service-type-test":"patched"}}}`)}
+
 			// this should FAIL!
-			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(serviceNoTypeYAML, namespace))
-			if err == nil {
-				t.Fatal("A Service without type was accepted")
-			}
+			err := testutils.PatchK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(loadBalancerServiceYAML, "legacy", namespace, "legacy"), patch)
+			testutils.ExpectDenied(t, err, "service-type", serviceTypeMessage)
 
 			return ctx
-		})
-
-	_ = testEnv.Test(t, f.Feature())
-
-}
+		}).
+		Assess("An existing compliant Service can be updated", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// get namespace
+			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
 
-func TestWithoutParameter(t *testing.T) {
+			// this should PASS!
+			err := testutils.UpdateK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(clusterIPServiceYAML, "compliant", namespace, "updated"))
+			testutils.ExpectAllowed(t, err)
 
-	f := features.New("Service without VAP parameter").
-		Assess("Without the VAP parameter, Services are rejected", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			return ctx
+		}).
+		Assess("An existing compliant Service cannot be patched to be non-compliant", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
 			// get namespace
 			namespace := ctx.Value(testutils.GetNamespaceKey(t)).(string)
 
-			// this should FAIL as we do not have a parameter for VAP!
-			err := testutils.ApplyK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(serviceClusterIPYAML, namespace))
-			if err == nil {
-				t.Fatal("A Service without the VAP parameter was accepted")
-			}
+			// define patch
+			patch := k8s.Patch{PatchType: types.StrategicMergePatchType, Data: []byte(`{"spec":{"type":"LoadBalancer"}}`)}
+
+			// this should FAIL!
+			err := testutils.PatchK8sResourceFromYAML(ctx, cfg, fmt.Sprintf(clusterIPServiceYAML, "compliant", namespace, "compliant"), patch)
+			testutils.ExpectDenied(t, err, "service-type", serviceTypeMessage)
 
 			return ctx
 		})
@@ -227,3 +307,7 @@ func TestWithoutParameter(t *testing.T) {
 	_ = testEnv.Test(t, f.Feature())
 
 }
+
+func TestParameterSchema(t *testing.T) {
+	testutils.RunParameterSchemaTests(t, testEnv, "crd-parameter.yaml", validParameterYAMLs)
+}
@@ -0,0 +1,307 @@
+package release
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"go.yaml.in/yaml/v3"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	sigsyaml "sigs.k8s.io/yaml"
+)
+
+const (
+	configFile     = "full-release-config.yaml"
+	releaseDir     = "release"
+	policiesDir    = "../policies"
+	policiesDomain = "vap-library.com"
+
+	policiesFile      = "policies.yaml"
+	bindingsFile      = "bindings.yaml"
+	crdsFile          = "crds.yaml"
+	kustomizationFile = "kustomization.yaml"
+)
+
+// configEntry is the entry of a policy directory in the release config
+type configEntry struct {
+	Policy  string
+	Enabled bool
+	// Bindings are the bindings by name, in the order of the config
+	Bindings []configBinding
+}
+
+// configBinding is a binding of the release config
+type configBinding struct {
+	Name              string
+	ValidationActions any
+}
+
+// loadConfig parses the release config, keeping the order of the policies and the bindings
+func loadConfig(t *testing.T) []configEntry {
+	t.Helper()
+
+	content, err := os.ReadFile(configFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := yaml.Node{}
+	if err := yaml.Unmarshal(content, &root); err != nil {
+		t.Fatalf("failed to parse %s: %s", configFile, err)
+	}
+	if len(root.Content) != 1 || root.Content[0].Kind != yaml.MappingNode {
+		t.Fatalf("%s must be a map of policy directories", configFile)
+	}
+
+	var entries []configEntry
+	policies := root.Content[0].Content
+	for i := 0; i < len(policies); i += 2 {
+		details := struct {
+			Enabled  bool                        `yaml:"enabled"`
+			Bindings []map[string]map[string]any `yaml:"bindings"`
+		}{}
+		if err := policies[i+1].Decode(&details); err != nil {
+			t.Fatalf("failed to parse the entry %s of %s: %s", policies[i].Value, configFile, err)
+		}
+
+		entry := configEntry{Policy: policies[i].Value, Enabled: details.Enabled}
+		for _, item := range details.Bindings {
+			if len(item) != 1 {
+				t.Fatalf("the bindings of %s in %s must be maps with a single binding name", entry.Policy, configFile)
+			}
+			for name, fields := range item {
+				entry.Bindings = append(entry.Bindings, configBinding{
+					Name:              name,
+					ValidationActions: fields["validationActions"],
+				})
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// TestReleaseBundle runs release.py in a copy of the repository and compares its output with the committed bundle
+func TestReleaseBundle(t *testing.T) {
+	python, err := exec.LookPath("python3")
+	if err != nil {
+		t.Skip("python3 is not installed")
+	}
+	if err := exec.Command(python, "-c", "import yaml").Run(); err != nil {
+		t.Skip("PyYAML is not installed, see requirements.txt")
+	}
+
+	// release.py reads the policies from ../policies and writes to release/, it runs in a copy so that the committed
+	// bundle stays untouched
+	root := t.TempDir()
+	if err := os.CopyFS(filepath.Join(root, "policies"), os.DirFS(policiesDir)); err != nil {
+		t.Fatal(err)
+	}
+	workDir := filepath.Join(root, "release-process")
+	if err := os.MkdirAll(filepath.Join(workDir, releaseDir), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"release.py", configFile} {
+		content, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(workDir, name), content, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cmd := exec.Command(python, "release.py", configFile)
+	cmd.Dir = workDir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("release.py failed: %s\n%s", err, output)
+	}
+
+	for _, name := range []string{policiesFile, bindingsFile, crdsFile, kustomizationFile} {
+		path := filepath.Join(releaseDir, name)
+		committed, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		generated, err := os.ReadFile(filepath.Join(workDir, releaseDir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(committed, generated) {
+			t.Errorf("%s is not up to date with %s and the policies, regenerate it with `python3 release.py %s` in release-process:\n%s",
+				path, configFile, configFile, firstDifference(committed, generated))
+		}
+	}
+}
+
+// firstDifference describes the first line that differs between the committed and the generated file
+func firstDifference(committed, generated []byte) string {
+	committedLines := strings.Split(string(committed), "\n")
+	generatedLines := strings.Split(string(generated), "\n")
+	for i := 0; i < max(len(committedLines), len(generatedLines)); i++ {
+		var c, g string
+		if i < len(committedLines) {
+			c = committedLines[i]
+		}
+		if i < len(generatedLines) {
+			g = generatedLines[i]
+		}
+		if c != g {
+			return fmt.Sprintf("line %d\n  committed: %q\n  generated: %q", i+1, c, g)
+		}
+	}
+	return "the files differ"
+}
+
+// TestReleaseConfig checks that every policy directory is released with a deny and a warn binding
+func TestReleaseConfig(t *testing.T) {
+	entries := loadConfig(t)
+
+	dirs, err := filepath.Glob(filepath.Join(policiesDir, "*", "policy.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dirs) == 0 {
+		t.Fatalf("no policies found in %s", policiesDir)
+	}
+	for _, dir := range dirs {
+		policy := filepath.Base(filepath.Dir(dir))
+		i := slices.IndexFunc(entries, func(entry configEntry) bool { return entry.Policy == policy })
+		if i < 0 {
+			t.Errorf("%s is missing from %s", policy, configFile)
+			continue
+		}
+		entry := entries[i]
+		if !entry.Enabled {
+			t.Errorf("%s is not enabled in %s", policy, configFile)
+		}
+
+		for mode, action := range map[string]admissionregistrationv1.ValidationAction{"deny": admissionregistrationv1.Deny, "warn": admissionregistrationv1.Warn} {
+			name := policy + "-" + mode + "." + policiesDomain
+			j := slices.IndexFunc(entry.Bindings, func(binding configBinding) bool { return binding.Name == name })
+			if j < 0 {
+				t.Errorf("%s has no %s binding %s in %s", policy, mode, name, configFile)
+				continue
+			}
+			actions, _ := entry.Bindings[j].ValidationActions.([]any)
+			if !slices.Contains(actions, any(string(action))) {
+				t.Errorf("the validationActions of the binding %s must include %s", name, action)
+			}
+		}
+	}
+
+	for _, entry := range entries {
+		if _, err := os.Stat(filepath.Join(policiesDir, entry.Policy, "policy.yaml")); err != nil {
+			t.Errorf("%s in %s has no policy: %s", entry.Policy, configFile, err)
+		}
+	}
+}
+
+// TestReleaseObjects checks the policies, the bindings and the CRDs of the committed bundle
+func TestReleaseObjects(t *testing.T) {
+	bundle := map[string][]byte{}
+	for _, name := range []string{policiesFile, bindingsFile, crdsFile} {
+		content, err := os.ReadFile(filepath.Join(releaseDir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		bundle[name] = content
+	}
+
+	policies := map[string]admissionregistrationv1.ValidatingAdmissionPolicy{}
+	for _, policy := range decodeAll[admissionregistrationv1.ValidatingAdmissionPolicy](t, policiesFile, bundle[policiesFile]) {
+		if policy.Kind != "ValidatingAdmissionPolicy" {
+			t.Errorf("%s contains a %s %s", policiesFile, policy.Kind, policy.Name)
+		}
+		policies[policy.Name] = policy
+	}
+
+	served := map[schema.GroupVersionKind]bool{}
+	for _, crd := range decodeAll[apiextensionsv1.CustomResourceDefinition](t, crdsFile, bundle[crdsFile]) {
+		if crd.Kind != "CustomResourceDefinition" {
+			t.Errorf("%s contains a %s %s", crdsFile, crd.Kind, crd.Name)
+		}
+		for _, version := range crd.Spec.Versions {
+			if version.Served {
+				served[schema.GroupVersionKind{Group: crd.Spec.Group, Version: version.Name, Kind: crd.Spec.Names.Kind}] = true
+			}
+		}
+	}
+
+	for name, policy := range policies {
+		if !strings.HasSuffix(name, "."+policiesDomain) {
+			t.Errorf("the name of the policy %s must end with .%s", name, policiesDomain)
+		}
+		if policy.Spec.ParamKind == nil {
+			continue
+		}
+		gv, err := schema.ParseGroupVersion(policy.Spec.ParamKind.APIVersion)
+		if err != nil {
+			t.Errorf("the paramKind of the policy %s is invalid: %s", name, err)
+			continue
+		}
+		if !served[gv.WithKind(policy.Spec.ParamKind.Kind)] {
+			t.Errorf("the paramKind %s %s of the policy %s has no CRD in %s", policy.Spec.ParamKind.APIVersion, policy.Spec.ParamKind.Kind, name, crdsFile)
+		}
+	}
+
+	for _, binding := range decodeAll[admissionregistrationv1.ValidatingAdmissionPolicyBinding](t, bindingsFile, bundle[bindingsFile]) {
+		policy, ok := policies[binding.Spec.PolicyName]
+		if !ok {
+			t.Errorf("the binding %s refers to the policy %s that is not in %s", binding.Name, binding.Spec.PolicyName, policiesFile)
+			continue
+		}
+		if len(binding.Spec.ValidationActions) == 0 {
+			t.Errorf("the binding %s has no validationActions", binding.Name)
+		}
+		if slices.Contains(binding.Spec.ValidationActions, admissionregistrationv1.Deny) && slices.Contains(binding.Spec.ValidationActions, admissionregistrationv1.Warn) {
+			t.Errorf("the binding %s must not both deny and warn", binding.Name)
+		}
+
+		switch {
+		case policy.Spec.ParamKind == nil && binding.Spec.ParamRef != nil:
+			t.Errorf("the binding %s has a paramRef but the policy %s has no paramKind", binding.Name, policy.Name)
+		case policy.Spec.ParamKind != nil && binding.Spec.ParamRef == nil:
+			t.Errorf("the binding %s has no paramRef but the policy %s has a paramKind", binding.Name, policy.Name)
+		case binding.Spec.ParamRef != nil && binding.Spec.ParamRef.Name != policy.Name:
+			t.Errorf("the paramRef of the binding %s must be named %s, got %q", binding.Name, policy.Name, binding.Spec.ParamRef.Name)
+		}
+	}
+}
+
+// decodeAll strictly decodes the documents of a bundle file
+func decodeAll[T any](t *testing.T, name string, content []byte) []T {
+	t.Helper()
+
+	var objects []T
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(content)))
+	for i := 0; ; i++ {
+		document, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("failed to read %s: %s", name, err)
+		}
+		if len(bytes.TrimSpace(bytes.TrimPrefix(bytes.TrimSpace(document), []byte("---")))) == 0 {
+			continue
+		}
+		var object T
+		if err := sigsyaml.UnmarshalStrict(document, &object); err != nil {
+			t.Errorf("document %d of %s is malformed: %s", i, name, err)
+			continue
+		}
+		objects = append(objects, object)
+	}
+	return objects
+}
@@ -0,0 +1,103 @@
+package testutils
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	policyNameSuffix = ".vap-library.com"
+
+	// NoParamsMessage is the denial message of a binding that does not find its mandatory parameter
+	NoParamsMessage = "no params found for policy binding"
+)
+
+// denialRegexp matches the message of a request that was denied by a ValidatingAdmissionPolicy
+var denialRegexp = regexp.MustCompile(`ValidatingAdmissionPolicy '([^']+)'(?: with binding '([^']+)')? denied request: (?s)(.*)$`)
+
+// Denial is a request rejection by a ValidatingAdmissionPolicy
+type Denial struct {
+	// Policy is the name of the policy that denied the request
+	Policy string
+	// Binding is the name of the binding, empty when the policy itself could not be configured
+	Binding string
+	// Reason is the reason of the status error
+	Reason metav1.StatusReason
+	// Message is the message of the policy without the policy and binding prefix
+	Message string
+}
+
+// ParseDenial parses the error returned by the API server. It returns an error if err is not a rejection by a
+// ValidatingAdmissionPolicy.
+func ParseDenial(err error) (*Denial, error) {
+	if err == nil {
+		return nil, errors.New("the request was not rejected")
+	}
+
+	var statusErr *apierrors.StatusError
+	if !errors.As(err, &statusErr) {
+		return nil, fmt.Errorf("the request was rejected with a non API error: %w", err)
+	}
+
+	matches := denialRegexp.FindStringSubmatch(statusErr.ErrStatus.Message)
+	if matches == nil {
+		return nil, fmt.Errorf("the request was not rejected by a ValidatingAdmissionPolicy: %w", err)
+	}
+
+	return &Denial{
+		Policy:  matches[1],
+		Binding: matches[2],
+		Reason:  statusErr.ErrStatus.Reason,
+		Message: matches[3],
+	}, nil
+}
+
+// ExpectDenied fails the test unless err is a rejection by the given vap-library policy (e.g. "service-type"), through
+// one of its vap-library bindings, with the Invalid reason and a message that contains messageSubstring.
+func ExpectDenied(t *testing.T, err error, policyName string, messageSubstring string) *Denial {
+	t.Helper()
+	return ExpectDeniedWithReason(t, err, policyName, metav1.StatusReasonInvalid, messageSubstring)
+}
+
+// ExpectDeniedWithReason is like ExpectDenied but with a custom reason
+func ExpectDeniedWithReason(t *testing.T, err error, policyName string, reason metav1.StatusReason, messageSubstring string) *Denial {
+	t.Helper()
+	testReport.recordRequest(t, ResultDenied, err)
+
+	denial, parseErr := ParseDenial(err)
+	if parseErr != nil {
+		t.Fatal(parseErr)
+	}
+
+	shortName := strings.TrimSuffix(policyName, policyNameSuffix)
+	if denial.Policy != shortName+policyNameSuffix {
+		t.Fatalf("expected the request to be denied by policy %s but it was denied by %s: %s", shortName+policyNameSuffix, denial.Policy, err)
+	}
+	if !strings.HasPrefix(denial.Binding, shortName+"-") || !strings.HasSuffix(denial.Binding, policyNameSuffix) {
+		t.Fatalf("expected the request to be denied through a %s-*%s binding but the binding was %q: %s", shortName, policyNameSuffix, denial.Binding, err)
+	}
+	if denial.Reason != reason {
+		t.Fatalf("expected the request to be denied with reason %s but the reason was %s: %s", reason, denial.Reason, err)
+	}
+	if !strings.Contains(denial.Message, messageSubstring) {
+		t.Fatalf("expected the denial message to contain %q: %s", messageSubstring, err)
+	}
+
+	return denial
+}
+
+// ExpectAllowed fails the test if the request was rejected
+func ExpectAllowed(t *testing.T, err error) {
+	t.Helper()
+	testReport.recordRequest(t, ResultAllowed, err)
+
+	if err != nil {
+		t.Fatalf("expected the request to be allowed but it was rejected: %s", err)
+	}
+}
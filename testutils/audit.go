@@ -0,0 +1,330 @@
+package testutils
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	auditv1 "k8s.io/apiserver/pkg/apis/audit/v1"
+	"sigs.k8s.io/e2e-framework/klient/wait"
+	sigsyaml "sigs.k8s.io/yaml"
+)
+
+const (
+	// AuditLogEnvVar enables the audit log of the Kind cluster when it is set to true
+	AuditLogEnvVar = "VAPLIB_AUDIT_LOG"
+
+	// ValidationFailureAnnotation is the audit annotation that bindings with the Audit validation action publish
+	ValidationFailureAnnotation = "validation.policy.admission.k8s.io/validation_failure"
+
+	auditLogFileName    = "kube-apiserver-audit.log"
+	auditLogNodeDir     = "/var/log/kubernetes"
+	auditPolicyNodeDir  = "/etc/kubernetes/policies"
+	auditPolicyFileName = "audit-policy.yaml"
+)
+
+type auditLogCtxKey struct{}
+
+// auditLog is the audit log of a Kind cluster
+type auditLog struct {
+	// hostDir is the directory on the host that is mounted to the log directory of the node
+	hostDir string
+	// nodeName is the name of the control plane node container
+	nodeName string
+}
+
+// ValidationFailure is an item of the ValidationFailureAnnotation
+type ValidationFailure struct {
+	Message           string   `json:"message"`
+	Policy            string   `json:"policy"`
+	Binding           string   `json:"binding"`
+	ExpressionIndex   int      `json:"expressionIndex"`
+	ValidationActions []string `json:"validationActions"`
+}
+
+// AuditEvents returns the audit events of the requests for objects in the given namespace. With the in-process
+// backend the events are recorded by the evaluator, on Kind they are read from the audit log (see AuditLogEnvVar).
+func AuditEvents(ctx context.Context, namespace string) ([]auditv1.Event, error) {
+	var events []auditv1.Event
+	if evaluator := PolicyEvaluatorFromContext(ctx); evaluator != nil {
+		events = evaluator.AuditEvents()
+	} else if log, ok := ctx.Value(auditLogCtxKey{}).(*auditLog); ok {
+		var err error
+		if events, err = log.read(ctx); err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, fmt.Errorf("the audit log is not enabled, set %s=true", AuditLogEnvVar)
+	}
+
+	var filtered []auditv1.Event
+	for _, event := range events {
+		if event.ObjectRef != nil && event.ObjectRef.Namespace == namespace {
+			filtered = append(filtered, event)
+		}
+	}
+	return filtered, nil
+}
+
+// ValidationFailures returns the validation failures that the Audit bindings published on the event
+func ValidationFailures(event auditv1.Event) ([]ValidationFailure, error) {
+	value, ok := event.Annotations[ValidationFailureAnnotation]
+	if !ok {
+		return nil, nil
+	}
+
+	var failures []ValidationFailure
+	if err := json.Unmarshal([]byte(value), &failures); err != nil {
+		return nil, fmt.Errorf("failed to parse the %s annotation: %w", ValidationFailureAnnotation, err)
+	}
+	return failures, nil
+}
+
+// ExpectAudited fails the test unless a request in the namespace was audited as a validation failure of the given
+// vap-library policy with a message that contains messageSubstring. It waits for the audit log to be written.
+func ExpectAudited(ctx context.Context, t *testing.T, namespace string, policyName string, messageSubstring string) {
+	t.Helper()
+
+	fullName := strings.TrimSuffix(policyName, policyNameSuffix) + policyNameSuffix
+	expectAuditEvent(ctx, t, namespace, fmt.Sprintf("a validation failure of %s containing %q", fullName, messageSubstring), func(event auditv1.Event) bool {
+		failures, err := ValidationFailures(event)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, failure := range failures {
+			if failure.Policy == fullName && strings.Contains(failure.Message, messageSubstring) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// ExpectAuditAnnotation fails the test unless a request in the namespace was audited with an auditAnnotation of the
+// given vap-library policy with a value that contains valueSubstring. The key is the key of the auditAnnotation in the
+// policy, the annotation of the event is "<policy name>/<key>".
+func ExpectAuditAnnotation(ctx context.Context, t *testing.T, namespace string, policyName string, key string, valueSubstring string) {
+	t.Helper()
+
+	annotation := strings.TrimSuffix(policyName, policyNameSuffix) + policyNameSuffix + "/" + key
+	expectAuditEvent(ctx, t, namespace, fmt.Sprintf("the audit annotation %s containing %q", annotation, valueSubstring), func(event auditv1.Event) bool {
+		value, ok := event.Annotations[annotation]
+		return ok && strings.Contains(value, valueSubstring)
+	})
+}
+
+// SkipWithoutAuditLog skips the test when there are no audit events to check
+func SkipWithoutAuditLog(ctx context.Context, t *testing.T) {
+	t.Helper()
+
+	if PolicyEvaluatorFromContext(ctx) != nil {
+		return
+	}
+	if _, ok := ctx.Value(auditLogCtxKey{}).(*auditLog); !ok {
+		t.Skipf("the audit log is not enabled, set %s=true", AuditLogEnvVar)
+	}
+}
+
+// expectAuditEvent polls the audit events of the namespace until one matches
+func expectAuditEvent(ctx context.Context, t *testing.T, namespace string, description string, match func(event auditv1.Event) bool) {
+	t.Helper()
+	testReport.addCheck(t, CheckReport{Expected: ResultAudited, Actual: ResultNotAudited, Message: description})
+
+	timeout, err := ReadinessTimeout()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var lastErr error
+	var events []auditv1.Event
+	err = wait.For(
+		func(ctx context.Context) (bool, error) {
+			events, lastErr = AuditEvents(ctx, namespace)
+			if lastErr != nil {
+				return false, nil
+			}
+			for _, event := range events {
+				if match(event) {
+					return true, nil
+				}
+			}
+			return false, nil
+		},
+		wait.WithContext(ctx), wait.WithTimeout(timeout), wait.WithInterval(readinessPollInterval), wait.WithImmediate(),
+	)
+	if err != nil {
+		if lastErr != nil {
+			t.Fatalf("expected %s in the audit log of namespace %s: %s", description, namespace, lastErr)
+		}
+		t.Fatalf("expected %s in the audit log of namespace %s, found %d events without it", description, namespace, len(events))
+	}
+	testReport.setActual(t, ResultAudited)
+}
+
+// useAuditLog returns true if the audit log of the Kind cluster is enabled
+func useAuditLog() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv(AuditLogEnvVar))
+	return enabled
+}
+
+// writeAuditKindConfig writes a Kind config to hostDir that extends baseConfig with an audit policy and an audit log
+// on the control plane node. The log directory of the node is mounted from hostDir. The other settings and nodes of
+// baseConfig are kept.
+func writeAuditKindConfig(baseConfig string, auditPolicy string, hostDir string) (string, error) {
+	content, err := os.ReadFile(baseConfig)
+	if err != nil {
+		return "", err
+	}
+	config := map[string]interface{}{}
+	if err := sigsyaml.Unmarshal(content, &config); err != nil {
+		return "", fmt.Errorf("failed to parse %s: %w", baseConfig, err)
+	}
+
+	auditPolicy, err = filepath.Abs(auditPolicy)
+	if err != nil {
+		return "", err
+	}
+	hostDir, err = filepath.Abs(hostDir)
+	if err != nil {
+		return "", err
+	}
+
+	// the patches only apply to the kubeadm API version that Kind uses for the node image
+	extraVolumes := []interface{}{
+		map[string]interface{}{"name": "audit-policies", "hostPath": auditPolicyNodeDir, "mountPath": auditPolicyNodeDir, "readOnly": true, "pathType": "DirectoryOrCreate"},
+		map[string]interface{}{"name": "audit-logs", "hostPath": auditLogNodeDir, "mountPath": auditLogNodeDir, "readOnly": false, "pathType": "DirectoryOrCreate"},
+	}
+	logPath := auditLogNodeDir + "/" + auditLogFileName
+	policyPath := auditPolicyNodeDir + "/" + auditPolicyFileName
+	patches := []map[string]interface{}{
+		{
+			"apiVersion": "kubeadm.k8s.io/v1beta3",
+			"kind":       "ClusterConfiguration",
+			"apiServer": map[string]interface{}{
+				"extraArgs":    map[string]interface{}{"audit-log-path": logPath, "audit-policy-file": policyPath},
+				"extraVolumes": extraVolumes,
+			},
+		},
+		{
+			"apiVersion": "kubeadm.k8s.io/v1beta4",
+			"kind":       "ClusterConfiguration",
+			"apiServer": map[string]interface{}{
+				"extraArgs": []interface{}{
+					map[string]interface{}{"name": "audit-log-path", "value": logPath},
+					map[string]interface{}{"name": "audit-policy-file", "value": policyPath},
+				},
+				"extraVolumes": extraVolumes,
+			},
+		},
+	}
+	var kubeadmConfigPatches []interface{}
+	for _, patch := range patches {
+		raw, err := sigsyaml.Marshal(patch)
+		if err != nil {
+			return "", err
+		}
+		kubeadmConfigPatches = append(kubeadmConfigPatches, string(raw))
+	}
+
+	// the audit log is added to the first control plane node, a node without a role is a control plane node
+	nodes, _ := config["nodes"].([]interface{})
+	var controlPlane map[string]interface{}
+	for _, n := range nodes {
+		if node, ok := n.(map[string]interface{}); ok && (node["role"] == nil || node["role"] == "control-plane") {
+			controlPlane = node
+			break
+		}
+	}
+	if controlPlane == nil {
+		controlPlane = map[string]interface{}{"role": "control-plane"}
+		nodes = append([]interface{}{controlPlane}, nodes...)
+	}
+	existingPatches, _ := controlPlane["kubeadmConfigPatches"].([]interface{})
+	controlPlane["kubeadmConfigPatches"] = append(existingPatches, kubeadmConfigPatches...)
+	existingMounts, _ := controlPlane["extraMounts"].([]interface{})
+	controlPlane["extraMounts"] = append(existingMounts,
+		map[string]interface{}{"hostPath": auditPolicy, "containerPath": policyPath, "readOnly": true},
+		map[string]interface{}{"hostPath": hostDir, "containerPath": auditLogNodeDir},
+	)
+	config["nodes"] = nodes
+
+	raw, err := sigsyaml.Marshal(config)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(hostDir, "kind-config.yaml")
+	return path, os.WriteFile(path, raw, 0o600)
+}
+
+// read reads all the events of the audit log. The API server creates the log file with 0600 permissions, so it is
+// read through the node container if the mounted file cannot be read on the host.
+func (l *auditLog) read(ctx context.Context) ([]auditv1.Event, error) {
+	content, err := os.ReadFile(filepath.Join(l.hostDir, auditLogFileName))
+	if errors.Is(err, os.ErrPermission) {
+		content, err = exec.CommandContext(ctx, "docker", "exec", l.nodeName, "cat", auditLogNodeDir+"/"+auditLogFileName).Output()
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		// nothing was audited yet
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read the audit log: %w", err)
+	}
+
+	var events []auditv1.Event
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		event := auditv1.Event{}
+		if err := json.Unmarshal(line, &event); err != nil {
+			// the last line may be partially written
+			continue
+		}
+		events = append(events, event)
+	}
+	return events, scanner.Err()
+}
+
+// auditEvent returns an audit event like the one the API server writes for a request with the Metadata level
+func auditEvent(verb string, objectRef *auditv1.ObjectReference, annotations map[string]string, err error) auditv1.Event {
+	now := metav1.NewMicroTime(time.Now())
+
+	status := &metav1.Status{Status: metav1.StatusSuccess, Code: http.StatusOK}
+	if verb == "create" {
+		status.Code = http.StatusCreated
+	}
+	var statusErr *apierrors.StatusError
+	if errors.As(err, &statusErr) {
+		errStatus := statusErr.Status()
+		status = &errStatus
+	} else if err != nil {
+		status = &metav1.Status{Status: metav1.StatusFailure, Code: http.StatusInternalServerError, Message: err.Error()}
+	}
+
+	return auditv1.Event{
+		Level:                    auditv1.LevelMetadata,
+		Stage:                    auditv1.StageResponseComplete,
+		Verb:                     verb,
+		ObjectRef:                objectRef,
+		ResponseStatus:           status,
+		Annotations:              annotations,
+		RequestReceivedTimestamp: now,
+		StageTimestamp:           now,
+	}
+}
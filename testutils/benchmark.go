@@ -0,0 +1,562 @@
+package testutils
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"text/tabwriter"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/prometheus/common/model"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/client-go/discovery"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/e2e-framework/klient/decoder"
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
+	"sigs.k8s.io/e2e-framework/pkg/env"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+)
+
+const (
+	// BenchmarkPoliciesEnvVar overrides the policies of the admission benchmark, e.g. pss-capabilities,pss-seccomp
+	BenchmarkPoliciesEnvVar = "VAPLIB_BENCHMARK_POLICIES"
+	// BenchmarkContainersEnvVar overrides the numbers of containers of the benchmarked objects, e.g. 1,10,50
+	BenchmarkContainersEnvVar = "VAPLIB_BENCHMARK_CONTAINERS"
+)
+
+// CheckDurationMetric is the histogram of the API server with the duration of the policy checks. The API server only
+// observes the checks that fail (deny, warn or audit) or cannot be evaluated, not the checks of admitted objects.
+const CheckDurationMetric = "apiserver_validating_admission_policy_check_duration_seconds"
+
+// AdmissionDurationMetric is the histogram of the API server with the duration of the admission plugins, the
+// ValidatingAdmissionPolicy plugin observes every request. The in-process backend has no admission chain to observe.
+const AdmissionDurationMetric = "apiserver_admission_controller_admission_duration_seconds"
+
+// maxBenchmarkContainers is the largest number of containers of a benchmarked object
+const maxBenchmarkContainers = 50
+
+// AdmissionBenchmark measures the latency that ValidatingAdmissionPolicies add to the admission of workloads. Every
+// request is a server-side dry-run create, sent once to a namespace where none of the policies is enforced and once to
+// a namespace where all of them are enforced with their deny bindings.
+type AdmissionBenchmark struct {
+	// Policies are the enforced policies, BenchmarkPoliciesEnvVar overrides them
+	Policies []string
+	// Parameters are the parameters of the policies by policy name, YAML with a %s placeholder for the namespace
+	Parameters map[string]string
+	// Kinds are the benchmarked kinds of WorkloadKinds, Pod and Deployment by default
+	Kinds []string
+	// Containers are the numbers of containers of the benchmarked objects (1 to 50), 1, 10 and 50 by default, and
+	// BenchmarkContainersEnvVar overrides them
+	Containers []int
+	// PodSpec returns the PodSpec of the benchmarked objects, a PodSpec that all the PSS and resource policies admit
+	// by default
+	PodSpec func(containers int) corev1.PodSpec
+
+	ctx        context.Context
+	cfg        *envconf.Config
+	namespaces map[bool]string
+	results    map[string]*LatencyResult
+	names      []string
+}
+
+// LatencyResult is the measured admission latency of an object without and with the policies
+type LatencyResult struct {
+	// Kind and Containers describe the benchmarked object
+	Kind       string
+	Containers int
+	// Baseline is measured without policies, Policies with the policies enforced
+	Baseline Measurement
+	Policies Measurement
+}
+
+// Measurement is the latency of a number of requests and the metrics of the API server while they were sent
+type Measurement struct {
+	Requests      int
+	P50, P95, P99 time.Duration
+	// Admission is the AdmissionDurationMetric of the ValidatingAdmissionPolicy plugin for the created objects
+	Admission Histogram
+	// Checks are the CheckDurationMetric observations by policy name
+	Checks map[string]Histogram
+}
+
+// Histogram is the change of a histogram of the API server metrics
+type Histogram struct {
+	Count uint64
+	Sum   time.Duration
+	// Buckets are the cumulative counts by upper bound in seconds
+	Buckets map[float64]uint64
+}
+
+// Mean returns the mean of the observations
+func (h Histogram) Mean() time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	return h.Sum / time.Duration(h.Count)
+}
+
+// UpperBound returns the upper bound of the smallest bucket that holds the fraction of the observations, the
+// histograms of the API server are too coarse for percentiles
+func (h Histogram) UpperBound(fraction float64) time.Duration {
+	bounds := make([]float64, 0, len(h.Buckets))
+	for bound := range h.Buckets {
+		bounds = append(bounds, bound)
+	}
+	sort.Float64s(bounds)
+	for _, bound := range bounds {
+		if float64(h.Buckets[bound]) >= fraction*float64(h.Count) {
+			return time.Duration(bound * float64(time.Second))
+		}
+	}
+	return time.Duration(math.MaxInt64)
+}
+
+// add adds the observations of another histogram, or subtracts them if sign is -1
+func (h *Histogram) add(other Histogram, sign int64) {
+	if h.Buckets == nil {
+		h.Buckets = map[float64]uint64{}
+	}
+	h.Count += uint64(sign) * other.Count
+	h.Sum += time.Duration(sign) * other.Sum
+	for bound, count := range other.Buckets {
+		h.Buckets[bound] += uint64(sign) * count
+	}
+}
+
+// CreateBenchmarkEnv creates the test environment of an admission benchmark package: the policies, their parameter
+// CRDs and their bindings are installed, nothing else. The policies are BenchmarkPoliciesEnvVar if it is set. Unless
+// the benchmarks run (go test -bench), the environment is empty so that go test ./... does not create a cluster.
+func CreateBenchmarkEnv(bench *AdmissionBenchmark) (env.Environment, error) {
+	if !benchmarksRequested(os.Args[1:]) {
+		return env.New(), nil
+	}
+	if err := bench.configure(); err != nil {
+		return nil, err
+	}
+
+	policyDirs := map[string]string{}
+	policyNameForBindingGeneration := map[string]bool{}
+	for _, name := range bench.Policies {
+		dir := "../../policies/" + name + "/"
+		if _, err := os.Stat(dir + "policy.yaml"); err != nil {
+			return nil, fmt.Errorf("unknown policy %s: %w", name, err)
+		}
+		_, err := os.Stat(dir + "crd-parameter.yaml")
+		policyDirs[dir] = "*.yaml"
+		policyNameForBindingGeneration[name] = err == nil
+	}
+
+	testEnv, err := CreateTestEnv("", false, nil, policyDirs, policyNameForBindingGeneration)
+	if err != nil {
+		return nil, err
+	}
+	testEnv.Setup(bench.setup)
+	testEnv.Finish(bench.finish)
+	return testEnv, nil
+}
+
+// benchmarksRequested returns true if the arguments of the test binary select benchmarks
+func benchmarksRequested(args []string) bool {
+	for i, arg := range args {
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if name != "test.bench" {
+			continue
+		}
+		if !hasValue && i+1 < len(args) {
+			value = args[i+1]
+		}
+		return value != ""
+	}
+	return false
+}
+
+// configure applies the defaults and the environment variables
+func (bench *AdmissionBenchmark) configure() error {
+	if policies := os.Getenv(BenchmarkPoliciesEnvVar); policies != "" {
+		bench.Policies = strings.Split(policies, ",")
+	}
+	if len(bench.Policies) == 0 {
+		return fmt.Errorf("no policies to benchmark, set %s", BenchmarkPoliciesEnvVar)
+	}
+	if len(bench.Kinds) == 0 {
+		bench.Kinds = []string{"Pod", "Deployment"}
+	}
+	for _, kind := range bench.Kinds {
+		if !slices.Contains(WorkloadKinds, kind) {
+			return fmt.Errorf("unknown workload kind %s, must be one of %v", kind, WorkloadKinds)
+		}
+	}
+
+	if containers := os.Getenv(BenchmarkContainersEnvVar); containers != "" {
+		bench.Containers = nil
+		for _, s := range strings.Split(containers, ",") {
+			n, err := strconv.Atoi(strings.TrimSpace(s))
+			if err != nil {
+				return fmt.Errorf("invalid %s: %w", BenchmarkContainersEnvVar, err)
+			}
+			bench.Containers = append(bench.Containers, n)
+		}
+	}
+	if len(bench.Containers) == 0 {
+		bench.Containers = []int{1, 10, maxBenchmarkContainers}
+	}
+	for _, n := range bench.Containers {
+		if n < 1 || n > maxBenchmarkContainers {
+			return fmt.Errorf("the number of containers must be between 1 and %d, got %d", maxBenchmarkContainers, n)
+		}
+	}
+
+	if bench.PodSpec == nil {
+		bench.PodSpec = benchmarkPodSpec
+	}
+	bench.results = map[string]*LatencyResult{}
+	return nil
+}
+
+// setup creates the namespace without policies and the namespace with the policies and their parameters
+func (bench *AdmissionBenchmark) setup(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+	runID := envconf.RandomName("vap-benchmark", 20)
+	bench.namespaces = map[bool]string{false: runID + "-baseline", true: runID + "-policies"}
+
+	labels := map[string]string{}
+	for _, name := range bench.Policies {
+		labels["vap-library.com/"+name] = ModeDeny
+	}
+	for _, enforced := range []bool{false, true} {
+		if err := createNamespace(ctx, cfg, bench.namespaces[enforced], nil); err != nil {
+			return ctx, err
+		}
+	}
+	// the labels are added after the creation to wait until the admission plugin sees them
+	if err := AddNamespaceLabels(ctx, cfg, bench.namespaces[true], labels); err != nil {
+		return ctx, err
+	}
+
+	for _, name := range bench.Policies {
+		yaml, ok := bench.Parameters[name]
+		if !ok {
+			continue
+		}
+		if err := ApplyParameterFromYAML(ctx, cfg, fmt.Sprintf(yaml, bench.namespaces[true])); err != nil {
+			return ctx, fmt.Errorf("failed to apply the parameter of %s: %w", name, err)
+		}
+	}
+
+	bench.ctx, bench.cfg = ctx, cfg
+	return ctx, nil
+}
+
+// finish prints the results and deletes the namespaces
+func (bench *AdmissionBenchmark) finish(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+	if len(bench.names) > 0 {
+		bench.WriteResults(os.Stdout)
+	}
+	for _, namespace := range bench.namespaces {
+		if err := deleteNamespace(ctx, cfg, namespace); err != nil {
+			return ctx, err
+		}
+	}
+	return ctx, nil
+}
+
+// Run runs a sub-benchmark for every kind, number of containers and namespace. Every iteration is one request, so
+// -benchtime=500x sends 500 requests. The percentiles of the latency are reported as p50-ms, p95-ms and p99-ms and
+// printed with the metrics of the API server when the test environment finishes.
+func (bench *AdmissionBenchmark) Run(b *testing.B) {
+	if bench.ctx == nil {
+		b.Fatal("the admission benchmark is not set up, create the test environment with CreateBenchmarkEnv")
+	}
+
+	for _, kind := range bench.Kinds {
+		for _, containers := range bench.Containers {
+			name := fmt.Sprintf("%s/containers=%d", kind, containers)
+			result, ok := bench.results[name]
+			if !ok {
+				result = &LatencyResult{Kind: kind, Containers: containers}
+				bench.results[name] = result
+				bench.names = append(bench.names, name)
+			}
+
+			b.Run(name+"/policies=none", func(b *testing.B) {
+				result.Baseline = bench.measure(b, kind, containers, false)
+			})
+			b.Run(name+"/policies=enforced", func(b *testing.B) {
+				result.Policies = bench.measure(b, kind, containers, true)
+			})
+		}
+	}
+}
+
+// measure sends b.N dry-run requests and returns their latency and the metrics of the API server
+func (bench *AdmissionBenchmark) measure(b *testing.B, kind string, containers int, enforced bool) Measurement {
+	ctx, cfg := bench.ctx, bench.cfg
+	namespace := bench.namespaces[enforced]
+
+	obj := benchmarkObject(kind, namespace, bench.PodSpec(containers))
+	create, err := dryRunCreateFunc(ctx, cfg)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	// the first request warms up the caches and must be admitted, a denied request would not measure all policies
+	if err := create(obj.DeepCopyObject().(k8s.Object)); err != nil {
+		b.Fatalf("the benchmarked %s with %d containers is not admitted: %s", kind, containers, err)
+	}
+
+	before, err := scrapeMetrics(ctx, cfg)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	latencies := make([]time.Duration, 0, b.N)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		request := obj.DeepCopyObject().(k8s.Object)
+		start := time.Now()
+		err := create(request)
+		latencies = append(latencies, time.Since(start))
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.StopTimer()
+
+	after, err := scrapeMetrics(ctx, cfg)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	m := latencyPercentiles(latencies)
+	m.Admission = after.admission
+	m.Admission.add(before.admission, -1)
+	m.Checks = map[string]Histogram{}
+	for policy, h := range after.checks {
+		h.add(before.checks[policy], -1)
+		if h.Count > 0 {
+			m.Checks[policy] = h
+		}
+	}
+
+	b.ReportMetric(milliseconds(m.P50), "p50-ms")
+	b.ReportMetric(milliseconds(m.P95), "p95-ms")
+	b.ReportMetric(milliseconds(m.P99), "p99-ms")
+	return m
+}
+
+// WriteResults writes a table of the latency without and with the policies, and of the metrics of the API server
+func (bench *AdmissionBenchmark) WriteResults(out io.Writer) {
+	fmt.Fprintf(out, "Admission latency (ms) of %s\n", strings.Join(bench.Policies, ", "))
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(w, "kind\tcontainers\trequests\tp50\tp95\tp99\tp50 (policies)\tp95 (policies)\tp99 (policies)\tp50 added\tp95 added\tp99 added\t\n")
+	for _, name := range bench.names {
+		r := bench.results[name]
+		fmt.Fprintf(w, "%s\t%d\t%d\t%.3f\t%.3f\t%.3f\t%.3f\t%.3f\t%.3f\t%+.3f\t%+.3f\t%+.3f\t\n", r.Kind, r.Containers, r.Policies.Requests,
+			milliseconds(r.Baseline.P50), milliseconds(r.Baseline.P95), milliseconds(r.Baseline.P99),
+			milliseconds(r.Policies.P50), milliseconds(r.Policies.P95), milliseconds(r.Policies.P99),
+			milliseconds(r.Policies.P50-r.Baseline.P50), milliseconds(r.Policies.P95-r.Baseline.P95), milliseconds(r.Policies.P99-r.Baseline.P99))
+	}
+	w.Flush()
+
+	fmt.Fprintf(out, "\n%s{name=\"ValidatingAdmissionPolicy\"} (ms, not available in-process)\n", AdmissionDurationMetric)
+	w = tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(w, "kind\tcontainers\tcount\tmean\tp99 below\tcount (policies)\tmean (policies)\tp99 below (policies)\t\n")
+	for _, name := range bench.names {
+		r := bench.results[name]
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t\n", r.Kind, r.Containers, formatHistogram(r.Baseline.Admission), formatHistogram(r.Policies.Admission))
+	}
+	w.Flush()
+
+	fmt.Fprintf(out, "\n%s (ms, only failed checks are observed)\n", CheckDurationMetric)
+	w = tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(w, "kind\tcontainers\tpolicy\tcount\tmean\tp99 below\t\n")
+	for _, name := range bench.names {
+		r := bench.results[name]
+		policies := slices.Sorted(maps.Keys(r.Policies.Checks))
+		for _, policy := range policies {
+			fmt.Fprintf(w, "%s\t%d\t%s\t%s\t\n", r.Kind, r.Containers, policy, formatHistogram(r.Policies.Checks[policy]))
+		}
+		if len(policies) == 0 {
+			fmt.Fprintf(w, "%s\t%d\t-\t0\t-\t-\t\n", r.Kind, r.Containers)
+		}
+	}
+	w.Flush()
+}
+
+// formatHistogram returns the count, the mean and the upper bound of the 99th percentile as table cells
+func formatHistogram(h Histogram) string {
+	if h.Count == 0 {
+		return "0\t-\t-"
+	}
+	return fmt.Sprintf("%d\t%.3f\t%g", h.Count, milliseconds(h.Mean()), milliseconds(h.UpperBound(0.99)))
+}
+
+// benchmarkObject returns the object of the kind from Workloads
+func benchmarkObject(kind string, namespace string, podSpec corev1.PodSpec) k8s.Object {
+	for i, obj := range Workloads("benchmark", namespace, podSpec) {
+		if WorkloadKinds[i] == kind {
+			return obj
+		}
+	}
+	return nil
+}
+
+// benchmarkPodSpec returns a PodSpec with the containers that the PSS and resource policies admit
+func benchmarkPodSpec(containers int) corev1.PodSpec {
+	podSpec := corev1.PodSpec{
+		SecurityContext: &corev1.PodSecurityContext{
+			RunAsNonRoot:   ptr.To(true),
+			RunAsUser:      ptr.To(int64(10001)),
+			SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+		},
+		Volumes: []corev1.Volume{{Name: "work", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}}},
+	}
+	resources := corev1.ResourceList{
+		corev1.ResourceCPU:              resource.MustParse("100m"),
+		corev1.ResourceMemory:           resource.MustParse("64Mi"),
+		corev1.ResourceEphemeralStorage: resource.MustParse("100Mi"),
+	}
+	for i := range containers {
+		podSpec.Containers = append(podSpec.Containers, corev1.Container{
+			Name:  fmt.Sprintf("container-%d", i),
+			Image: "public.ecr.aws/docker/library/busybox:1.36",
+			SecurityContext: &corev1.SecurityContext{
+				AllowPrivilegeEscalation: ptr.To(false),
+				Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+				RunAsNonRoot:             ptr.To(true),
+				RunAsUser:                ptr.To(int64(10001)),
+				SeccompProfile:           &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+			},
+			Resources:    corev1.ResourceRequirements{Requests: resources, Limits: resources},
+			VolumeMounts: []corev1.VolumeMount{{Name: "work", MountPath: "/work"}},
+		})
+	}
+	return podSpec
+}
+
+// dryRunCreateFunc returns a function that creates an object with server-side dry-run on the cluster or in the
+// in-process evaluator
+func dryRunCreateFunc(ctx context.Context, cfg *envconf.Config) (func(k8s.Object) error, error) {
+	if evaluator := PolicyEvaluatorFromContext(ctx); evaluator != nil {
+		return func(obj k8s.Object) error {
+			return evaluator.Apply(ctx, obj, WithDryRun)
+		}, nil
+	}
+
+	r, err := resources.New(cfg.Client().RESTConfig())
+	if err != nil {
+		return nil, err
+	}
+	handler := decoder.CreateHandler(r, WithDryRun)
+	return func(obj k8s.Object) error {
+		return handler(ctx, obj)
+	}, nil
+}
+
+// benchmarkMetrics are the histograms of the API server that the benchmark reports
+type benchmarkMetrics struct {
+	admission Histogram
+	checks    map[string]Histogram
+}
+
+// scrapeMetrics returns the histograms of the API server, or of the in-process evaluator which records the
+// CheckDurationMetric in the same registry
+func scrapeMetrics(ctx context.Context, cfg *envconf.Config) (benchmarkMetrics, error) {
+	var families map[string]*dto.MetricFamily
+	if PolicyEvaluatorFromContext(ctx) != nil {
+		gathered, err := legacyregistry.DefaultGatherer.Gather()
+		if err != nil {
+			return benchmarkMetrics{}, err
+		}
+		families = map[string]*dto.MetricFamily{}
+		for _, family := range gathered {
+			families[family.GetName()] = family
+		}
+	} else {
+		client, err := discovery.NewDiscoveryClientForConfig(cfg.Client().RESTConfig())
+		if err != nil {
+			return benchmarkMetrics{}, err
+		}
+		data, err := client.RESTClient().Get().AbsPath("/metrics").DoRaw(ctx)
+		if err != nil {
+			return benchmarkMetrics{}, fmt.Errorf("failed to read the metrics of the API server: %w", err)
+		}
+		parser := expfmt.NewTextParser(model.UTF8Validation)
+		if families, err = parser.TextToMetricFamilies(bytes.NewReader(data)); err != nil {
+			return benchmarkMetrics{}, fmt.Errorf("failed to parse the metrics of the API server: %w", err)
+		}
+	}
+
+	admission := histogramsByLabel(families[AdmissionDurationMetric], "name",
+		map[string]string{"name": "ValidatingAdmissionPolicy", "type": "validate", "operation": "CREATE"})
+	return benchmarkMetrics{
+		admission: admission["ValidatingAdmissionPolicy"],
+		checks:    histogramsByLabel(families[CheckDurationMetric], "policy", nil),
+	}, nil
+}
+
+// histogramsByLabel sums the histograms of the metric family that have the labels by the value of a label
+func histogramsByLabel(family *dto.MetricFamily, label string, labels map[string]string) map[string]Histogram {
+	histograms := map[string]Histogram{}
+	for _, metric := range family.GetMetric() {
+		values := map[string]string{}
+		for _, pair := range metric.GetLabel() {
+			values[pair.GetName()] = pair.GetValue()
+		}
+		if !matchesLabels(values, labels) {
+			continue
+		}
+
+		h := Histogram{
+			Count:   metric.GetHistogram().GetSampleCount(),
+			Sum:     time.Duration(metric.GetHistogram().GetSampleSum() * float64(time.Second)),
+			Buckets: map[float64]uint64{},
+		}
+		for _, bucket := range metric.GetHistogram().GetBucket() {
+			h.Buckets[bucket.GetUpperBound()] = bucket.GetCumulativeCount()
+		}
+		sum := histograms[values[label]]
+		sum.add(h, 1)
+		histograms[values[label]] = sum
+	}
+	return histograms
+}
+
+func matchesLabels(values map[string]string, labels map[string]string) bool {
+	for name, value := range labels {
+		if values[name] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// latencyPercentiles returns the number of requests and the nearest-rank percentiles of their latency
+func latencyPercentiles(latencies []time.Duration) Measurement {
+	sorted := slices.Clone(latencies)
+	slices.Sort(sorted)
+	percentile := func(p float64) time.Duration {
+		if len(sorted) == 0 {
+			return 0
+		}
+		return sorted[int(math.Ceil(p*float64(len(sorted))))-1]
+	}
+	return Measurement{Requests: len(sorted), P50: percentile(0.50), P95: percentile(0.95), P99: percentile(0.99)}
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,301 @@
+package testutils
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	sigsyaml "sigs.k8s.io/yaml"
+)
+
+// Enforcement modes of the bindings, the value of the vap-library.com/<policy> namespace label
+const (
+	ModeDeny  = "deny"
+	ModeWarn  = "warn"
+	ModeAudit = "audit"
+)
+
+// ReleaseConfigPath is the release config, relative to the directory of a policy where the tests run
+const ReleaseConfigPath = "../../release-process/full-release-config.yaml"
+
+// BindingBuilder builds a ValidatingAdmissionPolicyBinding for a vap-library policy. NewBindingBuilder starts from the
+// binding that the release would ship for the enforcement mode, the With* methods change it.
+type BindingBuilder struct {
+	binding *admissionregistrationv1.ValidatingAdmissionPolicyBinding
+	// policyName and mode select the namespaces, or the objects with SelectObjectsByLabel
+	policyName string
+	mode       string
+}
+
+// NewBindingBuilder returns a builder for the <policyName>-<mode>.vap-library.com binding. It selects the namespaces
+// labelled with vap-library.com/<policyName>: <mode>, and the validation actions are Deny and Audit for ModeDeny,
+// Warn for ModeWarn and Audit for ModeAudit.
+func NewBindingBuilder(policyName string, mode string) *BindingBuilder {
+	matchPolicy := admissionregistrationv1.Equivalent
+	binding := &admissionregistrationv1.ValidatingAdmissionPolicyBinding{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: admissionregistrationv1.SchemeGroupVersion.String(),
+			Kind:       "ValidatingAdmissionPolicyBinding",
+		},
+		ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("%s-%s%s", policyName, mode, policyNameSuffix)},
+		Spec: admissionregistrationv1.ValidatingAdmissionPolicyBindingSpec{
+			PolicyName: policyName + policyNameSuffix,
+			MatchResources: &admissionregistrationv1.MatchResources{
+				MatchPolicy: &matchPolicy,
+				NamespaceSelector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{"vap-library.com/" + policyName: mode},
+				},
+				ObjectSelector: &metav1.LabelSelector{},
+			},
+			ValidationActions: validationActionsForMode(mode),
+		},
+	}
+	return &BindingBuilder{binding: binding, policyName: policyName, mode: mode}
+}
+
+// WithName sets the name of the binding
+func (b *BindingBuilder) WithName(name string) *BindingBuilder {
+	b.binding.Name = name
+	return b
+}
+
+// WithValidationActions replaces the validation actions of the binding
+func (b *BindingBuilder) WithValidationActions(actions ...admissionregistrationv1.ValidationAction) *BindingBuilder {
+	b.binding.Spec.ValidationActions = actions
+	return b
+}
+
+// WithNamespaceSelector replaces the namespace selector of the binding
+func (b *BindingBuilder) WithNamespaceSelector(selector *metav1.LabelSelector) *BindingBuilder {
+	b.binding.Spec.MatchResources.NamespaceSelector = selector
+	return b
+}
+
+// WithObjectSelector sets the object selector of the binding
+func (b *BindingBuilder) WithObjectSelector(selector *metav1.LabelSelector) *BindingBuilder {
+	b.binding.Spec.MatchResources.ObjectSelector = selector
+	return b
+}
+
+// SelectObjectsByLabel selects the objects labelled with vap-library.com/<policyName>: <mode> (see ObjectLabels) in
+// every namespace instead of the objects in the namespaces with that label. A namespaceSelector never filters
+// cluster-scoped objects other than Namespaces, so this is how a test enforces a policy on some of them only.
+func (b *BindingBuilder) SelectObjectsByLabel() *BindingBuilder {
+	b.binding.Spec.MatchResources.NamespaceSelector = &metav1.LabelSelector{}
+	b.binding.Spec.MatchResources.ObjectSelector = &metav1.LabelSelector{MatchLabels: ObjectLabels(b.policyName, b.mode)}
+	return b
+}
+
+// MatchAll removes the namespace and the object selector, the binding matches every object that the policy matches
+func (b *BindingBuilder) MatchAll() *BindingBuilder {
+	b.binding.Spec.MatchResources.NamespaceSelector = &metav1.LabelSelector{}
+	b.binding.Spec.MatchResources.ObjectSelector = &metav1.LabelSelector{}
+	return b
+}
+
+// WithParamRef references the parameter of the policy by name like the release does: <policyName>.vap-library.com in
+// the namespace of the request, and the request is denied if the parameter does not exist
+func (b *BindingBuilder) WithParamRef() *BindingBuilder {
+	return b.WithParamName(b.binding.Spec.PolicyName)
+}
+
+// WithParamName references the parameter with the given name
+func (b *BindingBuilder) WithParamName(name string) *BindingBuilder {
+	paramRef := b.paramRef()
+	paramRef.Name = name
+	paramRef.Selector = nil
+	return b
+}
+
+// WithParamSelector references the parameters that match the label selector instead of a parameter by name
+func (b *BindingBuilder) WithParamSelector(selector *metav1.LabelSelector) *BindingBuilder {
+	paramRef := b.paramRef()
+	paramRef.Selector = selector
+	paramRef.Name = ""
+	return b
+}
+
+// WithParamNamespace looks up the parameters in the given namespace instead of the namespace of the request, e.g. for
+// a cluster-wide parameter
+func (b *BindingBuilder) WithParamNamespace(namespace string) *BindingBuilder {
+	b.paramRef().Namespace = namespace
+	return b
+}
+
+// WithParameterNotFoundAction sets what happens to a request if no parameter is found
+func (b *BindingBuilder) WithParameterNotFoundAction(action admissionregistrationv1.ParameterNotFoundActionType) *BindingBuilder {
+	b.paramRef().ParameterNotFoundAction = &action
+	return b
+}
+
+// Build returns a copy of the binding
+func (b *BindingBuilder) Build() *admissionregistrationv1.ValidatingAdmissionPolicyBinding {
+	return b.binding.DeepCopy()
+}
+
+// Apply creates the binding, or updates it if it already exists, and waits until the binding becomes effective
+func (b *BindingBuilder) Apply(ctx context.Context, cfg *envconf.Config, timeout time.Duration) error {
+	return ApplyBinding(ctx, cfg, b.Build(), timeout)
+}
+
+// Delete removes the binding
+func (b *BindingBuilder) Delete(ctx context.Context, cfg *envconf.Config) error {
+	if evaluator := PolicyEvaluatorFromContext(ctx); evaluator != nil {
+		return evaluator.Delete(ctx, b.Build())
+	}
+	return cfg.Client().Resources().Delete(ctx, b.Build())
+}
+
+// paramRef returns the paramRef of the binding and creates it with parameterNotFoundAction Deny if it is not set yet
+func (b *BindingBuilder) paramRef() *admissionregistrationv1.ParamRef {
+	if b.binding.Spec.ParamRef == nil {
+		action := admissionregistrationv1.DenyAction
+		b.binding.Spec.ParamRef = &admissionregistrationv1.ParamRef{ParameterNotFoundAction: &action}
+	}
+	return b.binding.Spec.ParamRef
+}
+
+// ApplyBinding creates the binding, or updates it if it already exists, and waits until the binding becomes effective
+func ApplyBinding(ctx context.Context, cfg *envconf.Config, binding *admissionregistrationv1.ValidatingAdmissionPolicyBinding, timeout time.Duration) error {
+	return applyBindings(ctx, cfg, []*admissionregistrationv1.ValidatingAdmissionPolicyBinding{binding}, timeout)
+}
+
+// applyBindings creates or updates the bindings and waits once until all of them become effective
+func applyBindings(ctx context.Context, cfg *envconf.Config, bindings []*admissionregistrationv1.ValidatingAdmissionPolicyBinding, timeout time.Duration) error {
+	if evaluator := PolicyEvaluatorFromContext(ctx); evaluator != nil {
+		for _, binding := range bindings {
+			if err := evaluator.Apply(ctx, binding); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	r, err := resources.New(cfg.Client().RESTConfig())
+	if err != nil {
+		return err
+	}
+	for _, binding := range bindings {
+		// a binding may be left behind on a shared cluster by an earlier run
+		if err := createOrUpdate(ctx, r, binding); err != nil {
+			return err
+		}
+	}
+
+	// wait for the bindings to be registered properly
+	return WaitForBindings(ctx, cfg, timeout)
+}
+
+// ReleaseBindings returns the bindings of the policy exactly as the release process generates them from the release
+// config (see release-process/release.py). It returns no bindings if the policy is not enabled in the release.
+func ReleaseBindings(configPath string, policyName string) ([]*admissionregistrationv1.ValidatingAdmissionPolicyBinding, error) {
+	content, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	config := map[string]struct {
+		Enabled  bool                                                                      `json:"enabled"`
+		Bindings []map[string]admissionregistrationv1.ValidatingAdmissionPolicyBindingSpec `json:"bindings"`
+	}{}
+	if err := sigsyaml.Unmarshal(content, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse the release config %s: %w", configPath, err)
+	}
+
+	entry, ok := config[policyName]
+	if !ok || !entry.Enabled {
+		return nil, nil
+	}
+
+	var bindings []*admissionregistrationv1.ValidatingAdmissionPolicyBinding
+	for _, item := range entry.Bindings {
+		for name, spec := range item {
+			spec.PolicyName = policyName + policyNameSuffix
+			bindings = append(bindings, &admissionregistrationv1.ValidatingAdmissionPolicyBinding{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: admissionregistrationv1.SchemeGroupVersion.String(),
+					Kind:       "ValidatingAdmissionPolicyBinding",
+				},
+				ObjectMeta: metav1.ObjectMeta{Name: name},
+				Spec:       spec,
+			})
+		}
+	}
+	return bindings, nil
+}
+
+// ApplyReleaseBindings applies the bindings of the policy from the release config and waits until they become
+// effective
+func ApplyReleaseBindings(ctx context.Context, cfg *envconf.Config, policyName string, timeout time.Duration) (context.Context, error) {
+	bindings, err := ReleaseBindings(ReleaseConfigPath, policyName)
+	if err != nil {
+		return ctx, err
+	}
+	if len(bindings) == 0 {
+		return ctx, fmt.Errorf("policy %s has no bindings in the release config %s", policyName, ReleaseConfigPath)
+	}
+
+	return ctx, applyBindings(ctx, cfg, bindings, timeout)
+}
+
+// testBindings returns the bindings that CreateTestEnv applies for the policy: the bindings of the release config and
+// the deny, warn and audit bindings that the release does not define
+func testBindings(policyName string, paramRef bool) ([]*admissionregistrationv1.ValidatingAdmissionPolicyBinding, error) {
+	bindings, err := ReleaseBindings(ReleaseConfigPath, policyName)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	released := map[string]bool{}
+	for _, binding := range bindings {
+		released[binding.Name] = true
+	}
+	for _, mode := range []string{ModeDeny, ModeWarn, ModeAudit} {
+		builder := NewBindingBuilder(policyName, mode)
+		if paramRef {
+			builder.WithParamRef()
+		}
+		if binding := builder.Build(); !released[binding.Name] {
+			bindings = append(bindings, binding)
+		}
+	}
+	return bindings, nil
+}
+
+// deleteBindingsForTesting removes the bindings that CreateTestEnv applied for the policy
+func deleteBindingsForTesting(ctx context.Context, cfg *envconf.Config, policyName string, paramRef bool) error {
+	bindings, err := testBindings(policyName, paramRef)
+	if err != nil {
+		return err
+	}
+
+	r, err := resources.New(cfg.Client().RESTConfig())
+	if err != nil {
+		return err
+	}
+	for _, binding := range bindings {
+		if err := r.Delete(ctx, binding); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// validationActionsForMode returns the validation actions of the release bindings for the enforcement mode
+func validationActionsForMode(mode string) []admissionregistrationv1.ValidationAction {
+	switch mode {
+	case ModeDeny:
+		return []admissionregistrationv1.ValidationAction{admissionregistrationv1.Deny, admissionregistrationv1.Audit}
+	case ModeWarn:
+		return []admissionregistrationv1.ValidationAction{admissionregistrationv1.Warn}
+	case ModeAudit:
+		return []admissionregistrationv1.ValidationAction{admissionregistrationv1.Audit}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package testutils
+
+import (
+	"context"
+	"flag"
+	"os"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/e2e-framework/klient/conf"
+	"sigs.k8s.io/e2e-framework/klient/decoder"
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
+)
+
+const (
+	// KubeconfigEnvVar is the kubeconfig of an existing cluster that the tests run on instead of creating a Kind cluster
+	KubeconfigEnvVar = "VAPLIB_KUBECONFIG"
+
+	// KindVersionEnvVar is the Kubernetes version (e.g. v1.31.9) or the node image of the Kind cluster
+	KindVersionEnvVar = "VAPLIB_KIND_VERSION"
+
+	kindNodeImage = "kindest/node"
+)
+
+var (
+	// reuseCluster runs the tests on the cluster of --kubeconfig (or KUBECONFIG, or ~/.kube/config) instead of
+	// creating a Kind cluster
+	reuseCluster bool
+
+	// kindVersionFlag overrides KindVersionEnvVar
+	kindVersionFlag string
+)
+
+// registerFlags registers the flags of the test environment on flag.CommandLine unless they already are. Like the
+// e2e-framework does for its own flags, they are registered right before env.NewFromFlags parses them, so the
+// binaries that import testutils do not advertise them.
+func registerFlags() {
+	if flag.Lookup("reuse-cluster") == nil {
+		flag.BoolVar(&reuseCluster, "reuse-cluster", false, "run the tests on the existing cluster of --kubeconfig instead of creating a Kind cluster")
+	}
+	if flag.Lookup("kind-version") == nil {
+		flag.StringVar(&kindVersionFlag, "kind-version", "", "Kubernetes version (e.g. v1.31.9) or node image of the Kind cluster, overrides "+KindVersionEnvVar)
+	}
+}
+
+// kindImage returns the node image of the Kind cluster. The version passed to CreateTestEnv takes precedence over the
+// --kind-version flag, the flag over KindVersionEnvVar, and defaultKindVersion is used if none of them is set.
+func kindImage(version string) string {
+	for _, v := range []string{version, kindVersionFlag, os.Getenv(KindVersionEnvVar)} {
+		if v == "" {
+			continue
+		}
+		if strings.Contains(v, ":") {
+			// already a node image, e.g. kindest/node:v1.31.9@sha256:...
+			return v
+		}
+		return kindNodeImage + ":" + v
+	}
+	return kindNodeImage + ":" + defaultKindVersion
+}
+
+// existingClusterKubeconfig returns the kubeconfig of the existing cluster to run the tests on, or an empty string if
+// a Kind cluster has to be created for the tests
+func existingClusterKubeconfig() string {
+	if kubeconfig := os.Getenv(KubeconfigEnvVar); kubeconfig != "" {
+		return kubeconfig
+	}
+	if reuseCluster {
+		return conf.ResolveKubeConfigFile()
+	}
+	return ""
+}
+
+// createOrUpdateHandler returns a HandlerFunc that creates the object or updates it if it already exists, so that
+// resources left behind by an earlier run on the same cluster are replaced by the current version
+func createOrUpdateHandler(r *resources.Resources) decoder.HandlerFunc {
+	return func(ctx context.Context, obj k8s.Object) error {
+		return createOrUpdate(ctx, r, obj)
+	}
+}
+
+// createOrUpdate creates the object or updates it if it already exists
+func createOrUpdate(ctx context.Context, r *resources.Resources, obj k8s.Object) error {
+	err := r.Create(ctx, obj)
+	if !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+
+	existing := obj.DeepCopyObject().(k8s.Object)
+	if err := r.Get(ctx, obj.GetName(), obj.GetNamespace(), existing); err != nil {
+		return err
+	}
+	obj.SetResourceVersion(existing.GetResourceVersion())
+	return r.Update(ctx, obj)
+}
@@ -0,0 +1,151 @@
+package testutils
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/e2e-framework/klient/decoder"
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+)
+
+// TestNamespaceLabel is set on the cluster-scoped objects of a test, its value is the namespace of the test, so that
+// objects left behind on a shared cluster can be traced back to their test
+const TestNamespaceLabel = "vap-library.com/test-namespace"
+
+type clusterObjectsCtxKey struct{}
+
+// clusterObjects are the cluster-scoped objects that a test persisted, they are deleted after the test
+type clusterObjects struct {
+	mu      sync.Mutex
+	objects []k8s.Object
+}
+
+func (c *clusterObjects) add(obj k8s.Object) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.objects = append(c.objects, obj.DeepCopyObject().(k8s.Object))
+}
+
+// ClusterScopedName returns a name for a cluster-scoped object of the test that no other test uses: the name prefixed
+// with the namespace of the test. Cluster-scoped objects are shared by all the tests of a cluster, unlike the
+// namespaced ones.
+func ClusterScopedName(ctx context.Context, t *testing.T, name string) string {
+	namespace, ok := ctx.Value(GetNamespaceKey(t)).(string)
+	if !ok {
+		t.Fatalf("no namespace found for test %s", t.Name())
+	}
+	return namespace + "-" + name
+}
+
+// ObjectLabels returns the label that selects an object for the given enforcement mode of the policy
+// (vap-library.com/<policyName>: <mode>), see BindingBuilder.SelectObjectsByLabel
+func ObjectLabels(policyName string, mode string) map[string]string {
+	return map[string]string{"vap-library.com/" + policyName: mode}
+}
+
+// CreateClusterScopedFixture creates a cluster-scoped object that the test needs, e.g. the ClusterRole of a
+// ClusterRoleBinding or a StorageClass. The object is persisted, labelled with TestNamespaceLabel and deleted after
+// the test. Use ClusterScopedName for its name, so that the tests do not share it. The object of the caller is not
+// modified.
+func CreateClusterScopedFixture(ctx context.Context, cfg *envconf.Config, t *testing.T, obj k8s.Object) error {
+	t.Helper()
+
+	namespace, ok := ctx.Value(GetNamespaceKey(t)).(string)
+	if !ok {
+		return fmt.Errorf("no namespace found for test %s", t.Name())
+	}
+	obj = obj.DeepCopyObject().(k8s.Object)
+	obj.SetLabels(mergeLabels(obj.GetLabels(), map[string]string{TestNamespaceLabel: namespace}))
+	return applyK8sResource(ctx, cfg, obj, WithoutDryRun)
+}
+
+// CreateClusterScopedFixtureFromYAML is CreateClusterScopedFixture for an object in YAML
+func CreateClusterScopedFixtureFromYAML(ctx context.Context, cfg *envconf.Config, t *testing.T, yaml string) error {
+	t.Helper()
+
+	obj, err := decoder.DecodeAny(strings.NewReader(yaml))
+	if err != nil {
+		return err
+	}
+	return CreateClusterScopedFixture(ctx, cfg, t, obj)
+}
+
+// trackClusterObject registers a persisted cluster-scoped object for the deletion after the test, namespaced objects
+// are deleted with the namespace of the test. An object whose scope cannot be looked up is registered as well, as it
+// is already persisted.
+func trackClusterObject(ctx context.Context, cfg *envconf.Config, obj k8s.Object, opts []resources.CreateOption) {
+	tracked, ok := ctx.Value(clusterObjectsCtxKey{}).(*clusterObjects)
+	if !ok {
+		return
+	}
+	createOptions := &metav1.CreateOptions{}
+	for _, opt := range opts {
+		opt(createOptions)
+	}
+	if len(createOptions.DryRun) > 0 {
+		return
+	}
+
+	namespaced, err := isNamespaced(ctx, cfg, obj)
+	if err != nil {
+		log.Printf("unable to find the scope of %s %s, deleting it after the test: %s", obj.GetObjectKind().GroupVersionKind().Kind, obj.GetName(), err)
+	}
+	if err != nil || !namespaced {
+		tracked.add(obj)
+	}
+}
+
+// isNamespaced returns true if the kind of the object is namespaced
+func isNamespaced(ctx context.Context, cfg *envconf.Config, obj k8s.Object) (bool, error) {
+	if evaluator := PolicyEvaluatorFromContext(ctx); evaluator != nil {
+		gvk := obj.GetObjectKind().GroupVersionKind()
+		mapping, ok := evaluator.mappings[gvk]
+		if !ok {
+			return false, &meta.NoKindMatchError{GroupKind: gvk.GroupKind(), SearchedVersions: []string{gvk.Version}}
+		}
+		return mapping.Scope.Name() == meta.RESTScopeNameNamespace, nil
+	}
+	return cfg.Client().Resources().GetControllerRuntimeClient().IsObjectNamespaced(obj)
+}
+
+// createClusterObjectsForTest stores the registry of the cluster-scoped objects of the test in the context
+func createClusterObjectsForTest(ctx context.Context) context.Context {
+	return context.WithValue(ctx, clusterObjectsCtxKey{}, &clusterObjects{})
+}
+
+// deleteClusterObjectsForTest deletes the cluster-scoped objects of the test in the reverse order of their creation
+func deleteClusterObjectsForTest(ctx context.Context, cfg *envconf.Config, t *testing.T) error {
+	tracked, ok := ctx.Value(clusterObjectsCtxKey{}).(*clusterObjects)
+	if !ok {
+		return nil
+	}
+	tracked.mu.Lock()
+	objects := tracked.objects
+	tracked.objects = nil
+	tracked.mu.Unlock()
+
+	for i := len(objects) - 1; i >= 0; i-- {
+		obj := objects[i]
+		t.Logf("Deleting %s %v for test %v", obj.GetObjectKind().GroupVersionKind().Kind, obj.GetName(), t.Name())
+
+		var err error
+		if evaluator := PolicyEvaluatorFromContext(ctx); evaluator != nil {
+			err = evaluator.Delete(ctx, obj)
+		} else {
+			err = cfg.Client().Resources().Delete(ctx, obj)
+		}
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,445 @@
+package testutils
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker"
+	"github.com/google/cel-go/common/overloads"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/version"
+	plugincel "k8s.io/apiserver/pkg/admission/plugin/cel"
+	celconfig "k8s.io/apiserver/pkg/apis/cel"
+	apiservercel "k8s.io/apiserver/pkg/cel"
+	"k8s.io/apiserver/pkg/cel/common"
+	"k8s.io/apiserver/pkg/cel/environment"
+	"k8s.io/apiserver/pkg/cel/library"
+	"k8s.io/apiserver/pkg/cel/openapi"
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+)
+
+const (
+	// ExpressionCostLimit is the cost limit of a single evaluation of a CEL expression in the API server
+	ExpressionCostLimit = uint64(celconfig.PerCallLimit)
+	// PolicyCostLimit is the cost budget of the variables, validations, message expressions and audit annotations of
+	// a ValidatingAdmissionPolicy per request in the API server
+	PolicyCostLimit = uint64(celconfig.RuntimeCELCostBudget)
+)
+
+// ExpressionCost is the estimated worst case cost of a CEL expression of a ValidatingAdmissionPolicy
+type ExpressionCost struct {
+	// Policy is the name of the policy
+	Policy string
+	// FieldRef is the path of the expression in the policy, e.g. spec.validations[0].expression
+	FieldRef string
+	// Kind is the matched kind with the highest cost
+	Kind string
+	// Cost is the estimated worst case cost, math.MaxUint64 if the cost is unbounded
+	Cost uint64
+}
+
+// Fraction returns the cost as a fraction of ExpressionCostLimit
+func (c ExpressionCost) Fraction() float64 {
+	return float64(c.Cost) / float64(ExpressionCostLimit)
+}
+
+// PolicyCost is the estimated worst case cost of the CEL expressions of a ValidatingAdmissionPolicy
+type PolicyCost struct {
+	// Name is the name of the policy
+	Name string
+	// Expressions are the costs of the expressions in the order of the policy
+	Expressions []ExpressionCost
+	// Kind is the matched kind with the highest total
+	Kind string
+	// Total is the estimated worst case cost of the expressions that share PolicyCostLimit for one request of Kind
+	Total uint64
+}
+
+// Fraction returns the total cost as a fraction of PolicyCostLimit
+func (c PolicyCost) Fraction() float64 {
+	return float64(c.Total) / float64(PolicyCostLimit)
+}
+
+// CostOptions are the assumptions about the size of the objects of EstimatePolicyCosts
+type CostOptions struct {
+	// MaxItems, if positive, is the maximum number of elements of every list and map, e.g. the containers of a Pod
+	MaxItems int64
+	// MaxLength, if positive, is the maximum length of every string
+	MaxLength int64
+}
+
+// EstimatePolicyCosts estimates the worst case cost of every CEL expression of the ValidatingAdmissionPolicies among
+// the objects the way the API server does: every expression is compiled against the schema of every kind the policy
+// matches and the size of every list, map and string is bounded by its maxItems, maxProperties or maxLength, or else
+// by the maximum size of a request. The schemas of the custom resources and parameters are taken from the
+// CustomResourceDefinitions among the objects and the schemas of the built-in kinds are derived from their Go types.
+// Expressions that do not compile against a kind (e.g. object.spec.template of a Pod, behind an object.kind guard) do
+// not count for that kind. Lists that are returned by variables have no known size, so their cost is unbounded.
+// The maximum size of a request allows for very large lists, so the options can bound the sizes to realistic objects.
+func EstimatePolicyCosts(objects []k8s.Object, opts CostOptions) ([]PolicyCost, error) {
+	mapper, resolver, err := policyTypes(objects)
+	if err != nil {
+		return nil, err
+	}
+
+	var costs []PolicyCost
+	for _, obj := range objects {
+		if obj.GetObjectKind().GroupVersionKind().Kind != "ValidatingAdmissionPolicy" {
+			continue
+		}
+		policy := &admissionregistrationv1.ValidatingAdmissionPolicy{}
+		if err := toTyped(obj, policy); err != nil {
+			return nil, err
+		}
+		cost, err := estimatePolicyCost(policy, mapper, resolver, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to estimate the cost of ValidatingAdmissionPolicy %s: %w", policy.Name, err)
+		}
+		costs = append(costs, cost)
+	}
+	sort.Slice(costs, func(i, j int) bool { return costs[i].Name < costs[j].Name })
+	return costs, nil
+}
+
+// CheckPolicyCosts returns an error that lists the expressions whose cost exceeds the fraction of ExpressionCostLimit
+// and the policies whose total exceeds the fraction of PolicyCostLimit, or nil if there are none
+func CheckPolicyCosts(costs []PolicyCost, fraction float64) error {
+	var messages []string
+	for _, policy := range costs {
+		for _, expression := range policy.Expressions {
+			if expression.Fraction() > fraction {
+				messages = append(messages, fmt.Sprintf("%s %s: the estimated cost %s for %s exceeds %.0f%% of the limit %d",
+					policy.Name, expression.FieldRef, formatCost(expression.Cost), expression.Kind, fraction*100, ExpressionCostLimit))
+			}
+		}
+		if policy.Fraction() > fraction {
+			messages = append(messages, fmt.Sprintf("%s: the estimated total cost %s for %s exceeds %.0f%% of the budget %d",
+				policy.Name, formatCost(policy.Total), policy.Kind, fraction*100, PolicyCostLimit))
+		}
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s", strings.Join(messages, "\n"))
+}
+
+// MostExpensiveExpressions returns the n expressions with the highest cost of all the policies
+func MostExpensiveExpressions(costs []PolicyCost, n int) []ExpressionCost {
+	var expressions []ExpressionCost
+	for _, policy := range costs {
+		expressions = append(expressions, policy.Expressions...)
+	}
+	sort.SliceStable(expressions, func(i, j int) bool { return expressions[i].Cost > expressions[j].Cost })
+	if n >= 0 && len(expressions) > n {
+		expressions = expressions[:n]
+	}
+	return expressions
+}
+
+// estimatePolicyCost estimates the cost of the expressions of the policy for every matched kind and keeps the worst
+func estimatePolicyCost(policy *admissionregistrationv1.ValidatingAdmissionPolicy, mapper meta.RESTMapper, resolver *goTypeSchemaResolver, opts CostOptions) (PolicyCost, error) {
+	result := PolicyCost{Name: policy.Name}
+
+	var params *apiservercel.DeclType
+	if policy.Spec.ParamKind != nil {
+		gv, err := schema.ParseGroupVersion(policy.Spec.ParamKind.APIVersion)
+		if err != nil {
+			return result, err
+		}
+		if params, err = resolver.declType(gv.WithKind(policy.Spec.ParamKind.Kind)); err != nil {
+			return result, err
+		}
+	}
+
+	expressions := policyExpressions(policy)
+	for _, gvk := range matchedKinds(policy, mapper) {
+		object, err := resolver.declType(gvk)
+		if err != nil {
+			return result, err
+		}
+		costs, total, err := estimateExpressionCosts(expressions, object, params, opts)
+		if err != nil {
+			return result, err
+		}
+
+		if result.Kind == "" || total > result.Total {
+			result.Kind = gvk.Kind
+			result.Total = total
+		}
+		for i, cost := range costs {
+			if i >= len(result.Expressions) {
+				result.Expressions = append(result.Expressions, ExpressionCost{Policy: policy.Name, FieldRef: expressions[i].fieldRef})
+			}
+			if cost != nil && (result.Expressions[i].Kind == "" || *cost > result.Expressions[i].Cost) {
+				result.Expressions[i].Kind = gvk.Kind
+				result.Expressions[i].Cost = *cost
+			}
+		}
+	}
+	return result, nil
+}
+
+// estimateExpressionCosts compiles the expressions for an object type and returns their costs (nil if an expression
+// does not compile) and the total of the expressions that share the policy budget
+func estimateExpressionCosts(expressions []policyExpression, object *apiservercel.DeclType, params *apiservercel.DeclType, opts CostOptions) ([]*uint64, uint64, error) {
+	envSet, err := costEnvSet(object, params)
+	if err != nil {
+		return nil, 0, err
+	}
+	compositionEnv, err := plugincel.NewCompositionEnv(plugincel.VariablesTypeName, envSet)
+	if err != nil {
+		return nil, 0, err
+	}
+	env, err := compositionEnv.Env(environment.StoredExpressions)
+	if err != nil {
+		return nil, 0, err
+	}
+	estimator := newPolicyCostEstimator(map[string]*apiservercel.DeclType{
+		plugincel.ObjectVarName:    object,
+		plugincel.OldObjectVarName: object,
+		plugincel.ParamsVarName:    params,
+		plugincel.RequestVarName:   plugincel.BuildRequestType(),
+		plugincel.NamespaceVarName: plugincel.BuildNamespaceType(),
+	}, opts)
+
+	costs := make([]*uint64, len(expressions))
+	var total uint64
+	for i, expression := range expressions {
+		ast, issues := env.Compile(expression.expression)
+		if issues != nil {
+			if expression.variable != "" {
+				// declare the variable anyway, like the API server does
+				compositionEnv.AddField(expression.variable, cel.DynType)
+			}
+			continue
+		}
+		if expression.variable != "" {
+			compositionEnv.AddField(expression.variable, ast.OutputType())
+		}
+
+		estimate, err := env.EstimateCost(ast, estimator)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to estimate the cost of %s: %w", expression.fieldRef, err)
+		}
+		cost := estimate.Max
+		costs[i] = &cost
+		if expression.budget {
+			total = addCost(total, cost)
+		}
+	}
+	return costs, total, nil
+}
+
+// costEnvSet returns the CEL environment of the policy expressions with typed object, oldObject and params
+func costEnvSet(object *apiservercel.DeclType, params *apiservercel.DeclType) (*environment.EnvSet, error) {
+	requestType := plugincel.BuildRequestType()
+	namespaceType := plugincel.BuildNamespaceType()
+	declTypes := []*apiservercel.DeclType{requestType, namespaceType, object}
+	opts := []cel.EnvOption{
+		cel.Variable(plugincel.RequestVarName, requestType.CelType()),
+		cel.Variable(plugincel.NamespaceVarName, namespaceType.CelType()),
+		cel.Variable(plugincel.ObjectVarName, object.CelType()),
+		cel.Variable(plugincel.OldObjectVarName, object.CelType()),
+		cel.Variable("authorizer", library.AuthorizerType),
+	}
+	if params != nil {
+		declTypes = append(declTypes, params)
+		opts = append(opts, cel.Variable(plugincel.ParamsVarName, params.CelType()))
+	} else {
+		opts = append(opts, cel.Variable(plugincel.ParamsVarName, cel.DynType))
+	}
+
+	return environment.MustBaseEnvSet(environment.DefaultCompatibilityVersion()).Extend(
+		environment.VersionedOptions{
+			IntroducedVersion: version.MajorMinor(1, 0),
+			EnvOptions:        opts,
+			DeclTypes:         declTypes,
+		},
+	)
+}
+
+// policyExpression is a CEL expression of a policy
+type policyExpression struct {
+	fieldRef   string
+	expression string
+	// variable is the name of the variable the expression defines
+	variable string
+	// budget is true if the expression counts towards PolicyCostLimit
+	budget bool
+}
+
+// policyExpressions returns the CEL expressions of the policy, the variables first
+func policyExpressions(policy *admissionregistrationv1.ValidatingAdmissionPolicy) []policyExpression {
+	var expressions []policyExpression
+	for i, v := range policy.Spec.Variables {
+		expressions = append(expressions, policyExpression{fieldRef: fmt.Sprintf("spec.variables[%d].expression", i), expression: v.Expression, variable: v.Name, budget: true})
+	}
+	for i, c := range policy.Spec.MatchConditions {
+		expressions = append(expressions, policyExpression{fieldRef: fmt.Sprintf("spec.matchConditions[%d].expression", i), expression: c.Expression})
+	}
+	for i, v := range policy.Spec.Validations {
+		expressions = append(expressions, policyExpression{fieldRef: fmt.Sprintf("spec.validations[%d].expression", i), expression: v.Expression, budget: true})
+		if v.MessageExpression != "" {
+			expressions = append(expressions, policyExpression{fieldRef: fmt.Sprintf("spec.validations[%d].messageExpression", i), expression: v.MessageExpression, budget: true})
+		}
+	}
+	for i, a := range policy.Spec.AuditAnnotations {
+		expressions = append(expressions, policyExpression{fieldRef: fmt.Sprintf("spec.auditAnnotations[%d].valueExpression", i), expression: a.ValueExpression, budget: true})
+	}
+	return expressions
+}
+
+// matchedKinds returns the kinds of the resource rules of the policy. A wildcard version matches every version of
+// the resource, wildcard groups and resources and unknown resources are skipped.
+func matchedKinds(policy *admissionregistrationv1.ValidatingAdmissionPolicy, mapper meta.RESTMapper) []schema.GroupVersionKind {
+	if policy.Spec.MatchConstraints == nil {
+		return nil
+	}
+
+	seen := map[schema.GroupVersionKind]bool{}
+	var kinds []schema.GroupVersionKind
+	for _, rule := range policy.Spec.MatchConstraints.ResourceRules {
+		for _, group := range rule.APIGroups {
+			for _, version := range rule.APIVersions {
+				for _, resource := range rule.Resources {
+					// the subresources of a resource are sent as the object of the resource
+					resource, _, _ = strings.Cut(resource, "/")
+					if group == "*" || resource == "*" {
+						continue
+					}
+					if version == "*" {
+						// every served version
+						version = ""
+					}
+					gvks, err := mapper.KindsFor(schema.GroupVersionResource{Group: group, Version: version, Resource: resource})
+					if err != nil {
+						continue
+					}
+					for _, gvk := range gvks {
+						if !seen[gvk] {
+							seen[gvk] = true
+							kinds = append(kinds, gvk)
+						}
+					}
+				}
+			}
+		}
+	}
+	return kinds
+}
+
+// addCost adds two costs without overflowing
+func addCost(a, b uint64) uint64 {
+	if a > math.MaxUint64-b {
+		return math.MaxUint64
+	}
+	return a + b
+}
+
+// capSize returns the size capped to the limit, if the limit is positive
+func capSize(size int64, limit int64) int64 {
+	if limit > 0 && size > limit {
+		return limit
+	}
+	return size
+}
+
+// formatCost formats a cost, unbounded costs are shown as such
+func formatCost(cost uint64) string {
+	if cost == math.MaxUint64 {
+		return "unbounded"
+	}
+	return fmt.Sprintf("%d", cost)
+}
+
+// policyCostEstimator estimates the sizes of lists, maps and strings from the types of the variables and the costs
+// of the Kubernetes CEL libraries
+type policyCostEstimator struct {
+	library *library.CostEstimator
+	roots   map[string]*apiservercel.DeclType
+	opts    CostOptions
+}
+
+// newPolicyCostEstimator returns a cost estimator for the variables of the types
+func newPolicyCostEstimator(roots map[string]*apiservercel.DeclType, opts CostOptions) *policyCostEstimator {
+	e := &policyCostEstimator{roots: roots, opts: opts}
+	e.library = &library.CostEstimator{SizeEstimator: e}
+	return e
+}
+
+// EstimateSize implements checker.CostEstimator
+func (e *policyCostEstimator) EstimateSize(element checker.AstNode) *checker.SizeEstimate {
+	path := element.Path()
+	if len(path) == 0 {
+		return nil
+	}
+	current := e.roots[path[0]]
+	if current == nil {
+		return nil
+	}
+	for _, name := range path[1:] {
+		switch name {
+		case "@items", "@values":
+			current = current.ElemType
+		case "@keys":
+			current = current.KeyType
+		default:
+			field, ok := current.Fields[name]
+			if !ok {
+				// properties named like CEL keywords, e.g. namespace, are escaped
+				if escaped, escapable := apiservercel.Escape(name); escapable {
+					field, ok = current.Fields[escaped]
+				}
+			}
+			if !ok {
+				return nil
+			}
+			current = field.Type
+		}
+		if current == nil {
+			return nil
+		}
+	}
+
+	size := current.MaxElements
+	switch {
+	case current.IsList() || current.IsMap():
+		size = capSize(size, e.opts.MaxItems)
+	case !current.IsObject():
+		size = capSize(size, e.opts.MaxLength)
+	}
+	return &checker.SizeEstimate{Min: 0, Max: uint64(size)}
+}
+
+// EstimateCallCost implements checker.CostEstimator. The string conversion of a string returns the string itself, so
+// the size of the result is the size of the argument.
+func (e *policyCostEstimator) EstimateCallCost(function, overloadID string, target *checker.AstNode, args []checker.AstNode) *checker.CallEstimate {
+	if overloadID == overloads.StringToString && len(args) == 1 {
+		size := args[0].ComputedSize()
+		if size == nil {
+			size = e.EstimateSize(args[0])
+		}
+		if size != nil {
+			return &checker.CallEstimate{CostEstimate: checker.CostEstimate{Min: 1, Max: 1}, ResultSize: size}
+		}
+	}
+	return e.library.EstimateCallCost(function, overloadID, target, args)
+}
+
+// declType returns the CEL type of the kind
+func (r *goTypeSchemaResolver) declType(gvk schema.GroupVersionKind) (*apiservercel.DeclType, error) {
+	s, err := r.ResolveSchema(gvk)
+	if err != nil {
+		return nil, err
+	}
+	declType := common.SchemaDeclType(&openapi.Schema{Schema: s}, true)
+	if declType == nil {
+		return nil, fmt.Errorf("the schema of %s has no CEL type", gvk)
+	}
+	return declType.MaybeAssignTypeName(strings.ReplaceAll(gvk.GroupVersion().String(), "/", ".") + "." + gvk.Kind), nil
+}
@@ -0,0 +1,350 @@
+package testutils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/admission"
+	"k8s.io/apiserver/pkg/admission/plugin/policy/validating"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+)
+
+// CoverageDirEnvVar is the directory where the in-process backend writes the CEL coverage of the policies of a test
+// package, one <package>.<policy>.json file per policy
+const CoverageDirEnvVar = "VAPLIB_COVERAGE_DIR"
+
+// CoverageReport is the CEL coverage of a set of policies
+type CoverageReport struct {
+	Policies []PolicyCoverage `json:"policies"`
+}
+
+// PolicyCoverage is the CEL coverage of the validations of a policy
+type PolicyCoverage struct {
+	// Name is the name of the ValidatingAdmissionPolicy
+	Name string `json:"name"`
+	// Requests is the number of requests that the policy evaluated
+	Requests int `json:"requests"`
+	// NotMatched is the number of requests that the matchConditions of the policy excluded
+	NotMatched int `json:"notMatched"`
+	// Validations is the coverage of every entry of spec.validations, in the order of the policy
+	Validations []ValidationCoverage `json:"validations"`
+}
+
+// ValidationCoverage is the coverage of one entry of spec.validations
+type ValidationCoverage struct {
+	Index      int    `json:"index"`
+	Expression string `json:"expression"`
+	Message    string `json:"message,omitempty"`
+	// Evaluated is the number of objects the expression was evaluated for
+	Evaluated int `json:"evaluated"`
+	// Allowed is the number of objects the expression allowed
+	Allowed int `json:"allowed"`
+	// Denied is the number of objects the expression denied
+	Denied int `json:"denied"`
+	// Errors is the number of evaluation errors
+	Errors int `json:"errors"`
+	// DeniedKinds is the number of denied objects per kind
+	DeniedKinds map[string]int `json:"deniedKinds,omitempty"`
+}
+
+// Exercised returns true if the validation denied at least one object, only then a test has shown that the
+// expression rejects what it should
+func (v ValidationCoverage) Exercised() bool {
+	return v.Denied > 0
+}
+
+// coverageRecorder records the decisions of the validations of the policies that the in-process backend evaluates
+type coverageRecorder struct {
+	mu       sync.Mutex
+	policies map[string]*PolicyCoverage
+}
+
+// newCoverageRecorder returns an empty recorder
+func newCoverageRecorder() *coverageRecorder {
+	return &coverageRecorder{policies: map[string]*PolicyCoverage{}}
+}
+
+// compile returns a function that compiles a policy and records the decisions of its validator
+func (r *coverageRecorder) compile(compile func(*admissionregistrationv1.ValidatingAdmissionPolicy) validating.Validator) func(*admissionregistrationv1.ValidatingAdmissionPolicy) validating.Validator {
+	return func(policy *admissionregistrationv1.ValidatingAdmissionPolicy) validating.Validator {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		// a policy is compiled again when it changes, the counts of the validations that did not change are kept
+		previous := r.policies[policy.Name]
+		coverage := &PolicyCoverage{Name: policy.Name}
+		if previous != nil {
+			coverage.Requests, coverage.NotMatched = previous.Requests, previous.NotMatched
+		}
+		for i, v := range policy.Spec.Validations {
+			validation := ValidationCoverage{Index: i, Expression: v.Expression, Message: v.Message, DeniedKinds: map[string]int{}}
+			if previous != nil && i < len(previous.Validations) && previous.Validations[i].Expression == v.Expression {
+				validation = previous.Validations[i]
+			}
+			coverage.Validations = append(coverage.Validations, validation)
+		}
+		r.policies[policy.Name] = coverage
+
+		return &coverageValidator{Validator: compile(policy), recorder: r, policyName: policy.Name}
+	}
+}
+
+// record adds the decisions of a request to the coverage of the policy
+func (r *coverageRecorder) record(policyName string, kind string, result validating.ValidateResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	coverage, ok := r.policies[policyName]
+	if !ok {
+		return
+	}
+	coverage.Requests++
+	if len(result.Decisions) == 0 && len(coverage.Validations) > 0 {
+		coverage.NotMatched++
+		return
+	}
+	// the validator returns a single error decision if the evaluation failed as a whole
+	if len(result.Decisions) != len(coverage.Validations) {
+		for i := range coverage.Validations {
+			coverage.Validations[i].Errors++
+		}
+		return
+	}
+
+	for i, decision := range result.Decisions {
+		validation := &coverage.Validations[i]
+		validation.Evaluated++
+		switch decision.Evaluation {
+		case validating.EvalAdmit:
+			validation.Allowed++
+		case validating.EvalDeny:
+			validation.Denied++
+			validation.DeniedKinds[kind]++
+		case validating.EvalError:
+			validation.Errors++
+		}
+	}
+}
+
+// report returns a copy of the coverage of all the policies
+func (r *coverageRecorder) report() CoverageReport {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	report := CoverageReport{}
+	for _, coverage := range r.policies {
+		report.Policies = append(report.Policies, copyPolicyCoverage(*coverage))
+	}
+	sort.Slice(report.Policies, func(i, j int) bool { return report.Policies[i].Name < report.Policies[j].Name })
+	return report
+}
+
+// coverageValidator records the decisions of a validator
+type coverageValidator struct {
+	validating.Validator
+	recorder   *coverageRecorder
+	policyName string
+}
+
+// Validate implements validating.Validator
+func (v *coverageValidator) Validate(ctx context.Context, matchedResource schema.GroupVersionResource, versionedAttr *admission.VersionedAttributes, versionedParams runtime.Object, namespace *corev1.Namespace, runtimeCELCostBudget int64, authz authorizer.Authorizer) validating.ValidateResult {
+	result := v.Validator.Validate(ctx, matchedResource, versionedAttr, versionedParams, namespace, runtimeCELCostBudget, authz)
+	v.recorder.record(v.policyName, versionedAttr.VersionedKind.Kind, result)
+	return result
+}
+
+// WriteCoverage writes the coverage of every policy to <dir>/<prefix>.<policy>.json
+func WriteCoverage(dir string, prefix string, report CoverageReport) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	for _, coverage := range report.Policies {
+		f, err := os.Create(filepath.Join(dir, prefix+"."+coverage.Name+".json"))
+		if err != nil {
+			return err
+		}
+		err = writeJSON(f, coverage)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadCoverage reads the coverage files of a directory. The coverage of a policy that was written by several test
+// packages is summed up.
+func LoadCoverage(dir string) (CoverageReport, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return CoverageReport{}, err
+	}
+
+	policies := map[string]*PolicyCoverage{}
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return CoverageReport{}, err
+		}
+		coverage := PolicyCoverage{}
+		if err := json.Unmarshal(content, &coverage); err != nil {
+			return CoverageReport{}, fmt.Errorf("failed to parse %s: %w", file, err)
+		}
+		if existing, ok := policies[coverage.Name]; ok {
+			mergePolicyCoverage(existing, coverage)
+		} else {
+			policies[coverage.Name] = &coverage
+		}
+	}
+
+	report := CoverageReport{}
+	for _, coverage := range policies {
+		report.Policies = append(report.Policies, *coverage)
+	}
+	sort.Slice(report.Policies, func(i, j int) bool { return report.Policies[i].Name < report.Policies[j].Name })
+	return report, nil
+}
+
+// Unexercised returns the validations that never denied an object, per policy
+func (r CoverageReport) Unexercised() map[string][]ValidationCoverage {
+	unexercised := map[string][]ValidationCoverage{}
+	for _, coverage := range r.Policies {
+		for _, validation := range coverage.Validations {
+			if !validation.Exercised() {
+				unexercised[coverage.Name] = append(unexercised[coverage.Name], validation)
+			}
+		}
+	}
+	return unexercised
+}
+
+// WriteText writes the report as a table with one line per validation, followed by the list of the validations
+// that are never exercised
+func (r CoverageReport) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "POLICY\tVALIDATION\tEVALUATED\tALLOWED\tDENIED\tERRORS\tDENIED KINDS")
+	for _, coverage := range r.Policies {
+		for _, v := range coverage.Validations {
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%d\t%s\n", coverage.Name, v.Index, v.Evaluated, v.Allowed, v.Denied, v.Errors, formatKinds(v.DeniedKinds))
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	unexercised := r.Unexercised()
+	if len(unexercised) == 0 {
+		_, err := fmt.Fprintln(w, "\nEvery validation denied at least one test object.")
+		return err
+	}
+	fmt.Fprintln(w, "\nValidations that never denied a test object:")
+	for _, coverage := range r.Policies {
+		for _, v := range unexercised[coverage.Name] {
+			state := "never evaluated"
+			if v.Evaluated > 0 {
+				state = fmt.Sprintf("evaluated %d times", v.Evaluated)
+			}
+			fmt.Fprintf(w, "  %s validations[%d] (%s): %s\n", coverage.Name, v.Index, state, summarize(v))
+		}
+	}
+	return nil
+}
+
+// WriteJSON writes the report as JSON
+func (r CoverageReport) WriteJSON(w io.Writer) error {
+	return writeJSON(w, r)
+}
+
+// writeJSON writes the value as indented JSON, the CEL operators are not escaped
+func writeJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// copyPolicyCoverage returns a deep copy of the coverage
+func copyPolicyCoverage(coverage PolicyCoverage) PolicyCoverage {
+	validations := make([]ValidationCoverage, len(coverage.Validations))
+	for i, v := range coverage.Validations {
+		kinds := map[string]int{}
+		for kind, n := range v.DeniedKinds {
+			kinds[kind] = n
+		}
+		v.DeniedKinds = kinds
+		validations[i] = v
+	}
+	coverage.Validations = validations
+	return coverage
+}
+
+// mergePolicyCoverage adds the counts of other to the coverage, the validations are matched by their expression
+func mergePolicyCoverage(coverage *PolicyCoverage, other PolicyCoverage) {
+	coverage.Requests += other.Requests
+	coverage.NotMatched += other.NotMatched
+	for _, o := range other.Validations {
+		merged := false
+		for i := range coverage.Validations {
+			v := &coverage.Validations[i]
+			if v.Index != o.Index || v.Expression != o.Expression {
+				continue
+			}
+			v.Evaluated += o.Evaluated
+			v.Allowed += o.Allowed
+			v.Denied += o.Denied
+			v.Errors += o.Errors
+			if v.DeniedKinds == nil {
+				v.DeniedKinds = map[string]int{}
+			}
+			for kind, n := range o.DeniedKinds {
+				v.DeniedKinds[kind] += n
+			}
+			merged = true
+		}
+		if !merged {
+			coverage.Validations = append(coverage.Validations, o)
+		}
+	}
+}
+
+// formatKinds returns the counts per kind sorted by kind, e.g. CronJob=2,Pod=5
+func formatKinds(kinds map[string]int) string {
+	names := make([]string, 0, len(kinds))
+	for kind := range kinds {
+		names = append(names, kind)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, len(names))
+	for i, kind := range names {
+		parts[i] = fmt.Sprintf("%s=%d", kind, kinds[kind])
+	}
+	return strings.Join(parts, ",")
+}
+
+// summarize returns the message of the validation, or the beginning of its expression, on one line
+func summarize(v ValidationCoverage) string {
+	text := v.Message
+	if text == "" {
+		text = v.Expression
+	}
+	text = strings.Join(strings.Fields(text), " ")
+	if len(text) > 160 {
+		text = text[:157] + "..."
+	}
+	return text
+}
@@ -0,0 +1,480 @@
+package testutils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apiextensions-apiserver/pkg/apiserver/validation"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/e2e-framework/klient/decoder"
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+	"sigs.k8s.io/e2e-framework/pkg/env"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/features"
+)
+
+// ValidateCustomResource validates the object against the OpenAPI schema of its version in the matching
+// CustomResourceDefinition among crds, like the API server does on create and update (the x-kubernetes-validations
+// rules are not evaluated). It returns an error if no CustomResourceDefinition serves the kind and version of the
+// object or if the object is invalid, the error lists every invalid field.
+func ValidateCustomResource(crds []k8s.Object, obj k8s.Object) error {
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	crd, err := crdForKind(crds, gvk.GroupKind())
+	if err != nil {
+		return err
+	}
+	if crd == nil {
+		return fmt.Errorf("no CustomResourceDefinition found for %s", gvk.GroupKind())
+	}
+	errs, err := validateCustomResource(crd, obj)
+	if err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s %s is invalid: %w", gvk.Kind, obj.GetName(), errs.ToAggregate())
+	}
+	return nil
+}
+
+// crdForKind returns the CustomResourceDefinition of the kind among the objects, or nil if there is none
+func crdForKind(objects []k8s.Object, gk schema.GroupKind) (*apiextensionsv1.CustomResourceDefinition, error) {
+	for _, obj := range objects {
+		if obj.GetObjectKind().GroupVersionKind().Kind != "CustomResourceDefinition" {
+			continue
+		}
+		crd := &apiextensionsv1.CustomResourceDefinition{}
+		if err := toTyped(obj, crd); err != nil {
+			return nil, err
+		}
+		if crd.Spec.Group == gk.Group && crd.Spec.Names.Kind == gk.Kind {
+			return crd, nil
+		}
+	}
+	return nil, nil
+}
+
+// validateCustomResource validates the object against the schema of its version in the CustomResourceDefinition
+func validateCustomResource(crd *apiextensionsv1.CustomResourceDefinition, obj k8s.Object) (field.ErrorList, error) {
+	openAPISchema, err := crdVersionSchema(crd, obj.GetObjectKind().GroupVersionKind().Version)
+	if err != nil || openAPISchema == nil {
+		return nil, err
+	}
+
+	internal := &apiextensions.JSONSchemaProps{}
+	if err := apiextensionsv1.Convert_v1_JSONSchemaProps_To_apiextensions_JSONSchemaProps(openAPISchema, internal, nil); err != nil {
+		return nil, err
+	}
+	validator, _, err := validation.NewSchemaValidator(internal)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema of CustomResourceDefinition %s: %w", crd.Name, err)
+	}
+	u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	return validation.ValidateCustomResource(nil, u, validator), nil
+}
+
+// crdVersionSchema returns the OpenAPI schema of the served version of the CustomResourceDefinition, it is nil if the
+// version has no schema
+func crdVersionSchema(crd *apiextensionsv1.CustomResourceDefinition, version string) (*apiextensionsv1.JSONSchemaProps, error) {
+	for _, v := range crd.Spec.Versions {
+		if v.Name != version || !v.Served {
+			continue
+		}
+		if v.Schema == nil {
+			return nil, nil
+		}
+		return v.Schema.OpenAPIV3Schema, nil
+	}
+	return nil, fmt.Errorf("CustomResourceDefinition %s does not serve version %s", crd.Name, version)
+}
+
+// ExpectInvalid fails the test unless err is a rejection by the schema validation of the API server (the Invalid
+// reason, not a ValidatingAdmissionPolicy) that reports the given field, e.g. "spec.allowedTypes[0]"
+func ExpectInvalid(t *testing.T, err error, fieldPath string) {
+	t.Helper()
+	testReport.recordRequest(t, ResultInvalid, err)
+
+	if err == nil {
+		t.Fatalf("expected the request to be rejected as invalid for field %s but it was allowed", fieldPath)
+	}
+	if denial, parseErr := ParseDenial(err); parseErr == nil {
+		t.Fatalf("expected the request to be rejected by the schema validation but it was denied by %s: %s", denial.Policy, err)
+	}
+	if !apierrors.IsInvalid(err) {
+		t.Fatalf("expected the request to be rejected as invalid but the error was: %s", err)
+	}
+	if !strings.Contains(err.Error(), fieldPath+":") {
+		t.Fatalf("expected the error to report the field %s: %s", fieldPath, err)
+	}
+}
+
+// InvalidParameter is a parameter that the schema of its CustomResourceDefinition must reject, see InvalidParameters
+type InvalidParameter struct {
+	// Description says how the parameter violates the schema
+	Description string
+	// Field is the path of the invalid field that the API server reports, e.g. spec.allowedTypes[0]
+	Field string
+	// Object is the invalid parameter
+	Object k8s.Object
+}
+
+// InvalidParameters derives an invalid parameter from the valid ones for every constraint of the schema of the
+// CustomResourceDefinition: a missing required field, a value that is not in the enum, a list that is shorter than
+// minItems or longer than maxItems, a string that is shorter than minLength, longer than maxLength or does not match
+// the pattern, and a number below the minimum or above the maximum. Only the fields that a valid parameter sets can be
+// changed, so it returns an error that lists the constraints that none of the valid parameters covers. It also returns
+// an error if a valid parameter is not valid.
+func InvalidParameters(crd k8s.Object, valid ...k8s.Object) ([]InvalidParameter, error) {
+	typed := &apiextensionsv1.CustomResourceDefinition{}
+	if err := toTyped(crd, typed); err != nil {
+		return nil, err
+	}
+
+	g := &invalidParameterGenerator{covered: map[string]bool{}}
+	for _, obj := range valid {
+		errs, err := validateCustomResource(typed, obj)
+		if err != nil {
+			return nil, err
+		}
+		if len(errs) > 0 {
+			return nil, fmt.Errorf("the valid parameter %s is invalid: %w", obj.GetName(), errs.ToAggregate())
+		}
+		openAPISchema, err := crdVersionSchema(typed, obj.GetObjectKind().GroupVersionKind().Version)
+		if err != nil || openAPISchema == nil {
+			return nil, err
+		}
+
+		u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			return nil, err
+		}
+		g.root = u
+		g.walkProperties(openAPISchema, u, nil, "")
+	}
+
+	var uncovered []string
+	for _, v := range typed.Spec.Versions {
+		if v.Served && v.Schema != nil && v.Schema.OpenAPIV3Schema != nil {
+			for _, constraint := range schemaConstraints(v.Schema.OpenAPIV3Schema, "") {
+				if !g.covered[constraint] {
+					uncovered = append(uncovered, constraint)
+				}
+			}
+		}
+	}
+	if len(uncovered) > 0 {
+		sort.Strings(uncovered)
+		return nil, fmt.Errorf("no valid parameter covers these constraints of CustomResourceDefinition %s: %s", typed.Name, strings.Join(uncovered, ", "))
+	}
+
+	// the generated parameters have to be rejected for the expected field
+	for _, p := range g.invalid {
+		errs, err := validateCustomResource(typed, p.Object)
+		if err != nil {
+			return nil, err
+		}
+		if len(errs) == 0 || !strings.Contains(errs.ToAggregate().Error(), p.Field+":") {
+			return nil, fmt.Errorf("the parameter with %s is not rejected for %s: %v", p.Description, p.Field, errs.ToAggregate())
+		}
+	}
+	return g.invalid, nil
+}
+
+// invalidParameterGenerator walks a valid parameter along its schema and derives the invalid parameters, one for
+// every constraint
+type invalidParameterGenerator struct {
+	root    map[string]interface{}
+	covered map[string]bool
+	invalid []InvalidParameter
+}
+
+// pathSegment is a field name or a list index
+type pathSegment interface{}
+
+// walkProperties walks the properties of an object value, the metadata is validated by the API server separately
+func (g *invalidParameterGenerator) walkProperties(s *apiextensionsv1.JSONSchemaProps, value map[string]interface{}, path []pathSegment, constraintPath string) {
+	for _, required := range s.Required {
+		if _, ok := value[required]; ok {
+			g.add(joinPath(constraintPath, required)+" (required)", "a missing required field "+fieldPath(append(path, required)),
+				fieldPath(append(path, required)), func(root map[string]interface{}) {
+					delete(lookup(root, path).(map[string]interface{}), required)
+				})
+		}
+	}
+	for name, property := range s.Properties {
+		if path == nil && (name == "metadata" || name == "apiVersion" || name == "kind") {
+			continue
+		}
+		if child, ok := value[name]; ok {
+			property := property
+			g.walk(&property, child, append(append([]pathSegment(nil), path...), name), joinPath(constraintPath, name))
+		}
+	}
+}
+
+// walk derives the invalid parameters of a value and its children
+func (g *invalidParameterGenerator) walk(s *apiextensionsv1.JSONSchemaProps, value interface{}, path []pathSegment, constraintPath string) {
+	p := fieldPath(path)
+	switch v := value.(type) {
+	case map[string]interface{}:
+		g.walkProperties(s, v, path, constraintPath)
+	case []interface{}:
+		if s.MinItems != nil && *s.MinItems > 0 {
+			minItems := int(*s.MinItems)
+			g.add(constraintPath+" (minItems)", fmt.Sprintf("%s with less than %d items", p, minItems), p, func(root map[string]interface{}) {
+				list := lookup(root, path).([]interface{})
+				set(root, path, list[:min(len(list), minItems-1)])
+			})
+		}
+		if s.MaxItems != nil && len(v) > 0 {
+			maxItems := int(*s.MaxItems)
+			g.add(constraintPath+" (maxItems)", fmt.Sprintf("%s with more than %d items", p, maxItems), p, func(root map[string]interface{}) {
+				list := lookup(root, path).([]interface{})
+				for len(list) <= maxItems {
+					list = append(list, runtime.DeepCopyJSONValue(list[0]))
+				}
+				set(root, path, list)
+			})
+		}
+		if s.Items != nil && s.Items.Schema != nil {
+			for i, item := range v {
+				g.walk(s.Items.Schema, item, append(append([]pathSegment(nil), path...), i), constraintPath+"[*]")
+			}
+		}
+	case string:
+		if len(s.Enum) > 0 {
+			g.add(constraintPath+" (enum)", p+" with a value that is not in the enum", p, func(root map[string]interface{}) {
+				set(root, path, "not-a-supported-value")
+			})
+		}
+		if s.MinLength != nil && *s.MinLength > 0 {
+			g.add(constraintPath+" (minLength)", fmt.Sprintf("%s shorter than %d characters", p, *s.MinLength), p, func(root map[string]interface{}) {
+				set(root, path, strings.Repeat("a", int(*s.MinLength)-1))
+			})
+		}
+		if s.MaxLength != nil {
+			g.add(constraintPath+" (maxLength)", fmt.Sprintf("%s longer than %d characters", p, *s.MaxLength), p, func(root map[string]interface{}) {
+				set(root, path, strings.Repeat("a", int(*s.MaxLength)+1))
+			})
+		}
+		if s.Pattern != "" {
+			if invalid, ok := stringNotMatching(s.Pattern); ok {
+				g.add(constraintPath+" (pattern)", p+" that does not match the pattern", p, func(root map[string]interface{}) {
+					set(root, path, invalid)
+				})
+			}
+		}
+	case int64, float64:
+		if s.Minimum != nil {
+			g.add(constraintPath+" (minimum)", fmt.Sprintf("%s below %v", p, *s.Minimum), p, func(root map[string]interface{}) {
+				set(root, path, int64(*s.Minimum)-1)
+			})
+		}
+		if s.Maximum != nil {
+			g.add(constraintPath+" (maximum)", fmt.Sprintf("%s above %v", p, *s.Maximum), p, func(root map[string]interface{}) {
+				set(root, path, int64(*s.Maximum)+1)
+			})
+		}
+	}
+}
+
+// add derives an invalid parameter from the valid one for the constraint, unless it is already covered
+func (g *invalidParameterGenerator) add(constraint string, description string, fieldPath string, mutate func(root map[string]interface{})) {
+	if g.covered[constraint] {
+		return
+	}
+	g.covered[constraint] = true
+
+	root := runtime.DeepCopyJSON(g.root)
+	mutate(root)
+	g.invalid = append(g.invalid, InvalidParameter{
+		Description: description,
+		Field:       fieldPath,
+		Object:      &unstructured.Unstructured{Object: root},
+	})
+}
+
+// schemaConstraints returns the constraints of the schema, in the same format as the invalid parameter generator
+func schemaConstraints(s *apiextensionsv1.JSONSchemaProps, constraintPath string) []string {
+	var constraints []string
+	for _, required := range s.Required {
+		constraints = append(constraints, joinPath(constraintPath, required)+" (required)")
+	}
+	for name, property := range s.Properties {
+		if constraintPath == "" && (name == "metadata" || name == "apiVersion" || name == "kind") {
+			continue
+		}
+		property := property
+		constraints = append(constraints, schemaConstraints(&property, joinPath(constraintPath, name))...)
+	}
+	if s.Items != nil && s.Items.Schema != nil {
+		constraints = append(constraints, schemaConstraints(s.Items.Schema, constraintPath+"[*]")...)
+	}
+
+	keywords := []struct {
+		name string
+		set  bool
+	}{
+		{"minItems", s.MinItems != nil && *s.MinItems > 0},
+		{"maxItems", s.MaxItems != nil},
+		{"enum", len(s.Enum) > 0 && s.Type == "string"},
+		{"minLength", s.MinLength != nil && *s.MinLength > 0},
+		{"maxLength", s.MaxLength != nil},
+		{"pattern", s.Pattern != "" && stringNotMatchingExists(s.Pattern)},
+		{"minimum", s.Minimum != nil},
+		{"maximum", s.Maximum != nil},
+	}
+	for _, keyword := range keywords {
+		if keyword.set {
+			constraints = append(constraints, fmt.Sprintf("%s (%s)", constraintPath, keyword.name))
+		}
+	}
+	return constraints
+}
+
+// stringNotMatching returns a string that does not match the pattern
+func stringNotMatching(pattern string) (string, bool) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", false
+	}
+	for _, candidate := range []string{"Not A Valid Value!", "-", "0", ""} {
+		if !re.MatchString(candidate) {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+func stringNotMatchingExists(pattern string) bool {
+	_, ok := stringNotMatching(pattern)
+	return ok
+}
+
+// fieldPath returns the path of a field like the API server reports it, e.g. spec.allowedParentRefs[0].name
+func fieldPath(path []pathSegment) string {
+	var b strings.Builder
+	for _, segment := range path {
+		switch s := segment.(type) {
+		case int:
+			fmt.Fprintf(&b, "[%d]", s)
+		case string:
+			if b.Len() > 0 {
+				b.WriteString(".")
+			}
+			b.WriteString(s)
+		}
+	}
+	return b.String()
+}
+
+func joinPath(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// lookup returns the value at the path
+func lookup(root map[string]interface{}, path []pathSegment) interface{} {
+	var value interface{} = root
+	for _, segment := range path {
+		switch s := segment.(type) {
+		case int:
+			value = value.([]interface{})[s]
+		case string:
+			value = value.(map[string]interface{})[s]
+		}
+	}
+	return value
+}
+
+// set replaces the value at the path
+func set(root map[string]interface{}, path []pathSegment, value interface{}) {
+	parent := lookup(root, path[:len(path)-1])
+	switch s := path[len(path)-1].(type) {
+	case int:
+		parent.([]interface{})[s] = value
+	case string:
+		parent.(map[string]interface{})[s] = value
+	}
+}
+
+// RunParameterSchemaTests checks the schema of the parameter CustomResourceDefinition in crdFile: the valid
+// parameters must be admitted and the invalid parameters that InvalidParameters derives from them must be rejected
+// for the invalid field. The parameters are YAML with a %s placeholder for the namespace, like the other test objects,
+// and every request is sent with server-side dry-run.
+func RunParameterSchemaTests(t *testing.T, testEnv env.Environment, crdFile string, validParameterYAMLs []string) {
+	t.Helper()
+
+	data, err := os.ReadFile(crdFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	crd, err := decoder.DecodeAny(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the parameters are generated with a placeholder namespace, the namespace of the test is set when they are applied
+	var valid []k8s.Object
+	for _, yaml := range validParameterYAMLs {
+		obj, err := decoder.DecodeAny(strings.NewReader(fmt.Sprintf(yaml, "placeholder")))
+		if err != nil {
+			t.Fatal(err)
+		}
+		valid = append(valid, obj)
+	}
+	invalid, err := InvalidParameters(crd, valid...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := features.New(fmt.Sprintf("Schema of %s", crd.GetName()))
+	for i, obj := range valid {
+		spec, err := json.Marshal(obj.(*unstructured.Unstructured).Object["spec"])
+		if err != nil {
+			t.Fatal(err)
+		}
+		f = f.Assess(fmt.Sprintf("Successful deployment of valid parameter %d: %s", i+1, spec), func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			err := applyParameterForSchemaTest(ctx, t, cfg, obj)
+			ExpectAllowed(t, err)
+
+			return ctx
+		})
+	}
+	for _, p := range invalid {
+		f = f.Assess(fmt.Sprintf("Rejected deployment of a parameter with %s", p.Description), func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			err := applyParameterForSchemaTest(ctx, t, cfg, p.Object)
+			ExpectInvalid(t, err, p.Field)
+
+			return ctx
+		})
+	}
+
+	testEnv.Test(t, f.Feature())
+}
+
+// applyParameterForSchemaTest applies a copy of the parameter in the namespace of the test with server-side dry-run
+func applyParameterForSchemaTest(ctx context.Context, t *testing.T, cfg *envconf.Config, obj k8s.Object) error {
+	namespace := ctx.Value(GetNamespaceKey(t)).(string)
+
+	param := obj.DeepCopyObject().(k8s.Object)
+	param.SetNamespace(namespace)
+	return applyK8sResource(ctx, cfg, param, WithDryRun)
+}
@@ -0,0 +1,467 @@
+package testutils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/pod-security-admission/policy"
+	"sigs.k8s.io/e2e-framework/pkg/env"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/features"
+	sigsyaml "sigs.k8s.io/yaml"
+)
+
+const (
+	// DifferentialCasesEnvVar is the number of random PodSpecs that are generated on top of the enumerated ones
+	DifferentialCasesEnvVar = "VAPLIB_DIFFERENTIAL_CASES"
+	// DifferentialSeedEnvVar is the seed of the random PodSpecs
+	DifferentialSeedEnvVar = "VAPLIB_DIFFERENTIAL_SEED"
+
+	defaultDifferentialCases = 200
+	defaultDifferentialSeed  = 1
+)
+
+// PSSCheckIDs maps the pss-* policies to the checks of the restricted profile of k8s.io/pod-security-admission that
+// they implement
+var PSSCheckIDs = map[string]policy.CheckID{
+	"pss-capabilities":             "capabilities_restricted",
+	"pss-seccomp":                  "seccompProfile_restricted",
+	"pss-privilege-escalation":     "allowPrivilegeEscalation",
+	"pss-running-as-non-root":      "runAsNonRoot",
+	"pss-running-as-non-root-user": "runAsUser",
+	"pss-volume-types":             "restrictedVolumes",
+}
+
+// GeneratedPodSpec is a PodSpec of the differential tests
+type GeneratedPodSpec struct {
+	// Name describes the values of the PodSpec
+	Name string
+	// Spec is the PodSpec
+	Spec corev1.PodSpec
+}
+
+// KnownDifference is a documented disagreement between a policy and pod-security-admission. The PodSpecs it matches
+// are reported in the log instead of failing the test.
+type KnownDifference struct {
+	// Reason explains why the policy behaves differently
+	Reason string
+	// Matches returns true for the PodSpecs of the difference
+	Matches func(spec *corev1.PodSpec) bool
+}
+
+// KnownDifferenceWindows is the difference of the policies that do not exempt Windows Pods from the checks that the
+// restricted profile only applies to Linux
+var KnownDifferenceWindows = KnownDifference{
+	Reason: "the policy does not exempt Pods with spec.os.name windows",
+	Matches: func(spec *corev1.PodSpec) bool {
+		return spec.OS != nil && spec.OS.Name == corev1.Windows
+	},
+}
+
+// podSpecValue is a value of a field of a PodSpec
+type podSpecValue struct {
+	name  string
+	apply func(spec *corev1.PodSpec)
+}
+
+// podSpecDimension is a field of a PodSpec with the values that the generator tries
+type podSpecDimension struct {
+	name   string
+	values []podSpecValue
+}
+
+// RunDifferentialTests generates PodSpecs for the check of the restricted profile that the policy implements (see
+// PSSCheckIDs), sends a Pod with each of them through the policy and compares the result with the check of
+// k8s.io/pod-security-admission. Every disagreement that is not a known difference fails the test. With the in-process
+// backend no cluster is needed.
+func RunDifferentialTests(t *testing.T, testEnv env.Environment, policyName string, known ...KnownDifference) {
+	checkID, ok := PSSCheckIDs[policyName]
+	if !ok {
+		t.Fatalf("policy %s does not implement a check of the restricted profile", policyName)
+	}
+	check, err := podSecurityCheck(checkID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	random, seed, err := differentialSettings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	podSpecs := GeneratePodSpecs(checkID, random, seed)
+
+	f := features.New(fmt.Sprintf("Differential tests against the %s check", checkID)).
+		Assess("The policy agrees with pod-security-admission", func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// get namespace
+			namespace := ctx.Value(GetNamespaceKey(t)).(string)
+
+			t.Logf("comparing %d PodSpecs (%d random with seed %d)", len(podSpecs), random, seed)
+			disagreements := 0
+			knownDisagreements := map[string]int{}
+			invalid := 0
+			for i, podSpec := range podSpecs {
+				pod := &corev1.Pod{
+					TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+					ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("differential-%d", i), Namespace: namespace},
+					Spec:       podSpec.Spec,
+				}
+				expected := check(&pod.ObjectMeta, &pod.Spec)
+
+				err := applyK8sResource(ctx, cfg, pod, testObjectOptions(nil)...)
+				allowed := err == nil
+				if err != nil {
+					denial, parseErr := ParseDenial(err)
+					if parseErr != nil && apierrors.IsInvalid(err) {
+						// the API server validates the Pod before the policies, e.g. Windows Pods cannot set
+						// Linux only fields, so the policy never sees such a Pod
+						invalid++
+						continue
+					}
+					if parseErr != nil || denial.Policy != policyName+policyNameSuffix {
+						t.Errorf("%s: the Pod was rejected for another reason: %s", podSpec.Name, err)
+						continue
+					}
+				}
+
+				if allowed != expected.Allowed {
+					if difference := matchKnownDifference(known, &pod.Spec); difference != nil {
+						knownDisagreements[difference.Reason]++
+						continue
+					}
+					disagreements++
+					spec, _ := sigsyaml.Marshal(podSpec.Spec)
+					t.Errorf("%s: the policy %s the Pod but pod-security-admission %s it (%s: %s)\n%s",
+						podSpec.Name, verdict(allowed), verdict(expected.Allowed), expected.ForbiddenReason, expected.ForbiddenDetail, spec)
+				}
+			}
+			if invalid > 0 {
+				t.Logf("%d PodSpecs were skipped because the API server rejected them as invalid", invalid)
+			}
+			for _, difference := range known {
+				t.Logf("%d PodSpecs disagree because %s", knownDisagreements[difference.Reason], difference.Reason)
+			}
+			if disagreements > 0 {
+				t.Errorf("%d of %d PodSpecs disagree, rerun with %s=%d to reproduce the random ones", disagreements, len(podSpecs), DifferentialSeedEnvVar, seed)
+			}
+
+			return ctx
+		}).
+		Feature()
+
+	_ = testEnv.Test(t, f)
+}
+
+// GeneratePodSpecs returns every combination of the values of the fields that the check looks at, followed by random
+// PodSpecs that combine the values of all the fields. PodSpecs that are the same are only returned once.
+func GeneratePodSpecs(checkID policy.CheckID, random int, seed int64) []GeneratedPodSpec {
+	dimensions := podSpecDimensions()
+
+	var relevant []podSpecDimension
+	for _, d := range dimensions {
+		for _, name := range checkDimensions[checkID] {
+			if d.name == name {
+				relevant = append(relevant, d)
+			}
+		}
+	}
+
+	seen := map[string]bool{}
+	var result []GeneratedPodSpec
+	add := func(values []podSpecValue) {
+		podSpec := GeneratedPodSpec{Spec: basePodSpec()}
+		for _, v := range values {
+			v.apply(&podSpec.Spec)
+			if podSpec.Name != "" {
+				podSpec.Name += ", "
+			}
+			podSpec.Name += v.name
+		}
+		key, _ := json.Marshal(podSpec.Spec)
+		if seen[string(key)] {
+			return
+		}
+		seen[string(key)] = true
+		result = append(result, podSpec)
+	}
+
+	// enumerate all the combinations of the relevant fields
+	indexes := make([]int, len(relevant))
+	for {
+		values := make([]podSpecValue, len(relevant))
+		for i, d := range relevant {
+			values[i] = d.values[indexes[i]]
+		}
+		add(values)
+
+		i := len(indexes) - 1
+		for ; i >= 0; i-- {
+			indexes[i]++
+			if indexes[i] < len(relevant[i].values) {
+				break
+			}
+			indexes[i] = 0
+		}
+		if i < 0 {
+			break
+		}
+	}
+
+	// combine all the fields randomly
+	rnd := rand.New(rand.NewSource(seed))
+	for n := 0; n < random; n++ {
+		values := make([]podSpecValue, len(dimensions))
+		for i, d := range dimensions {
+			values[i] = d.values[rnd.Intn(len(d.values))]
+		}
+		add(values)
+	}
+
+	return result
+}
+
+// checkDimensions are the fields of the PodSpec that the checks look at
+var checkDimensions = map[policy.CheckID][]string{
+	"capabilities_restricted":   {"os", "initContainer", "container.drop", "container.add", "initContainer.drop", "initContainer.add"},
+	"seccompProfile_restricted": {"os", "initContainer", "pod.seccompProfile", "container.seccompProfile", "initContainer.seccompProfile"},
+	"allowPrivilegeEscalation":  {"os", "initContainer", "container.allowPrivilegeEscalation", "initContainer.allowPrivilegeEscalation"},
+	"runAsNonRoot":              {"os", "initContainer", "pod.runAsNonRoot", "container.runAsNonRoot", "initContainer.runAsNonRoot"},
+	"runAsUser":                 {"os", "initContainer", "pod.runAsUser", "container.runAsUser", "initContainer.runAsUser"},
+	"restrictedVolumes":         {"os", "volume", "secondVolume"},
+}
+
+// basePodSpec returns the PodSpec that the generated values are applied to
+func basePodSpec() corev1.PodSpec {
+	return corev1.PodSpec{
+		Containers: []corev1.Container{{Name: "app", Image: "public.ecr.aws/docker/library/busybox:1.36"}},
+	}
+}
+
+// podSpecDimensions returns the fields of a PodSpec that the generator changes, in the order they are applied
+func podSpecDimensions() []podSpecDimension {
+	dimensions := []podSpecDimension{
+		{name: "os", values: []podSpecValue{
+			{name: "os unset", apply: func(spec *corev1.PodSpec) {}},
+			{name: "os linux", apply: func(spec *corev1.PodSpec) { spec.OS = &corev1.PodOS{Name: corev1.Linux} }},
+			{name: "os windows", apply: func(spec *corev1.PodSpec) { spec.OS = &corev1.PodOS{Name: corev1.Windows} }},
+		}},
+		{name: "initContainer", values: []podSpecValue{
+			{name: "no initContainer", apply: func(spec *corev1.PodSpec) {}},
+			{name: "initContainer", apply: func(spec *corev1.PodSpec) {
+				spec.InitContainers = []corev1.Container{{Name: "init", Image: "public.ecr.aws/docker/library/busybox:1.36"}}
+			}},
+		}},
+		{name: "pod.runAsNonRoot", values: boolValues("pod.runAsNonRoot", func(spec *corev1.PodSpec, v *bool) {
+			podSecurityContext(spec).RunAsNonRoot = v
+		})},
+		{name: "pod.runAsUser", values: userValues("pod.runAsUser", func(spec *corev1.PodSpec, v *int64) {
+			podSecurityContext(spec).RunAsUser = v
+		})},
+		{name: "pod.seccompProfile", values: seccompValues("pod.seccompProfile", func(spec *corev1.PodSpec, v *corev1.SeccompProfile) {
+			podSecurityContext(spec).SeccompProfile = v
+		})},
+	}
+
+	for _, target := range []string{"container", "initContainer"} {
+		containers := func(spec *corev1.PodSpec) []corev1.Container {
+			if target == "container" {
+				return spec.Containers
+			}
+			return spec.InitContainers
+		}
+		forEach := func(apply func(sc *corev1.SecurityContext)) func(spec *corev1.PodSpec) {
+			return func(spec *corev1.PodSpec) {
+				for i := range containers(spec) {
+					apply(securityContext(&containers(spec)[i]))
+				}
+			}
+		}
+
+		dimensions = append(dimensions,
+			podSpecDimension{name: target + ".allowPrivilegeEscalation", values: boolValues(target+".allowPrivilegeEscalation", func(spec *corev1.PodSpec, v *bool) {
+				forEach(func(sc *corev1.SecurityContext) { sc.AllowPrivilegeEscalation = v })(spec)
+			})},
+			podSpecDimension{name: target + ".runAsNonRoot", values: boolValues(target+".runAsNonRoot", func(spec *corev1.PodSpec, v *bool) {
+				forEach(func(sc *corev1.SecurityContext) { sc.RunAsNonRoot = v })(spec)
+			})},
+			podSpecDimension{name: target + ".runAsUser", values: userValues(target+".runAsUser", func(spec *corev1.PodSpec, v *int64) {
+				forEach(func(sc *corev1.SecurityContext) { sc.RunAsUser = v })(spec)
+			})},
+			podSpecDimension{name: target + ".seccompProfile", values: seccompValues(target+".seccompProfile", func(spec *corev1.PodSpec, v *corev1.SeccompProfile) {
+				forEach(func(sc *corev1.SecurityContext) { sc.SeccompProfile = v })(spec)
+			})},
+			podSpecDimension{name: target + ".drop", values: capabilityValues(target+".drop", [][]corev1.Capability{{"ALL"}, {"NET_RAW"}, {"ALL", "NET_RAW"}}, func(spec *corev1.PodSpec, v []corev1.Capability) {
+				forEach(func(sc *corev1.SecurityContext) { capabilities(sc).Drop = v })(spec)
+			})},
+			podSpecDimension{name: target + ".add", values: capabilityValues(target+".add", [][]corev1.Capability{{"NET_BIND_SERVICE"}, {"NET_RAW"}, {"NET_BIND_SERVICE", "CHOWN"}}, func(spec *corev1.PodSpec, v []corev1.Capability) {
+				forEach(func(sc *corev1.SecurityContext) { capabilities(sc).Add = v })(spec)
+			})},
+		)
+	}
+
+	for _, name := range []string{"volume", "secondVolume"} {
+		dimension := podSpecDimension{name: name, values: []podSpecValue{{name: "no " + name, apply: func(spec *corev1.PodSpec) {}}}}
+		for _, source := range volumeSources() {
+			dimension.values = append(dimension.values, podSpecValue{name: name + " " + source.name, apply: func(spec *corev1.PodSpec) {
+				spec.Volumes = append(spec.Volumes, corev1.Volume{Name: fmt.Sprintf("volume-%d", len(spec.Volumes)), VolumeSource: source.source})
+			}})
+		}
+		dimensions = append(dimensions, dimension)
+	}
+
+	return dimensions
+}
+
+// volumeSources returns volume sources that the restricted profile allows and some that it forbids
+func volumeSources() []struct {
+	name   string
+	source corev1.VolumeSource
+} {
+	storage := "scratch-storage-class"
+	return []struct {
+		name   string
+		source corev1.VolumeSource
+	}{
+		{"configMap", corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: "config"}}}},
+		{"csi", corev1.VolumeSource{CSI: &corev1.CSIVolumeSource{Driver: "example"}}},
+		{"downwardAPI", corev1.VolumeSource{DownwardAPI: &corev1.DownwardAPIVolumeSource{}}},
+		{"emptyDir", corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
+		{"ephemeral", corev1.VolumeSource{Ephemeral: &corev1.EphemeralVolumeSource{VolumeClaimTemplate: &corev1.PersistentVolumeClaimTemplate{
+			Spec: corev1.PersistentVolumeClaimSpec{AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}, StorageClassName: &storage},
+		}}}},
+		{"persistentVolumeClaim", corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "claim"}}},
+		{"projected", corev1.VolumeSource{Projected: &corev1.ProjectedVolumeSource{}}},
+		{"secret", corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "secret"}}},
+		{"hostPath", corev1.VolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: "/data"}}},
+		{"nfs", corev1.VolumeSource{NFS: &corev1.NFSVolumeSource{Server: "nfs.example.com", Path: "/data"}}},
+		{"iscsi", corev1.VolumeSource{ISCSI: &corev1.ISCSIVolumeSource{TargetPortal: "10.0.0.1:3260", IQN: "iqn.2001-04.com.example:storage", FSType: "ext4"}}},
+		{"image", corev1.VolumeSource{Image: &corev1.ImageVolumeSource{Reference: "public.ecr.aws/docker/library/busybox:1.36"}}},
+	}
+}
+
+// boolValues returns the values of an optional bool field: unset, true and false
+func boolValues(name string, set func(spec *corev1.PodSpec, v *bool)) []podSpecValue {
+	values := []podSpecValue{{name: name + " unset", apply: func(spec *corev1.PodSpec) {}}}
+	for _, b := range []bool{true, false} {
+		values = append(values, podSpecValue{name: fmt.Sprintf("%s %t", name, b), apply: func(spec *corev1.PodSpec) { set(spec, &b) }})
+	}
+	return values
+}
+
+// userValues returns the values of an optional user id field: unset, root and a non-root user
+func userValues(name string, set func(spec *corev1.PodSpec, v *int64)) []podSpecValue {
+	values := []podSpecValue{{name: name + " unset", apply: func(spec *corev1.PodSpec) {}}}
+	for _, u := range []int64{0, 1000} {
+		values = append(values, podSpecValue{name: fmt.Sprintf("%s %d", name, u), apply: func(spec *corev1.PodSpec) { set(spec, &u) }})
+	}
+	return values
+}
+
+// seccompValues returns the values of an optional seccomp profile: unset and every profile type
+func seccompValues(name string, set func(spec *corev1.PodSpec, v *corev1.SeccompProfile)) []podSpecValue {
+	localhost := "profiles/audit.json"
+	profiles := []corev1.SeccompProfile{
+		{Type: corev1.SeccompProfileTypeRuntimeDefault},
+		{Type: corev1.SeccompProfileTypeLocalhost, LocalhostProfile: &localhost},
+		{Type: corev1.SeccompProfileTypeUnconfined},
+	}
+
+	values := []podSpecValue{{name: name + " unset", apply: func(spec *corev1.PodSpec) {}}}
+	for _, p := range profiles {
+		values = append(values, podSpecValue{name: fmt.Sprintf("%s %s", name, p.Type), apply: func(spec *corev1.PodSpec) { set(spec, p.DeepCopy()) }})
+	}
+	return values
+}
+
+// capabilityValues returns the values of an optional list of capabilities: unset and the given lists
+func capabilityValues(name string, lists [][]corev1.Capability, set func(spec *corev1.PodSpec, v []corev1.Capability)) []podSpecValue {
+	values := []podSpecValue{{name: name + " unset", apply: func(spec *corev1.PodSpec) {}}}
+	for _, l := range lists {
+		values = append(values, podSpecValue{name: fmt.Sprintf("%s %v", name, l), apply: func(spec *corev1.PodSpec) {
+			set(spec, append([]corev1.Capability{}, l...))
+		}})
+	}
+	return values
+}
+
+// podSecurityContext returns the security context of the PodSpec and creates it if it is not set yet
+func podSecurityContext(spec *corev1.PodSpec) *corev1.PodSecurityContext {
+	if spec.SecurityContext == nil {
+		spec.SecurityContext = &corev1.PodSecurityContext{}
+	}
+	return spec.SecurityContext
+}
+
+// securityContext returns the security context of the container and creates it if it is not set yet
+func securityContext(container *corev1.Container) *corev1.SecurityContext {
+	if container.SecurityContext == nil {
+		container.SecurityContext = &corev1.SecurityContext{}
+	}
+	return container.SecurityContext
+}
+
+// capabilities returns the capabilities of the security context and creates them if they are not set yet
+func capabilities(sc *corev1.SecurityContext) *corev1.Capabilities {
+	if sc.Capabilities == nil {
+		sc.Capabilities = &corev1.Capabilities{}
+	}
+	return sc.Capabilities
+}
+
+// matchKnownDifference returns the first known difference that matches the PodSpec, or nil
+func matchKnownDifference(known []KnownDifference, spec *corev1.PodSpec) *KnownDifference {
+	for i := range known {
+		if known[i].Matches(spec) {
+			return &known[i]
+		}
+	}
+	return nil
+}
+
+// podSecurityCheck returns the latest version of the check of k8s.io/pod-security-admission
+func podSecurityCheck(id policy.CheckID) (policy.CheckPodFn, error) {
+	for _, check := range policy.DefaultChecks() {
+		if check.ID == id && len(check.Versions) > 0 {
+			return check.Versions[len(check.Versions)-1].CheckPod, nil
+		}
+	}
+	return nil, fmt.Errorf("pod-security-admission has no check %s", id)
+}
+
+// differentialSettings returns the number of random PodSpecs and their seed
+func differentialSettings() (int, int64, error) {
+	random := defaultDifferentialCases
+	if value := os.Getenv(DifferentialCasesEnvVar); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return 0, 0, fmt.Errorf("invalid %s %q", DifferentialCasesEnvVar, value)
+		}
+		random = n
+	}
+
+	seed := int64(defaultDifferentialSeed)
+	if value := os.Getenv(DifferentialSeedEnvVar); value != "" {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid %s %q", DifferentialSeedEnvVar, value)
+		}
+		seed = n
+	}
+
+	return random, seed, nil
+}
+
+// verdict returns allowed or denied
+func verdict(allowed bool) string {
+	if allowed {
+		return ResultAllowed
+	}
+	return ResultDenied
+}
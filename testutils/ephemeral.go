@@ -0,0 +1,85 @@
+package testutils
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/e2e-framework/klient/decoder"
+	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	sigsyaml "sigs.k8s.io/yaml"
+)
+
+// ephemeralContainersSubresource is the subresource of Pods that kubectl debug updates
+const ephemeralContainersSubresource = "ephemeralcontainers"
+
+// ApplyEphemeralContainerFromYAML creates the Pod from the pod yaml string unless it already exists, then adds the
+// ephemeral containers from the container yaml strings to it through the ephemeralcontainers subresource, like kubectl
+// debug does. The Pod must be compliant, the returned error is the result of the admission of the ephemeral containers.
+func ApplyEphemeralContainerFromYAML(ctx context.Context, cfg *envconf.Config, podYAML string, containerYAMLs ...string) error {
+	containers := make([]v1.EphemeralContainer, len(containerYAMLs))
+	for i, containerYAML := range containerYAMLs {
+		if err := sigsyaml.UnmarshalStrict([]byte(containerYAML), &containers[i]); err != nil {
+			return fmt.Errorf("failed to decode the ephemeral container: %w", err)
+		}
+	}
+	return ApplyEphemeralContainer(ctx, cfg, podYAML, containers...)
+}
+
+// ApplyEphemeralContainer is like ApplyEphemeralContainerFromYAML with typed ephemeral containers
+func ApplyEphemeralContainer(ctx context.Context, cfg *envconf.Config, podYAML string, containers ...v1.EphemeralContainer) error {
+	obj, err := decoder.DecodeAny(strings.NewReader(podYAML))
+	if err != nil {
+		return err
+	}
+	pod := &v1.Pod{}
+	if err := toTyped(obj, pod); err != nil {
+		return err
+	}
+
+	// the Pod is persisted, the ephemeral containers are added to it
+	err = applyK8sResource(ctx, cfg, pod)
+	if err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create the pod %s: %w", pod.Name, err)
+	}
+
+	return UpdateEphemeralContainers(ctx, cfg, pod.Namespace, pod.Name, containers...)
+}
+
+// UpdateEphemeralContainers adds the ephemeral containers to an existing Pod through the ephemeralcontainers
+// subresource and returns the result of the admission. Like ApplyK8sResourceFromYAML, the request is sent with
+// server-side dry-run unless it is disabled with DryRunEnvVar, so the same Pod can be used for several containers.
+func UpdateEphemeralContainers(ctx context.Context, cfg *envconf.Config, namespace string, podName string, containers ...v1.EphemeralContainer) error {
+	var opts []resources.UpdateOption
+	if useDryRun() {
+		opts = append(opts, func(o *metav1.UpdateOptions) { o.DryRun = []string{metav1.DryRunAll} })
+	}
+
+	if evaluator := PolicyEvaluatorFromContext(ctx); evaluator != nil {
+		obj, err := evaluator.Get(v1.SchemeGroupVersion.WithKind("Pod"), namespace, podName)
+		if err != nil {
+			return err
+		}
+		pod := &v1.Pod{}
+		if err := toTyped(obj, pod); err != nil {
+			return err
+		}
+		pod.Spec.EphemeralContainers = append(pod.Spec.EphemeralContainers, containers...)
+		return evaluator.UpdateSubresource(ctx, pod, ephemeralContainersSubresource, opts...)
+	}
+
+	r, err := resources.New(cfg.Client().RESTConfig())
+	if err != nil {
+		return err
+	}
+	pod := &v1.Pod{}
+	if err := r.Get(ctx, podName, namespace, pod); err != nil {
+		return err
+	}
+	pod.Spec.EphemeralContainers = append(pod.Spec.EphemeralContainers, containers...)
+	return r.UpdateSubresource(ctx, pod, ephemeralContainersSubresource, opts...)
+}
@@ -0,0 +1,251 @@
+package testutils
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/e2e-framework/klient/decoder"
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+	"sigs.k8s.io/e2e-framework/pkg/env"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/features"
+	sigsyaml "sigs.k8s.io/yaml"
+)
+
+const (
+	// ResultAllowed means that the object is admitted
+	ResultAllowed = "allowed"
+	// ResultDenied means that the object is rejected
+	ResultDenied = "denied"
+	// ResultWarned means that the object is admitted with a warning
+	ResultWarned = "warned"
+	// ResultInvalid means that the object is rejected by the schema validation of the API server, e.g. a parameter that
+	// does not match the schema of its CustomResourceDefinition
+	ResultInvalid = "invalid"
+)
+
+// TestCase is a declarative policy test case. Test cases are stored as YAML documents in the `tests` directory of a
+// policy, every document is one test case:
+//
+//	name: A Service with invalid type is rejected
+//	namespaceLabels:
+//	  vap-library.com/service-type: deny
+//	parameter:
+//	  apiVersion: vap-library.com/v1beta1
+//	  kind: VAPLibServiceTypeParam
+//	  ...
+//	object:
+//	  apiVersion: v1
+//	  kind: Service
+//	  ...
+//	expect:
+//	  result: denied
+//	  message: spec.type must be present
+//
+// The namespace of the parameter and the object is set to the namespace of the test unless they define one.
+type TestCase struct {
+	// Name is the name of the test case, defaults to the file name and the index of the document
+	Name string `json:"name,omitempty"`
+	// NamespaceLabels are added to the labels of the test namespace before the parameter and the object are applied
+	NamespaceLabels map[string]string `json:"namespaceLabels,omitempty"`
+	// Parameter is an optional policy parameter that is applied before the object
+	Parameter map[string]interface{} `json:"parameter,omitempty"`
+	// Object is the object that is sent to the API server
+	Object map[string]interface{} `json:"object"`
+	// Expect is the expected outcome
+	Expect Expectation `json:"expect"`
+}
+
+// Expectation is the expected outcome of a TestCase
+type Expectation struct {
+	// Result is one of allowed, denied, warned or invalid
+	Result string `json:"result"`
+	// Message is a substring of the denial or warning message (optional), or the invalid field for invalid
+	Message string `json:"message,omitempty"`
+	// Policy is the name of the policy that denies the object, defaults to the name of the policy directory
+	Policy string `json:"policy,omitempty"`
+}
+
+// LoadTestCases loads all the test cases from the YAML files of the given directory
+func LoadTestCases(dir string) ([]TestCase, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	var testCases []TestCase
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		reader := yaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(content)))
+		for i := 1; ; i++ {
+			doc, err := reader.Read()
+			if errors.Is(err, io.EOF) {
+				break
+			} else if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", file, err)
+			}
+			if len(bytes.TrimSpace(doc)) == 0 {
+				continue
+			}
+
+			tc := TestCase{}
+			if err := sigsyaml.UnmarshalStrict(doc, &tc); err != nil {
+				return nil, fmt.Errorf("failed to decode test case %d of %s: %w", i, file, err)
+			}
+			if tc.Name == "" {
+				tc.Name = fmt.Sprintf("%s#%d", strings.TrimSuffix(filepath.Base(file), ".yaml"), i)
+			}
+			if err := tc.validate(); err != nil {
+				return nil, fmt.Errorf("invalid test case %q in %s: %w", tc.Name, file, err)
+			}
+			testCases = append(testCases, tc)
+		}
+	}
+
+	return testCases, nil
+}
+
+// RunTestCasesFromDir runs every test case of the given directory as a separate e2e feature. Every test case gets its
+// own namespace.
+func RunTestCasesFromDir(t *testing.T, testEnv env.Environment, dir string) {
+	testCases, err := LoadTestCases(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(testCases) == 0 {
+		t.Fatalf("no test cases found in %s", dir)
+	}
+
+	// every call of testEnv.Test creates a new namespace
+	for _, tc := range testCases {
+		_ = testEnv.Test(t, tc.Feature())
+	}
+}
+
+// Feature turns the test case into an e2e feature
+func (tc TestCase) Feature() features.Feature {
+	return features.New(tc.Name).
+		Setup(func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// get namespace
+			namespace := ctx.Value(GetNamespaceKey(t)).(string)
+
+			if len(tc.NamespaceLabels) > 0 {
+				if err := AddNamespaceLabels(ctx, cfg, namespace, tc.NamespaceLabels); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if tc.Parameter != nil {
+				param, err := decodeTestCaseObject(tc.Parameter, namespace)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := applyK8sResource(ctx, cfg, param); err != nil {
+					t.Fatal(err)
+				}
+
+				// wait for the parameter to be registered properly
+				timeout, err := ReadinessTimeout()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := WaitForParameter(ctx, cfg, param, timeout); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			return ctx
+		}).
+		Assess(fmt.Sprintf("The object is %s", tc.Expect.Result), func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// get namespace
+			namespace := ctx.Value(GetNamespaceKey(t)).(string)
+
+			obj, err := decodeTestCaseObject(tc.Object, namespace)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			warnings, err := applyK8sResourceWithWarnings(ctx, cfg, obj, testObjectOptions(nil)...)
+			expectResult(t, tc.Expect, err, warnings)
+
+			return ctx
+		}).
+		Feature()
+}
+
+// expectResult fails the test if the result of the request does not match the expectation
+func expectResult(t *testing.T, expect Expectation, err error, warnings []string) {
+	t.Helper()
+
+	switch expect.Result {
+	case ResultAllowed:
+		ExpectAllowed(t, err)
+	case ResultDenied:
+		policyName := expect.Policy
+		if policyName == "" {
+			// the tests run in the directory of the policy
+			wd, wdErr := os.Getwd()
+			if wdErr != nil {
+				t.Fatal(wdErr)
+			}
+			policyName = filepath.Base(wd)
+		}
+		ExpectDenied(t, err, policyName, expect.Message)
+	case ResultWarned:
+		ExpectAllowed(t, err)
+		ExpectWarned(t, warnings, expect.Message)
+	case ResultInvalid:
+		ExpectInvalid(t, err, expect.Message)
+	default:
+		t.Fatalf("unknown expected result %q", expect.Result)
+	}
+}
+
+// validate checks that the test case is complete
+func (tc TestCase) validate() error {
+	if tc.Object == nil {
+		return errors.New("object is missing")
+	}
+	switch tc.Expect.Result {
+	case ResultAllowed, ResultDenied, ResultWarned:
+		return nil
+	case ResultInvalid:
+		if tc.Expect.Message == "" {
+			return errors.New("expect.message must be the invalid field")
+		}
+		return nil
+	default:
+		return fmt.Errorf("expect.result must be one of %s, %s, %s or %s, got %q", ResultAllowed, ResultDenied, ResultWarned, ResultInvalid, tc.Expect.Result)
+	}
+}
+
+// decodeTestCaseObject decodes an object of a test case and sets its namespace unless it has one
+func decodeTestCaseObject(content map[string]interface{}, namespace string) (k8s.Object, error) {
+	raw, err := sigsyaml.Marshal(content)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := decoder.DecodeAny(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	if obj.GetNamespace() == "" {
+		obj.SetNamespace(namespace)
+	}
+	return obj, nil
+}
@@ -0,0 +1,947 @@
+package testutils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apiserver/pkg/admission"
+	"k8s.io/apiserver/pkg/admission/plugin/cel"
+	"k8s.io/apiserver/pkg/admission/plugin/policy/generic"
+	"k8s.io/apiserver/pkg/admission/plugin/policy/matching"
+	"k8s.io/apiserver/pkg/admission/plugin/policy/validating"
+	"k8s.io/apiserver/pkg/admission/plugin/webhook/matchconditions"
+	auditv1 "k8s.io/apiserver/pkg/apis/audit/v1"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	"k8s.io/apiserver/pkg/cel/environment"
+	"k8s.io/client-go/kubernetes"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/e2e-framework/klient/decoder"
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
+)
+
+const (
+	// BackendEnvVar selects the backend that CreateTestEnv uses to evaluate the policies
+	BackendEnvVar = "VAPLIB_TEST_BACKEND"
+	// BackendKind runs the tests against a Kind cluster (default)
+	BackendKind = "kind"
+	// BackendInProcess evaluates the policies in-process without a cluster
+	BackendInProcess = "inprocess"
+
+	// reconcileTimeout is the time we allow the in-process policy source to pick up policies, bindings and params
+	reconcileTimeout = 5 * time.Second
+	// namespacePollInterval is the interval at which we check if the namespace informer picked up an updated namespace
+	namespacePollInterval = 10 * time.Millisecond
+)
+
+type evaluatorCtxKey struct{}
+
+type (
+	policyTestContext = generic.PolicyTestContext[*admissionregistrationv1.ValidatingAdmissionPolicy, *admissionregistrationv1.ValidatingAdmissionPolicyBinding, validating.Validator]
+	policyHook        = generic.PolicyHook[*admissionregistrationv1.ValidatingAdmissionPolicy, *admissionregistrationv1.ValidatingAdmissionPolicyBinding, validating.Validator]
+)
+
+// builtinMappings are the REST mappings of the built-in kinds that the in-process backend knows about. Custom
+// resources are added from the CustomResourceDefinitions passed to NewPolicyEvaluator.
+var builtinMappings = []meta.RESTMapping{
+	namespacedMapping("", "v1", "Pod", "pods"),
+	namespacedMapping("", "v1", "PodTemplate", "podtemplates"),
+	namespacedMapping("", "v1", "ReplicationController", "replicationcontrollers"),
+	namespacedMapping("", "v1", "Service", "services"),
+	namespacedMapping("", "v1", "ConfigMap", "configmaps"),
+	namespacedMapping("", "v1", "Secret", "secrets"),
+	namespacedMapping("", "v1", "ServiceAccount", "serviceaccounts"),
+	namespacedMapping("", "v1", "PersistentVolumeClaim", "persistentvolumeclaims"),
+	namespacedMapping("", "v1", "ResourceQuota", "resourcequotas"),
+	namespacedMapping("", "v1", "LimitRange", "limitranges"),
+	clusterMapping("", "v1", "Namespace", "namespaces"),
+	clusterMapping("", "v1", "PersistentVolume", "persistentvolumes"),
+	namespacedMapping("apps", "v1", "Deployment", "deployments"),
+	namespacedMapping("apps", "v1", "ReplicaSet", "replicasets"),
+	namespacedMapping("apps", "v1", "DaemonSet", "daemonsets"),
+	namespacedMapping("apps", "v1", "StatefulSet", "statefulsets"),
+	namespacedMapping("batch", "v1", "Job", "jobs"),
+	namespacedMapping("batch", "v1", "CronJob", "cronjobs"),
+	namespacedMapping("rbac.authorization.k8s.io", "v1", "Role", "roles"),
+	namespacedMapping("rbac.authorization.k8s.io", "v1", "RoleBinding", "rolebindings"),
+	clusterMapping("rbac.authorization.k8s.io", "v1", "ClusterRole", "clusterroles"),
+	clusterMapping("rbac.authorization.k8s.io", "v1", "ClusterRoleBinding", "clusterrolebindings"),
+	namespacedMapping("networking.k8s.io", "v1", "Ingress", "ingresses"),
+	namespacedMapping("networking.k8s.io", "v1", "NetworkPolicy", "networkpolicies"),
+	clusterMapping("networking.k8s.io", "v1", "IngressClass", "ingressclasses"),
+	clusterMapping("storage.k8s.io", "v1", "StorageClass", "storageclasses"),
+	namespacedMapping("policy", "v1", "PodDisruptionBudget", "poddisruptionbudgets"),
+	clusterMapping("apiextensions.k8s.io", "v1", "CustomResourceDefinition", "customresourcedefinitions"),
+	clusterMapping("admissionregistration.k8s.io", "v1", "ValidatingAdmissionPolicy", "validatingadmissionpolicies"),
+	clusterMapping("admissionregistration.k8s.io", "v1", "ValidatingAdmissionPolicyBinding", "validatingadmissionpolicybindings"),
+	clusterMapping("admissionregistration.k8s.io", "v1", "ValidatingWebhookConfiguration", "validatingwebhookconfigurations"),
+	clusterMapping("admissionregistration.k8s.io", "v1", "MutatingWebhookConfiguration", "mutatingwebhookconfigurations"),
+}
+
+// PolicyEvaluator evaluates ValidatingAdmissionPolicies in-process with the upstream apiserver admission plugin. It
+// keeps track of the created namespaces and objects so that it behaves like a (very) minimal API server: objects
+// that are admitted are stored, creating an object twice fails with AlreadyExists and objects can only be created
+// in existing namespaces. Custom resources are validated against the schema of their CustomResourceDefinition, other
+// objects are neither defaulted nor validated against their schema.
+type PolicyEvaluator struct {
+	testContext      *policyTestContext
+	matcher          *matching.Matcher
+	compositionEnv   *cel.CompositionEnv
+	cancel           func()
+	mappings         map[schema.GroupVersionKind]meta.RESTMapping
+	objectInterfaces admission.ObjectInterfaces
+
+	coverage *coverageRecorder
+
+	mu          sync.Mutex
+	crds        map[schema.GroupKind]*apiextensionsv1.CustomResourceDefinition
+	paramKinds  map[schema.GroupVersionKind]bool
+	objects     map[string]runtime.Object
+	auditEvents []auditv1.Event
+}
+
+// NewPolicyEvaluator starts an in-process policy evaluator. CustomResourceDefinitions found among the objects are
+// registered first, then policies and bindings are loaded and all other objects are created in order.
+func NewPolicyEvaluator(ctx context.Context, objects ...k8s.Object) (*PolicyEvaluator, error) {
+	mappings := map[schema.GroupVersionKind]meta.RESTMapping{}
+	for _, mapping := range builtinMappings {
+		mappings[mapping.GroupVersionKind] = mapping
+	}
+	for _, obj := range objects {
+		if obj.GetObjectKind().GroupVersionKind().Kind != "CustomResourceDefinition" {
+			continue
+		}
+		crdMappings, err := mappingsFromCRD(obj)
+		if err != nil {
+			return nil, err
+		}
+		for _, mapping := range crdMappings {
+			mappings[mapping.GroupVersionKind] = mapping
+		}
+	}
+
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	var mappingList []meta.RESTMapping
+	for gvk, mapping := range mappings {
+		mappingList = append(mappingList, mapping)
+		if !scheme.Recognizes(gvk) {
+			scheme.AddKnownTypeWithName(gvk, &unstructured.Unstructured{})
+			scheme.AddKnownTypeWithName(gvk.GroupVersion().WithKind(gvk.Kind+"List"), &unstructured.UnstructuredList{})
+		}
+	}
+
+	// the composition env is the template of the compilers of all the policies, like in the admission plugin
+	compositionEnv, err := cel.NewCompositionEnv(cel.VariablesTypeName, environment.MustBaseEnvSet(environment.DefaultCompatibilityVersion()))
+	if err != nil {
+		return nil, err
+	}
+
+	coverage := newCoverageRecorder()
+	var matcher *matching.Matcher
+	testContext, cancel, err := generic.NewPolicyTestContext(
+		klogLogger{},
+		validating.NewValidatingAdmissionPolicyAccessor,
+		validating.NewValidatingAdmissionPolicyBindingAccessor,
+		coverage.compile(func(policy *admissionregistrationv1.ValidatingAdmissionPolicy) validating.Validator {
+			return compilePolicy(compositionEnv, policy)
+		}),
+		func(a authorizer.Authorizer, m *matching.Matcher, _ kubernetes.Interface) generic.Dispatcher[policyHook] {
+			// the matcher reads the namespaces from the informer of the test context
+			matcher = m
+			return validating.NewDispatcher(a, generic.NewPolicyMatcher(m))
+		},
+		nil,
+		mappingList,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := testContext.Start(); err != nil {
+		cancel()
+		return nil, err
+	}
+
+	e := &PolicyEvaluator{
+		testContext:      testContext,
+		matcher:          matcher,
+		compositionEnv:   compositionEnv,
+		cancel:           cancel,
+		mappings:         mappings,
+		objectInterfaces: admission.NewObjectInterfacesFromScheme(scheme),
+		coverage:         coverage,
+		crds:             map[schema.GroupKind]*apiextensionsv1.CustomResourceDefinition{},
+		paramKinds:       map[schema.GroupVersionKind]bool{},
+		objects:          map[string]runtime.Object{},
+	}
+
+	// Policies and bindings have to be loaded before anything else so that params are picked up properly, and the
+	// policies before the bindings, which the API server accepts in any order (e.g. bindings.yaml of the release
+	// sorts before policies.yaml)
+	var policies, bindings, others []k8s.Object
+	for _, obj := range objects {
+		switch obj.GetObjectKind().GroupVersionKind().Kind {
+		case "CustomResourceDefinition":
+			if err := e.registerCRD(obj); err != nil {
+				e.Close()
+				return nil, err
+			}
+		case "ValidatingAdmissionPolicy":
+			policies = append(policies, obj)
+		case "ValidatingAdmissionPolicyBinding":
+			bindings = append(bindings, obj)
+		default:
+			others = append(others, obj)
+		}
+	}
+	for _, obj := range slices.Concat(policies, bindings, others) {
+		if err := e.Apply(ctx, obj); err != nil {
+			e.Close()
+			return nil, err
+		}
+	}
+
+	return e, nil
+}
+
+// NewPolicyEvaluatorFromDirs starts an in-process policy evaluator with all the resources from the given directories.
+// The map key is the directory and the value is the file pattern, just like for the extra resources of CreateTestEnv.
+func NewPolicyEvaluatorFromDirs(ctx context.Context, dirs map[string]string) (*PolicyEvaluator, error) {
+	objects, err := decodeDirs(ctx, dirs)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewPolicyEvaluator(ctx, objects...)
+}
+
+// decodeDirs decodes all the resources from the given directories, the map key is the directory and the value is
+// the file pattern
+func decodeDirs(ctx context.Context, dirs map[string]string) ([]k8s.Object, error) {
+	var objects []k8s.Object
+	for dir, pattern := range dirs {
+		objs, err := decoder.DecodeAllFiles(ctx, os.DirFS(dir), pattern)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, objs...)
+	}
+	return objects, nil
+}
+
+// PolicyEvaluatorFromContext returns the in-process policy evaluator stored in the context or nil when the tests are
+// running against a cluster.
+func PolicyEvaluatorFromContext(ctx context.Context) *PolicyEvaluator {
+	e, _ := ctx.Value(evaluatorCtxKey{}).(*PolicyEvaluator)
+	return e
+}
+
+// Close stops the evaluator
+func (e *PolicyEvaluator) Close() {
+	e.cancel()
+}
+
+// Apply creates the object the same way the API server would: policies and bindings are loaded, any other object
+// is sent through admission and stored if it is admitted and the request is not a dry-run (see WithDryRun).
+func (e *PolicyEvaluator) Apply(ctx context.Context, obj k8s.Object, opts ...resources.CreateOption) error {
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	mapping, ok := e.mappings[gvk]
+	if !ok {
+		return &meta.NoKindMatchError{GroupKind: gvk.GroupKind(), SearchedVersions: []string{gvk.Version}}
+	}
+
+	switch gvk {
+	case admissionregistrationv1.SchemeGroupVersion.WithKind("ValidatingAdmissionPolicy"):
+		return e.loadPolicy(obj)
+	case admissionregistrationv1.SchemeGroupVersion.WithKind("ValidatingAdmissionPolicyBinding"):
+		return e.loadBinding(obj)
+	}
+
+	namespace := obj.GetNamespace()
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		if namespace == "" {
+			namespace = metav1.NamespaceDefault
+			obj.SetNamespace(namespace)
+		}
+		if !e.exists(namespaceKey(namespace)) {
+			return apierrors.NewNotFound(schema.GroupResource{Resource: "namespaces"}, namespace)
+		}
+	} else {
+		namespace = ""
+	}
+
+	key := objectKey(mapping.Resource, namespace, obj.GetName())
+	if e.exists(key) {
+		return apierrors.NewAlreadyExists(mapping.Resource.GroupResource(), obj.GetName())
+	}
+
+	createOptions := &metav1.CreateOptions{}
+	for _, opt := range opts {
+		opt(createOptions)
+	}
+	dryRun := len(createOptions.DryRun) > 0
+
+	if err := e.validate(obj); err != nil {
+		return err
+	}
+
+	attrs := admission.NewAttributesRecord(obj, nil, gvk, namespace, obj.GetName(), mapping.Resource, "", admission.Create, createOptions, dryRun, testUser())
+	if err := e.dispatch(ctx, attrs); err != nil {
+		return err
+	}
+	if dryRun {
+		return nil
+	}
+
+	return e.persist(ctx, key, obj)
+}
+
+// Update updates an existing object. The update is sent through admission with the stored object as the old object
+// and the object is stored unless the request is a dry-run.
+func (e *PolicyEvaluator) Update(ctx context.Context, obj k8s.Object, opts ...resources.UpdateOption) error {
+	updateOptions := &metav1.UpdateOptions{}
+	for _, opt := range opts {
+		opt(updateOptions)
+	}
+
+	key, oldObj, err := e.stored(obj)
+	if err != nil {
+		return err
+	}
+	return e.update(ctx, key, obj, oldObj, "", updateOptions, len(updateOptions.DryRun) > 0)
+}
+
+// UpdateSubresource updates a subresource of an existing object, e.g. the ephemeralcontainers of a Pod. The whole
+// object is sent through admission with the subresource in the request, like the API server does.
+func (e *PolicyEvaluator) UpdateSubresource(ctx context.Context, obj k8s.Object, subresource string, opts ...resources.UpdateOption) error {
+	updateOptions := &metav1.UpdateOptions{}
+	for _, opt := range opts {
+		opt(updateOptions)
+	}
+
+	key, oldObj, err := e.stored(obj)
+	if err != nil {
+		return err
+	}
+	return e.update(ctx, key, obj, oldObj, subresource, updateOptions, len(updateOptions.DryRun) > 0)
+}
+
+// Patch patches an existing object like the API server would: the patch is applied to the stored object and the
+// result is sent through admission as an update. JSON patches, merge patches and, for built-in kinds, strategic merge
+// patches are supported.
+func (e *PolicyEvaluator) Patch(ctx context.Context, obj k8s.Object, patch k8s.Patch, opts ...resources.PatchOption) error {
+	patchOptions := &metav1.PatchOptions{}
+	for _, opt := range opts {
+		opt(patchOptions)
+	}
+
+	key, oldObj, err := e.stored(obj)
+	if err != nil {
+		return err
+	}
+	original, err := json.Marshal(oldObj)
+	if err != nil {
+		return err
+	}
+
+	var patched []byte
+	switch patch.PatchType {
+	case types.JSONPatchType:
+		var jsonPatch jsonpatch.Patch
+		if jsonPatch, err = jsonpatch.DecodePatch(patch.Data); err == nil {
+			patched, err = jsonPatch.Apply(original)
+		}
+	case types.MergePatchType:
+		patched, err = jsonpatch.MergePatch(original, patch.Data)
+	case types.StrategicMergePatchType:
+		if _, ok := oldObj.(*unstructured.Unstructured); ok {
+			return apierrors.NewBadRequest(fmt.Sprintf("the %s patch type is not supported for %s", patch.PatchType, oldObj.GetObjectKind().GroupVersionKind().Kind))
+		}
+		patched, err = strategicpatch.StrategicMergePatch(original, patch.Data, oldObj)
+	default:
+		return apierrors.NewBadRequest(fmt.Sprintf("the %s patch type is not supported for %s", patch.PatchType, oldObj.GetObjectKind().GroupVersionKind().Kind))
+	}
+	if err != nil {
+		return apierrors.NewBadRequest(fmt.Sprintf("failed to apply the %s patch: %s", patch.PatchType, err))
+	}
+
+	newObj := oldObj.DeepCopyObject().(k8s.Object)
+	if u, ok := newObj.(*unstructured.Unstructured); ok {
+		u.Object = nil
+	}
+	if err := json.Unmarshal(patched, newObj); err != nil {
+		return apierrors.NewBadRequest(fmt.Sprintf("failed to decode the patched object: %s", err))
+	}
+	newObj.GetObjectKind().SetGroupVersionKind(oldObj.GetObjectKind().GroupVersionKind())
+	if err := e.update(ctx, key, newObj, oldObj, "", patchOptions, len(patchOptions.DryRun) > 0); err != nil {
+		return err
+	}
+
+	// return the patched object like the API server does
+	return toTyped(newObj, obj)
+}
+
+// stored returns the key and the stored version of an object
+func (e *PolicyEvaluator) stored(obj k8s.Object) (string, k8s.Object, error) {
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	mapping, ok := e.mappings[gvk]
+	if !ok {
+		return "", nil, &meta.NoKindMatchError{GroupKind: gvk.GroupKind(), SearchedVersions: []string{gvk.Version}}
+	}
+
+	namespace := obj.GetNamespace()
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		if namespace == "" {
+			namespace = metav1.NamespaceDefault
+			obj.SetNamespace(namespace)
+		}
+	} else {
+		namespace = ""
+	}
+
+	key := objectKey(mapping.Resource, namespace, obj.GetName())
+	e.mu.Lock()
+	oldObj, ok := e.objects[key]
+	e.mu.Unlock()
+	if !ok {
+		return "", nil, apierrors.NewNotFound(mapping.Resource.GroupResource(), obj.GetName())
+	}
+	return key, oldObj.DeepCopyObject().(k8s.Object), nil
+}
+
+// update sends an update through admission and stores the object unless the request is a dry-run
+func (e *PolicyEvaluator) update(ctx context.Context, key string, obj k8s.Object, oldObj k8s.Object, subresource string, options runtime.Object, dryRun bool) error {
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	mapping := e.mappings[gvk]
+
+	if err := e.validate(obj); err != nil {
+		return err
+	}
+
+	attrs := admission.NewAttributesRecord(obj, oldObj, gvk, obj.GetNamespace(), obj.GetName(), mapping.Resource, subresource, admission.Update, options, dryRun, testUser())
+	if err := e.dispatch(ctx, attrs); err != nil {
+		return err
+	}
+	if dryRun {
+		return nil
+	}
+
+	if err := e.persist(ctx, key, obj); err != nil {
+		return err
+	}
+	if gvk.Kind == "Namespace" && gvk.Group == "" {
+		return e.waitForNamespace(ctx, obj)
+	}
+	return nil
+}
+
+// waitForNamespace waits until the namespace matcher sees the labels of the updated namespace, it reads them from an
+// informer
+func (e *PolicyEvaluator) waitForNamespace(ctx context.Context, obj k8s.Object) error {
+	err := wait.PollUntilContextTimeout(ctx, namespacePollInterval, reconcileTimeout, true, func(ctx context.Context) (bool, error) {
+		namespace, err := e.matcher.GetNamespace(ctx, obj.GetName())
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		return maps.Equal(namespace.Labels, obj.GetLabels()), nil
+	})
+	if err != nil {
+		return fmt.Errorf("namespace %s was not picked up by the in-process namespace matcher: %w", obj.GetName(), err)
+	}
+	return nil
+}
+
+// dispatch sends the request through admission and records an audit event with the audit annotations of the request
+func (e *PolicyEvaluator) dispatch(ctx context.Context, attrs admission.Attributes) error {
+	recorder := &annotationRecorder{Attributes: attrs, annotations: map[string]string{}}
+	err := e.testContext.Plugin.Dispatch(ctx, recorder, e.objectInterfaces)
+
+	resource := attrs.GetResource()
+	objectRef := &auditv1.ObjectReference{
+		Resource:    resource.Resource,
+		Namespace:   attrs.GetNamespace(),
+		Name:        attrs.GetName(),
+		APIGroup:    resource.Group,
+		APIVersion:  resource.Version,
+		Subresource: attrs.GetSubresource(),
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.auditEvents = append(e.auditEvents, auditEvent(strings.ToLower(string(attrs.GetOperation())), objectRef, recorder.get(), err))
+
+	return err
+}
+
+// Coverage returns the CEL coverage of the validations of the policies, see CoverageReport
+func (e *PolicyEvaluator) Coverage() CoverageReport {
+	return e.coverage.report()
+}
+
+// AuditEvents returns the audit events of the requests that were sent through admission
+func (e *PolicyEvaluator) AuditEvents() []auditv1.Event {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]auditv1.Event(nil), e.auditEvents...)
+}
+
+// persist stores an admitted object and makes it visible to the namespace matcher and the param informers
+func (e *PolicyEvaluator) persist(ctx context.Context, key string, obj k8s.Object) error {
+	gvk := obj.GetObjectKind().GroupVersionKind()
+
+	// CRDs are registered on start, there is nothing to store in the tracker for them, but their schema validates the
+	// custom resources from now on
+	if gvk.Kind == "CustomResourceDefinition" {
+		if err := e.registerCRD(obj); err != nil {
+			return err
+		}
+		return e.store(key, obj)
+	}
+
+	stored := obj.DeepCopyObject()
+	if err := e.testContext.Update(stored); err != nil {
+		return err
+	}
+	if e.isParamKind(gvk) {
+		timeoutCtx, cancel := context.WithTimeout(ctx, reconcileTimeout)
+		defer cancel()
+		if err := e.testContext.WaitForReconcile(timeoutCtx, stored); err != nil {
+			return fmt.Errorf("param %s was not picked up by the in-process policy source: %w", obj.GetName(), err)
+		}
+	}
+
+	return e.store(key, stored)
+}
+
+// registerCRD keeps the CustomResourceDefinition for the schema validation of its custom resources
+func (e *PolicyEvaluator) registerCRD(obj k8s.Object) error {
+	crd := &apiextensionsv1.CustomResourceDefinition{}
+	if err := toTyped(obj, crd); err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.crds[schema.GroupKind{Group: crd.Spec.Group, Kind: crd.Spec.Names.Kind}] = crd
+	return nil
+}
+
+// validate validates a custom resource against the schema of its CustomResourceDefinition, like the API server does
+// before the validating admission
+func (e *PolicyEvaluator) validate(obj k8s.Object) error {
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	e.mu.Lock()
+	crd, ok := e.crds[gvk.GroupKind()]
+	e.mu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	errs, err := validateCustomResource(crd, obj)
+	if err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return apierrors.NewInvalid(gvk.GroupKind(), obj.GetName(), errs)
+	}
+	return nil
+}
+
+// Get returns a copy of a stored object
+func (e *PolicyEvaluator) Get(gvk schema.GroupVersionKind, namespace, name string) (k8s.Object, error) {
+	mapping, ok := e.mappings[gvk]
+	if !ok {
+		return nil, &meta.NoKindMatchError{GroupKind: gvk.GroupKind(), SearchedVersions: []string{gvk.Version}}
+	}
+	if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		namespace = ""
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	obj, ok := e.objects[objectKey(mapping.Resource, namespace, name)]
+	if !ok {
+		return nil, apierrors.NewNotFound(mapping.Resource.GroupResource(), name)
+	}
+	return obj.DeepCopyObject().(k8s.Object), nil
+}
+
+// Delete removes the object. Deleting a namespace removes every object in it.
+func (e *PolicyEvaluator) Delete(_ context.Context, obj k8s.Object) error {
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	mapping, ok := e.mappings[gvk]
+	if !ok {
+		return &meta.NoKindMatchError{GroupKind: gvk.GroupKind(), SearchedVersions: []string{gvk.Version}}
+	}
+
+	if gvk == admissionregistrationv1.SchemeGroupVersion.WithKind("ValidatingAdmissionPolicyBinding") {
+		binding := &admissionregistrationv1.ValidatingAdmissionPolicyBinding{}
+		if err := toTyped(obj, binding); err != nil {
+			return err
+		}
+		binding.TypeMeta = metav1.TypeMeta{}
+		return e.testContext.DeleteAndWait(binding)
+	}
+
+	namespace := obj.GetNamespace()
+	if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		namespace = ""
+	}
+	key := objectKey(mapping.Resource, namespace, obj.GetName())
+
+	e.mu.Lock()
+	stored, ok := e.objects[key]
+	if !ok {
+		e.mu.Unlock()
+		return apierrors.NewNotFound(mapping.Resource.GroupResource(), obj.GetName())
+	}
+	toDelete := []runtime.Object{stored}
+	delete(e.objects, key)
+	if gvk.Kind == "Namespace" && gvk.Group == "" {
+		for k, o := range e.objects {
+			if strings.HasPrefix(k, obj.GetName()+"/") {
+				toDelete = append(toDelete, o)
+				delete(e.objects, k)
+			}
+		}
+	}
+	e.mu.Unlock()
+
+	return e.testContext.DeleteAndWait(toDelete...)
+}
+
+// loadPolicy loads a ValidatingAdmissionPolicy into the policy source
+func (e *PolicyEvaluator) loadPolicy(obj k8s.Object) error {
+	policy := &admissionregistrationv1.ValidatingAdmissionPolicy{}
+	if err := toTyped(obj, policy); err != nil {
+		return err
+	}
+	if paramKind := policy.Spec.ParamKind; paramKind != nil {
+		gv, err := schema.ParseGroupVersion(paramKind.APIVersion)
+		if err != nil {
+			return err
+		}
+		e.mu.Lock()
+		e.paramKinds[gv.WithKind(paramKind.Kind)] = true
+		e.mu.Unlock()
+	}
+
+	if policy.Spec.FailurePolicy == nil {
+		failurePolicy := admissionregistrationv1.Fail
+		policy.Spec.FailurePolicy = &failurePolicy
+	}
+	defaultMatchResources(policy.Spec.MatchConstraints)
+	if err := compileErrors(e.compositionEnv, policy); err != nil {
+		return err
+	}
+
+	// The test context recognizes policies by their (fake) kind so the type meta has to be removed
+	policy.TypeMeta = metav1.TypeMeta{}
+	return e.testContext.Update(policy)
+}
+
+// loadBinding loads a ValidatingAdmissionPolicyBinding into the policy source and waits until it is in force. The
+// policy has to be loaded first.
+func (e *PolicyEvaluator) loadBinding(obj k8s.Object) error {
+	binding := &admissionregistrationv1.ValidatingAdmissionPolicyBinding{}
+	if err := toTyped(obj, binding); err != nil {
+		return err
+	}
+	defaultMatchResources(binding.Spec.MatchResources)
+	binding.TypeMeta = metav1.TypeMeta{}
+	if err := e.testContext.Update(binding); err != nil {
+		return err
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(e.testContext, reconcileTimeout)
+	defer cancel()
+	if err := e.testContext.WaitForReconcile(timeoutCtx, binding); err != nil {
+		return fmt.Errorf("binding %s was not picked up by the in-process policy source (is policy %s loaded?): %w", binding.Name, binding.Spec.PolicyName, err)
+	}
+	return nil
+}
+
+func (e *PolicyEvaluator) exists(key string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, ok := e.objects[key]
+	return ok
+}
+
+func (e *PolicyEvaluator) store(key string, obj runtime.Object) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.objects[key] = obj
+	return nil
+}
+
+func (e *PolicyEvaluator) isParamKind(gvk schema.GroupVersionKind) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.paramKinds[gvk]
+}
+
+// defaultMatchResources applies the defaults that the API server sets on the match resources of policies and
+// bindings
+func defaultMatchResources(m *admissionregistrationv1.MatchResources) {
+	if m == nil {
+		return
+	}
+	if m.NamespaceSelector == nil {
+		m.NamespaceSelector = &metav1.LabelSelector{}
+	}
+	if m.ObjectSelector == nil {
+		m.ObjectSelector = &metav1.LabelSelector{}
+	}
+	if m.MatchPolicy == nil {
+		matchPolicy := admissionregistrationv1.Equivalent
+		m.MatchPolicy = &matchPolicy
+	}
+	for _, rules := range [][]admissionregistrationv1.NamedRuleWithOperations{m.ResourceRules, m.ExcludeResourceRules} {
+		for i := range rules {
+			if rules[i].Scope == nil {
+				scope := admissionregistrationv1.AllScopes
+				rules[i].Scope = &scope
+			}
+		}
+	}
+}
+
+// compilePolicy compiles a ValidatingAdmissionPolicy the same way the validating admission policy plugin does. The
+// expressions that do not compile fail at evaluation, loadPolicy rejects such policies before (see compileErrors).
+func compilePolicy(compositionEnv *cel.CompositionEnv, policy *admissionregistrationv1.ValidatingAdmissionPolicy) validating.Validator {
+	hasParam := policy.Spec.ParamKind != nil
+	optionalVars := cel.OptionalVariableDeclarations{HasParams: hasParam, HasAuthorizer: true}
+	expressionOptionalVars := cel.OptionalVariableDeclarations{HasParams: hasParam, HasAuthorizer: false}
+
+	compiler := cel.NewCompositedCompilerFromTemplate(compositionEnv)
+
+	variables := make([]cel.NamedExpressionAccessor, len(policy.Spec.Variables))
+	for i, v := range policy.Spec.Variables {
+		variables[i] = &validating.Variable{Name: v.Name, Expression: v.Expression}
+	}
+	compiler.CompileAndStoreVariables(variables, optionalVars, environment.StoredExpressions)
+
+	var matcher matchconditions.Matcher
+	if len(policy.Spec.MatchConditions) > 0 {
+		matchConditions := make([]cel.ExpressionAccessor, len(policy.Spec.MatchConditions))
+		for i := range policy.Spec.MatchConditions {
+			matchConditions[i] = (*matchconditions.MatchCondition)(&policy.Spec.MatchConditions[i])
+		}
+		matcher = matchconditions.NewMatcher(compiler.CompileCondition(matchConditions, optionalVars, environment.StoredExpressions), policy.Spec.FailurePolicy, "policy", "validate", policy.Name)
+	}
+
+	validations := make([]cel.ExpressionAccessor, len(policy.Spec.Validations))
+	messageExpressions := make([]cel.ExpressionAccessor, len(policy.Spec.Validations))
+	for i, v := range policy.Spec.Validations {
+		validations[i] = &validating.ValidationCondition{Expression: v.Expression, Message: v.Message, Reason: v.Reason}
+		if v.MessageExpression != "" {
+			messageExpressions[i] = &validating.MessageExpressionCondition{MessageExpression: v.MessageExpression}
+		}
+	}
+
+	auditAnnotations := make([]cel.ExpressionAccessor, len(policy.Spec.AuditAnnotations))
+	for i, a := range policy.Spec.AuditAnnotations {
+		auditAnnotations[i] = &validating.AuditAnnotationCondition{Key: a.Key, ValueExpression: a.ValueExpression}
+	}
+
+	return validating.NewValidator(
+		compiler.CompileCondition(validations, optionalVars, environment.StoredExpressions),
+		matcher,
+		compiler.CompileCondition(auditAnnotations, optionalVars, environment.StoredExpressions),
+		compiler.CompileCondition(messageExpressions, expressionOptionalVars, environment.StoredExpressions),
+		policy.Spec.FailurePolicy,
+	)
+}
+
+// compileErrors compiles the expressions of a ValidatingAdmissionPolicy and returns the compilation errors as an
+// Invalid error, like the API server rejects a policy with an expression that does not compile
+func compileErrors(compositionEnv *cel.CompositionEnv, policy *admissionregistrationv1.ValidatingAdmissionPolicy) error {
+	hasParam := policy.Spec.ParamKind != nil
+	optionalVars := cel.OptionalVariableDeclarations{HasParams: hasParam, HasAuthorizer: true}
+	expressionOptionalVars := cel.OptionalVariableDeclarations{HasParams: hasParam, HasAuthorizer: false}
+
+	compiler := cel.NewCompositedCompilerFromTemplate(compositionEnv)
+	var errs field.ErrorList
+	check := func(path *field.Path, result cel.CompilationResult) {
+		if result.Error != nil {
+			errs = append(errs, field.Invalid(path, result.ExpressionAccessor.GetExpression(), result.Error.Error()))
+		}
+	}
+
+	spec := field.NewPath("spec")
+	for i, v := range policy.Spec.Variables {
+		check(spec.Child("variables").Index(i).Child("expression"), compiler.CompileAndStoreVariable(&validating.Variable{Name: v.Name, Expression: v.Expression}, optionalVars, environment.StoredExpressions))
+	}
+	for i := range policy.Spec.MatchConditions {
+		check(spec.Child("matchConditions").Index(i).Child("expression"), compiler.CompileCELExpression((*matchconditions.MatchCondition)(&policy.Spec.MatchConditions[i]), optionalVars, environment.StoredExpressions))
+	}
+	for i, v := range policy.Spec.Validations {
+		path := spec.Child("validations").Index(i)
+		check(path.Child("expression"), compiler.CompileCELExpression(&validating.ValidationCondition{Expression: v.Expression}, optionalVars, environment.StoredExpressions))
+		if v.MessageExpression != "" {
+			check(path.Child("messageExpression"), compiler.CompileCELExpression(&validating.MessageExpressionCondition{MessageExpression: v.MessageExpression}, expressionOptionalVars, environment.StoredExpressions))
+		}
+	}
+	for i, a := range policy.Spec.AuditAnnotations {
+		check(spec.Child("auditAnnotations").Index(i).Child("valueExpression"), compiler.CompileCELExpression(&validating.AuditAnnotationCondition{Key: a.Key, ValueExpression: a.ValueExpression}, optionalVars, environment.StoredExpressions))
+	}
+
+	if len(errs) > 0 {
+		return apierrors.NewInvalid(schema.GroupKind{Group: admissionregistrationv1.GroupName, Kind: "ValidatingAdmissionPolicy"}, policy.Name, errs)
+	}
+	return nil
+}
+
+// mappingsFromCRD returns the REST mappings of every served version of a CustomResourceDefinition
+func mappingsFromCRD(obj k8s.Object) ([]meta.RESTMapping, error) {
+	u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	group, _, _ := unstructured.NestedString(u, "spec", "group")
+	kind, _, _ := unstructured.NestedString(u, "spec", "names", "kind")
+	plural, _, _ := unstructured.NestedString(u, "spec", "names", "plural")
+	scope, _, _ := unstructured.NestedString(u, "spec", "scope")
+	versions, _, _ := unstructured.NestedSlice(u, "spec", "versions")
+	if group == "" || kind == "" || plural == "" {
+		return nil, fmt.Errorf("CustomResourceDefinition %s is missing group, kind or plural name", obj.GetName())
+	}
+
+	var mappings []meta.RESTMapping
+	for _, v := range versions {
+		version, _ := v.(map[string]interface{})
+		name, _, _ := unstructured.NestedString(version, "name")
+		if served, found, _ := unstructured.NestedBool(version, "served"); found && !served {
+			continue
+		}
+		if scope == "Cluster" {
+			mappings = append(mappings, clusterMapping(group, name, kind, plural))
+		} else {
+			mappings = append(mappings, namespacedMapping(group, name, kind, plural))
+		}
+	}
+	return mappings, nil
+}
+
+func namespacedMapping(group, version, kind, resource string) meta.RESTMapping {
+	return meta.RESTMapping{
+		Resource:         schema.GroupVersionResource{Group: group, Version: version, Resource: resource},
+		GroupVersionKind: schema.GroupVersionKind{Group: group, Version: version, Kind: kind},
+		Scope:            meta.RESTScopeNamespace,
+	}
+}
+
+func clusterMapping(group, version, kind, resource string) meta.RESTMapping {
+	return meta.RESTMapping{
+		Resource:         schema.GroupVersionResource{Group: group, Version: version, Resource: resource},
+		GroupVersionKind: schema.GroupVersionKind{Group: group, Version: version, Kind: kind},
+		Scope:            meta.RESTScopeRoot,
+	}
+}
+
+// toTyped converts a decoded object (typed or unstructured) into the given typed object
+func toTyped(obj k8s.Object, out runtime.Object) error {
+	u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return err
+	}
+	return runtime.DefaultUnstructuredConverter.FromUnstructured(u, out)
+}
+
+// objectKey is the key of the stored objects. Namespaced objects are prefixed with their namespace so that they can
+// be removed together with the namespace.
+func objectKey(gvr schema.GroupVersionResource, namespace, name string) string {
+	return fmt.Sprintf("%s/%s/%s", namespace, gvr.GroupResource().String(), name)
+}
+
+func namespaceKey(name string) string {
+	return objectKey(schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}, "", name)
+}
+
+// testUser is the user that sends the requests, the same as the admin user of a Kind cluster
+func testUser() user.Info {
+	return &user.DefaultInfo{Name: "kubernetes-admin", Groups: []string{"kubeadm:cluster-admins", user.AllAuthenticated}}
+}
+
+// annotationRecorder records the audit annotations that admission adds to the request
+type annotationRecorder struct {
+	admission.Attributes
+
+	mu          sync.Mutex
+	annotations map[string]string
+}
+
+// AddAnnotation implements admission.Attributes
+func (r *annotationRecorder) AddAnnotation(key, value string) error {
+	if err := r.Attributes.AddAnnotation(key, value); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.annotations[key] = value
+	return nil
+}
+
+func (r *annotationRecorder) get() map[string]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.annotations) == 0 {
+		return nil
+	}
+	annotations := map[string]string{}
+	for k, v := range r.annotations {
+		annotations[k] = v
+	}
+	return annotations
+}
+
+// klogLogger implements the logger of the upstream policy test context
+type klogLogger struct{}
+
+func (klogLogger) Helper() {}
+
+func (klogLogger) Logf(format string, args ...interface{}) {
+	klog.V(2).Infof(format, args...)
+}
+
+// useInProcessBackend returns true when the tests should be run with the in-process backend
+func useInProcessBackend() bool {
+	return os.Getenv(BackendEnvVar) == BackendInProcess
+}
+
+// SkipWithoutCluster skips the test when it is run with the in-process backend. Use it for steps that need a real
+// API server, e.g. waiting for a controller to act on an object.
+func SkipWithoutCluster(ctx context.Context, t *testing.T) {
+	if PolicyEvaluatorFromContext(ctx) != nil {
+		t.Skip("skipping as the test needs a cluster and the in-process backend is used")
+	}
+}
@@ -0,0 +1,388 @@
+package testutils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"sigs.k8s.io/e2e-framework/pkg/env"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/types"
+	"sigs.k8s.io/e2e-framework/support"
+	"sigs.k8s.io/e2e-framework/support/kind"
+)
+
+// RunDirEnvVar is the directory of the run ID files of the Kind clusters, <temp dir>/vaplibtest by default
+const RunDirEnvVar = "VAPLIB_RUN_DIR"
+
+const (
+	// maxKindClusterName keeps the name of the Kind cluster and of its nodes (<cluster>-control-plane) valid host names
+	maxKindClusterName = 40
+	// kindNameSuffix is the length of the random suffix of the name of the Kind cluster
+	kindNameSuffix = 6
+)
+
+// RunInfo is the run ID file of a Kind cluster that CreateTestEnv creates. The file is written before the cluster is
+// created and removed after it is destroyed, so a cluster whose file is older than a test run has leaked.
+type RunInfo struct {
+	// Cluster is the name of the Kind cluster
+	Cluster string `json:"cluster"`
+	// RunID is the prefix of the namespaces of the run
+	RunID string `json:"runID"`
+	// Package is the directory of the test package
+	Package string `json:"package"`
+	// PID is the process of the test binary
+	PID int `json:"pid"`
+	// Created is the time the cluster was requested
+	Created time.Time `json:"created"`
+}
+
+// CleanupOptions select the Kind clusters that CleanupClusters deletes
+type CleanupOptions struct {
+	// OlderThan is the minimum age of the run ID file of a cluster
+	OlderThan time.Duration
+	// IncludeUnlabelled also deletes the clusters with the vaplibtest prefix that have no run ID file, i.e. whose age
+	// is unknown
+	IncludeUnlabelled bool
+	// DryRun only returns the clusters that would be deleted
+	DryRun bool
+}
+
+// CleanupClusters deletes the Kind clusters with the vaplibtest prefix whose run ID file is older than the threshold,
+// and returns their names. Clusters of other tools are never deleted. The run ID files of the clusters that no longer
+// exist are removed as well once they are older than the threshold.
+func CleanupClusters(ctx context.Context, opts CleanupOptions) ([]string, error) {
+	clusters, err := kindClusters(ctx)
+	if err != nil {
+		return nil, err
+	}
+	runs, err := LoadRunInfos()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var deleted []string
+	for _, cluster := range clusters {
+		if !strings.HasPrefix(cluster, kindNamePrefix+"-") {
+			continue
+		}
+		run, labelled := runs[cluster]
+		if labelled && now.Sub(run.Created) < opts.OlderThan || !labelled && !opts.IncludeUnlabelled {
+			continue
+		}
+		if !opts.DryRun {
+			if err := deleteKindCluster(ctx, cluster); err != nil {
+				return deleted, err
+			}
+			if err := removeRunInfo(cluster); err != nil {
+				return deleted, err
+			}
+		}
+		deleted = append(deleted, cluster)
+	}
+
+	// Remove the run ID files that were left behind by clusters deleted otherwise
+	for cluster, run := range runs {
+		if opts.DryRun || now.Sub(run.Created) < opts.OlderThan || containsString(clusters, cluster) {
+			continue
+		}
+		if err := removeRunInfo(cluster); err != nil {
+			return deleted, err
+		}
+	}
+	return deleted, nil
+}
+
+// LoadRunInfos reads the run ID files of RunDirEnvVar, by cluster name
+func LoadRunInfos() (map[string]RunInfo, error) {
+	files, err := filepath.Glob(filepath.Join(runDir(), kindNamePrefix+"-*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	runs := map[string]RunInfo{}
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		run := RunInfo{}
+		if err := json.Unmarshal(content, &run); err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", file, err)
+		}
+		runs[run.Cluster] = run
+	}
+	return runs, nil
+}
+
+// runDir returns the directory of the run ID files
+func runDir() string {
+	if dir := os.Getenv(RunDirEnvVar); dir != "" {
+		return dir
+	}
+	return filepath.Join(os.TempDir(), kindNamePrefix)
+}
+
+// writeRunInfo writes the run ID file of the cluster
+func writeRunInfo(run RunInfo) error {
+	if err := os.MkdirAll(runDir(), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(filepath.Join(runDir(), run.Cluster+".json"))
+	if err != nil {
+		return err
+	}
+	if err := writeJSON(f, run); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// removeRunInfo removes the run ID file of the cluster, if it exists
+func removeRunInfo(cluster string) error {
+	err := os.Remove(filepath.Join(runDir(), cluster+".json"))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// kindClusters returns the names of the Kind clusters
+func kindClusters(ctx context.Context) ([]string, error) {
+	out, err := exec.CommandContext(ctx, "kind", "get", "clusters").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the Kind clusters: %w", err)
+	}
+	return strings.Fields(string(out)), nil
+}
+
+// deleteKindCluster deletes a Kind cluster, deleting a cluster that does not exist is not an error
+func deleteKindCluster(ctx context.Context, name string) error {
+	out, err := exec.CommandContext(ctx, "kind", "delete", "cluster", "--name", name).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to delete the Kind cluster %s: %w: %s", name, err, out)
+	}
+	return nil
+}
+
+// clusterNameForPackage returns the name of the Kind cluster of the test package: the vaplibtest prefix, the package
+// and a random suffix, so that a leaked cluster can be traced back to its package and concurrent runs do not collide
+func clusterNameForPackage(pkg string) string {
+	pkg = strings.Trim(regexp.MustCompile(`[^a-z0-9-]+`).ReplaceAllString(strings.ToLower(pkg), "-"), "-")
+	if max := maxKindClusterName - len(kindNamePrefix+"--") - kindNameSuffix; len(pkg) > max {
+		pkg = strings.TrimRight(pkg[:max], "-")
+	}
+	prefix := kindNamePrefix
+	if pkg != "" {
+		prefix += "-" + pkg
+	}
+	return envconf.RandomName(prefix, len(prefix+"-")+kindNameSuffix)
+}
+
+// kindCluster destroys the Kind cluster of a test package exactly once: at the end of the tests, on SIGINT or SIGTERM,
+// or before a panic in a test ends the process. The Finish functions of the environment only run for a panic in the
+// goroutine of TestMain.
+type kindCluster struct {
+	name string
+	// auditLogDir is removed together with the cluster, empty if it is kept
+	auditLogDir string
+
+	mu       sync.Mutex
+	provider support.E2EClusterProvider
+	once     sync.Once
+	err      error
+	signals  chan os.Signal
+}
+
+// setupFunc writes the run ID file and starts the signal handler, it runs before the cluster is created
+func (c *kindCluster) setupFunc(runID string) env.Func {
+	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		wd, err := os.Getwd()
+		if err != nil {
+			return ctx, err
+		}
+		run := RunInfo{Cluster: c.name, RunID: runID, Package: wd, PID: os.Getpid(), Created: time.Now().UTC()}
+		if err := writeRunInfo(run); err != nil {
+			return ctx, fmt.Errorf("failed to write the run ID file of the Kind cluster %s: %w", c.name, err)
+		}
+
+		c.signals = make(chan os.Signal, 1)
+		signal.Notify(c.signals, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			sig, ok := <-c.signals
+			if !ok {
+				return
+			}
+			log.Printf("received %s, destroying the Kind cluster %s", sig, c.name)
+			if err := c.destroy(context.Background()); err != nil {
+				log.Print(err)
+			}
+			os.Exit(1)
+		}()
+		return ctx, nil
+	}
+}
+
+// createdFunc keeps the provider of the created cluster, so that its kubeconfig is removed with it
+func (c *kindCluster) createdFunc() env.Func {
+	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		if provider, ok := ctx.Value(support.ClusterNameContextKey(c.name)).(support.E2EClusterProvider); ok {
+			c.mu.Lock()
+			c.provider = provider
+			c.mu.Unlock()
+		}
+		return ctx, nil
+	}
+}
+
+// finishFunc stops the signal handler and destroys the cluster
+func (c *kindCluster) finishFunc() env.Func {
+	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		if c.signals != nil {
+			signal.Stop(c.signals)
+			close(c.signals)
+		}
+		return ctx, c.destroy(ctx)
+	}
+}
+
+// destroy deletes the cluster, the run ID file and the audit log. It also deletes a cluster whose creation failed
+// half-way, and later calls wait for the first one and return its error.
+func (c *kindCluster) destroy(ctx context.Context) error {
+	c.once.Do(func() {
+		c.mu.Lock()
+		provider := c.provider
+		c.mu.Unlock()
+
+		if provider == nil {
+			provider = kind.NewCluster(c.name)
+		}
+		c.err = provider.Destroy(ctx)
+		if c.err != nil {
+			// keep the run ID file, CleanupClusters deletes the cluster later
+			return
+		}
+		if c.auditLogDir != "" {
+			_ = os.RemoveAll(c.auditLogDir)
+		}
+		c.err = removeRunInfo(c.name)
+	})
+	return c.err
+}
+
+// destroyOnPanic destroys the cluster and panics again if the caller is panicking, it must be deferred
+func (c *kindCluster) destroyOnPanic() {
+	if r := recover(); r != nil {
+		log.Printf("recovered from a panic, destroying the Kind cluster %s", c.name)
+		if err := c.destroy(context.Background()); err != nil {
+			log.Print(err)
+		}
+		panic(r)
+	}
+}
+
+// kindEnvironment destroys the Kind cluster when a test panics, in the test function or in a step of a feature, or
+// when a Finish function panics
+type kindEnvironment struct {
+	env.Environment
+	cluster *kindCluster
+}
+
+// WithContext returns a new environment with the context that still destroys the cluster on a panic
+func (e *kindEnvironment) WithContext(ctx context.Context) types.Environment {
+	return &kindEnvironment{Environment: e.Environment.WithContext(ctx), cluster: e.cluster}
+}
+
+// Run runs the tests and destroys the cluster if a Finish function panics, e.g. when the cluster could not be created
+func (e *kindEnvironment) Run(m *testing.M) int {
+	defer e.cluster.destroyOnPanic()
+	return e.Environment.Run(m)
+}
+
+// Test runs the features and destroys the cluster if one of them panics
+func (e *kindEnvironment) Test(t *testing.T, features ...types.Feature) context.Context {
+	defer e.cluster.destroyOnPanic()
+	return e.Environment.Test(t, e.panicSafe(features)...)
+}
+
+// TestInParallel runs the features in parallel and destroys the cluster if one of them panics
+func (e *kindEnvironment) TestInParallel(t *testing.T, features ...types.Feature) context.Context {
+	defer e.cluster.destroyOnPanic()
+	return e.Environment.TestInParallel(t, e.panicSafe(features)...)
+}
+
+// panicSafe wraps the steps of the features, they run in the goroutines of the subtests
+func (e *kindEnvironment) panicSafe(features []types.Feature) []types.Feature {
+	var result []types.Feature
+	for _, feature := range features {
+		var steps []types.Step
+		for _, step := range feature.Steps() {
+			fn := step.Func()
+			steps = append(steps, panicSafeStep{Step: step, fn: func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+				defer e.cluster.destroyOnPanic()
+				return fn(ctx, t, cfg)
+			}})
+		}
+		result = append(result, panicSafeFeature{Feature: feature, steps: steps})
+	}
+	return result
+}
+
+// panicSafeFeature is a feature whose steps destroy the cluster on a panic
+type panicSafeFeature struct {
+	types.Feature
+	steps []types.Step
+}
+
+func (f panicSafeFeature) Steps() []types.Step {
+	return f.steps
+}
+
+func (f panicSafeFeature) Description() string {
+	if describable, ok := f.Feature.(types.DescribableFeature); ok {
+		return describable.Description()
+	}
+	return ""
+}
+
+// panicSafeStep is a step that destroys the cluster on a panic
+type panicSafeStep struct {
+	types.Step
+	fn types.StepFunc
+}
+
+func (s panicSafeStep) Func() types.StepFunc {
+	return s.fn
+}
+
+func (s panicSafeStep) Description() string {
+	if describable, ok := s.Step.(types.DescribableStep); ok {
+		return describable.Description()
+	}
+	return ""
+}
+
+// containsString returns true if the value is in the slice
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
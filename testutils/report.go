@@ -0,0 +1,441 @@
+package testutils
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+	"sigs.k8s.io/e2e-framework/pkg/env"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+)
+
+// ReportDirEnvVar is the directory where CreateTestEnv writes the report of the test package, as
+// <policy>.<kubernetes version>.<backend>.json and <policy>.<kubernetes version>.<backend>.xml (JUnit)
+const ReportDirEnvVar = "VAPLIB_REPORT_DIR"
+
+const (
+	// ResultError means that the request failed for another reason than a policy denial
+	ResultError = "error"
+	// ResultAudited means that the request was audited as a validation failure
+	ResultAudited = "audited"
+	// ResultNotAudited means that no audit event of the validation failure was found
+	ResultNotAudited = "not audited"
+)
+
+// existingClusterBackend is the backend of the report when the tests run on an existing cluster
+const existingClusterBackend = "cluster"
+
+// clusterScopedKinds is the key of the kinds of the cluster-scoped objects, which have no namespace
+const clusterScopedKinds = "cluster"
+
+// TestReport is the result of the tests of a policy package on one Kubernetes version
+type TestReport struct {
+	// Policy is the name of the policy directory
+	Policy string `json:"policy"`
+	// KubernetesVersion is the version of the API server, or of the apiserver library of the in-process backend
+	KubernetesVersion string `json:"kubernetesVersion"`
+	// Backend is kind, inprocess or cluster (an existing cluster)
+	Backend string `json:"backend"`
+	// Steps are the assess steps that checked a request, in the order they ran
+	Steps []StepReport `json:"steps"`
+}
+
+// StepReport is the result of an assess step of a feature
+type StepReport struct {
+	// Test is the name of the test function
+	Test string `json:"test"`
+	// Feature is the name of the feature, empty for a check in the test function itself
+	Feature string `json:"feature,omitempty"`
+	// Assess is the name of the assess step
+	Assess string `json:"assess,omitempty"`
+	// Passed is false if the step failed
+	Passed bool `json:"passed"`
+	// Checks are the admission checks of the step
+	Checks []CheckReport `json:"checks"`
+}
+
+// CheckReport is the expected and the actual outcome of a request
+type CheckReport struct {
+	// Kind is the kind of the object that was sent last in the namespace of the test
+	Kind string `json:"kind,omitempty"`
+	// Expected is one of allowed, denied, warned or audited
+	Expected string `json:"expected"`
+	// Actual is one of allowed, denied, warned, error, audited or not audited
+	Actual string `json:"actual"`
+	// Message is the denial message, the warnings or the error of the request
+	Message string `json:"message,omitempty"`
+}
+
+// Passed returns the number of passed steps
+func (r TestReport) Passed() int {
+	passed := 0
+	for _, step := range r.Steps {
+		if step.Passed {
+			passed++
+		}
+	}
+	return passed
+}
+
+// WriteReport writes the report as JSON and as JUnit XML to the directory
+func WriteReport(dir string, report TestReport) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	// the backend is part of the name, an in-process run and a Kind run can have the same Kubernetes version
+	name := report.Policy + "." + strings.NewReplacer("/", "_", ":", "_", "@", "_").Replace(report.KubernetesVersion) + "." + report.Backend
+
+	f, err := os.Create(filepath.Join(dir, name+".json"))
+	if err != nil {
+		return err
+	}
+	if err := writeJSON(f, report); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	f, err = os.Create(filepath.Join(dir, name+".xml"))
+	if err != nil {
+		return err
+	}
+	if err := report.WriteJUnit(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// LoadReports reads the JSON reports of the directory
+func LoadReports(dir string) ([]TestReport, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	var reports []TestReport
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		report := TestReport{}
+		if err := json.Unmarshal(content, &report); err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", file, err)
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+// junitTestSuite is the testsuite element of a JUnit XML report
+type junitTestSuite struct {
+	XMLName    xml.Name        `xml:"testsuite"`
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Properties []junitProperty `xml:"properties>property"`
+	TestCases  []junitTestCase `xml:"testcase"`
+}
+
+// junitTestCase is the testcase element of a JUnit XML report
+type junitTestCase struct {
+	Name       string          `xml:"name,attr"`
+	ClassName  string          `xml:"classname,attr"`
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	Failure    *junitFailure   `xml:"failure,omitempty"`
+}
+
+// junitProperty is a property element of a JUnit XML report
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+// junitFailure is the failure element of a JUnit XML report
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the report as a JUnit XML testsuite with one testcase per assess step
+func (r TestReport) WriteJUnit(w io.Writer) error {
+	suite := junitTestSuite{
+		Name:  r.Policy + " (" + r.KubernetesVersion + ")",
+		Tests: len(r.Steps),
+		Properties: []junitProperty{
+			{Name: "policy", Value: r.Policy},
+			{Name: "kubernetesVersion", Value: r.KubernetesVersion},
+			{Name: "backend", Value: r.Backend},
+		},
+	}
+	for _, step := range r.Steps {
+		testCase := junitTestCase{Name: step.Assess, ClassName: r.Policy + "." + step.Test}
+		if step.Feature != "" {
+			testCase.ClassName += "." + step.Feature
+		}
+		if testCase.Name == "" {
+			testCase.Name = step.Test
+		}
+
+		var lines []string
+		for i, check := range step.Checks {
+			prefix := fmt.Sprintf("check%d.", i)
+			testCase.Properties = append(testCase.Properties,
+				junitProperty{Name: prefix + "kind", Value: check.Kind},
+				junitProperty{Name: prefix + "expected", Value: check.Expected},
+				junitProperty{Name: prefix + "actual", Value: check.Actual},
+			)
+			if check.Message != "" {
+				testCase.Properties = append(testCase.Properties, junitProperty{Name: prefix + "message", Value: check.Message})
+			}
+			line := fmt.Sprintf("%s: expected %s, got %s", check.Kind, check.Expected, check.Actual)
+			if check.Message != "" {
+				line += ": " + check.Message
+			}
+			lines = append(lines, line)
+		}
+		if !step.Passed {
+			suite.Failures++
+			testCase.Failure = &junitFailure{Message: "the step failed", Text: strings.Join(lines, "\n")}
+		}
+		suite.TestCases = append(suite.TestCases, testCase)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(suite); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// reportRecorder collects the checks of the test package
+type reportRecorder struct {
+	mu     sync.Mutex
+	report TestReport
+	// steps are the steps in the order they ran, by the name of their test
+	steps map[string]*StepReport
+	order []*StepReport
+	// namespaces are the namespaces of the test functions
+	namespaces map[string]string
+	// kinds are the kinds of the objects that were sent last per namespace, or under clusterScopedKinds
+	kinds map[string]sentKind
+	// sent counts the objects that were sent
+	sent int
+}
+
+// sentKind is the kind of an object that was sent and its position among all sent objects
+type sentKind struct {
+	kind string
+	seq  int
+}
+
+// testReport is the report of the test package, the tests of a package run one at a time
+var testReport = &reportRecorder{steps: map[string]*StepReport{}, namespaces: map[string]string{}, kinds: map[string]sentKind{}}
+
+// setEnvironment sets the policy, backend and Kubernetes version of the report
+func (r *reportRecorder) setEnvironment(policy string, backend string, kubernetesVersion string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.report.Policy = policy
+	r.report.Backend = backend
+	r.report.KubernetesVersion = kubernetesVersion
+}
+
+// setNamespace records the namespace of a test function
+func (r *reportRecorder) setNamespace(t *testing.T, namespace string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.namespaces[string(GetNamespaceKey(t))] = namespace
+}
+
+// recordObject records the kind of an object that is sent to the API server
+func (r *reportRecorder) recordObject(obj k8s.Object) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := obj.GetNamespace()
+	if key == "" {
+		key = clusterScopedKinds
+	}
+	r.sent++
+	r.kinds[key] = sentKind{kind: obj.GetObjectKind().GroupVersionKind().Kind, seq: r.sent}
+}
+
+// lastKind returns the kind of the object that was sent last in the namespace, or of the cluster-scoped object if it
+// was sent after it
+func (r *reportRecorder) lastKind(namespace string) string {
+	namespaced, clusterScoped := r.kinds[namespace], r.kinds[clusterScopedKinds]
+	if clusterScoped.seq > namespaced.seq {
+		return clusterScoped.kind
+	}
+	return namespaced.kind
+}
+
+// recordRequest adds a check of a request to the step of the test
+func (r *reportRecorder) recordRequest(t *testing.T, expected string, err error) {
+	actual, message := requestOutcome(err)
+	r.addCheck(t, CheckReport{Expected: expected, Actual: actual, Message: message})
+}
+
+// recordWarnings adds the warnings of a request to the last check of the step of the test if it was allowed, or adds
+// a check otherwise
+func (r *reportRecorder) recordWarnings(t *testing.T, expected string, warnings []string) {
+	actual := ResultAllowed
+	if len(warnings) > 0 {
+		actual = ResultWarned
+	}
+
+	r.mu.Lock()
+	step := r.steps[t.Name()]
+	if step != nil && len(step.Checks) > 0 && step.Checks[len(step.Checks)-1].Actual == ResultAllowed {
+		check := &step.Checks[len(step.Checks)-1]
+		check.Expected = expected
+		check.Actual = actual
+		check.Message = strings.Join(warnings, "\n")
+		r.mu.Unlock()
+		return
+	}
+	r.mu.Unlock()
+
+	r.addCheck(t, CheckReport{Expected: expected, Actual: actual, Message: strings.Join(warnings, "\n")})
+}
+
+// addCheck adds the check to the step of the test, the step is created with the first check
+func (r *reportRecorder) addCheck(t *testing.T, check CheckReport) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	step, ok := r.steps[t.Name()]
+	if !ok {
+		names := strings.SplitN(t.Name(), "/", 3)
+		step = &StepReport{Test: names[0], Passed: true}
+		if len(names) > 1 {
+			step.Feature = names[1]
+		}
+		if len(names) > 2 {
+			step.Assess = names[2]
+		}
+		r.steps[t.Name()] = step
+		r.order = append(r.order, step)
+		t.Cleanup(func() {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			step.Passed = !t.Failed()
+		})
+	}
+
+	check.Kind = r.lastKind(r.namespaces[string(GetNamespaceKey(t))])
+	step.Checks = append(step.Checks, check)
+}
+
+// setActual sets the actual outcome of the last check of the step of the test
+func (r *reportRecorder) setActual(t *testing.T, actual string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if step := r.steps[t.Name()]; step != nil && len(step.Checks) > 0 {
+		step.Checks[len(step.Checks)-1].Actual = actual
+	}
+}
+
+// snapshot returns a copy of the report
+func (r *reportRecorder) snapshot() TestReport {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	report := r.report
+	for _, step := range r.order {
+		copied := *step
+		copied.Checks = append([]CheckReport(nil), step.Checks...)
+		report.Steps = append(report.Steps, copied)
+	}
+	return report
+}
+
+// requestOutcome returns the result of a request and its message
+func requestOutcome(err error) (string, string) {
+	if err == nil {
+		return ResultAllowed, ""
+	}
+	if denial, parseErr := ParseDenial(err); parseErr == nil {
+		return ResultDenied, denial.Message
+	}
+	if apierrors.IsInvalid(err) {
+		return ResultInvalid, err.Error()
+	}
+	return ResultError, err.Error()
+}
+
+// serverVersion returns the version of the API server
+func serverVersion(config *rest.Config) (string, error) {
+	client, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return "", err
+	}
+	info, err := client.ServerVersion()
+	if err != nil {
+		return "", err
+	}
+	return info.GitVersion, nil
+}
+
+// inProcessVersion returns the Kubernetes version of the apiserver library that the in-process backend uses (the
+// library v0.X.Y is released with Kubernetes v1.X.Y)
+func inProcessVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == "k8s.io/apiserver" {
+			return strings.Replace(dep.Version, "v0.", "v1.", 1)
+		}
+	}
+	return "unknown"
+}
+
+// reportSetupFunc records the environment of the report. The version of a cluster is the version of its API server.
+func reportSetupFunc(backend string) env.Func {
+	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		// the tests run in the directory of the policy
+		wd, err := os.Getwd()
+		if err != nil {
+			return ctx, err
+		}
+
+		version := inProcessVersion()
+		if backend != BackendInProcess {
+			if version, err = serverVersion(cfg.Client().RESTConfig()); err != nil {
+				return ctx, err
+			}
+		}
+		testReport.setEnvironment(filepath.Base(wd), backend, version)
+		return ctx, nil
+	}
+}
+
+// reportFinishFunc writes the report of the test package to the directory
+func reportFinishFunc(dir string) env.Func {
+	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		return ctx, WriteReport(dir, testReport.snapshot())
+	}
+}
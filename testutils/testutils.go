@@ -3,12 +3,17 @@ package testutils
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
 
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/e2e-framework/klient/decoder"
+	"sigs.k8s.io/e2e-framework/klient/k8s"
 	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
 	"sigs.k8s.io/e2e-framework/pkg/env"
 	"sigs.k8s.io/e2e-framework/pkg/envconf"
@@ -16,6 +21,9 @@ import (
 	"sigs.k8s.io/e2e-framework/support/kind"
 )
 
+// DryRunEnvVar disables the server-side dry-run of the test objects when it is set to false
+const DryRunEnvVar = "VAPLIB_DRY_RUN"
+
 const (
 	defaultKindVersion = "v1.34.0"
 	kindNamePrefix     = "vaplibtest"
@@ -32,97 +40,245 @@ func CreateTestEnv(kindVersion string, keepLogs bool, namespaceLabels map[string
 	// Specifying a run ID so that multiple runs wouldn't collide.
 	runID := envconf.RandomName(testNamespace, 14)
 
-	// Use the default Kind version if none is provided
-	if kindVersion == "" {
-		kindVersion = defaultKindVersion
+	// Time we wait for the policies, bindings and parameters to become effective
+	readinessTimeout, err := ReadinessTimeout()
+	if err != nil {
+		return nil, err
 	}
 
 	// Create a new environment from the flags
-	var testEnv env.Environment
-	testEnv, _ = env.NewFromFlags()
+	registerFlags()
+	testEnv, err := env.NewFromFlags()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the test environment from the flags: %w", err)
+	}
+
+	// The node image is resolved after the flags are parsed
+	image := kindImage(kindVersion)
 
 	// Define an empty slice of EnvFunc type for Env setup and finish
 	var setupFuncs []env.Func
 	var finishFuncs []env.Func
 
-	// Create cluster
-	kindClusterName := envconf.RandomName(kindNamePrefix, 16)
-	setupFuncs = append(setupFuncs, envfuncs.CreateClusterWithConfig(kind.NewProvider(), kindClusterName, "../../testutils/kind-config.yaml", kind.WithImage("kindest/node:"+kindVersion)))
-
-	// Apply all yaml from the policy directory
-	setupFuncs = append(
-		setupFuncs,
-		func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
-			return applyResourcesFromDir(ctx, cfg, "./", "*.yaml", 2)
-		},
-	)
+	inProcess := useInProcessBackend()
+	kubeconfig := existingClusterKubeconfig()
+	existingCluster := kubeconfig != ""
+	var auditLogDir string
 
-	// Apply extra resources
-	for dir, pattern := range extraResourcesFromDir {
+	// The tests run in the directory of the policy, the Kind cluster is named after it
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	kindClusterName := clusterNameForPackage(filepath.Base(wd))
+	var cluster *kindCluster
+
+	if inProcess {
+		// Start the in-process evaluator with all yaml from the policy directory and the extra resources
+		resourceDirs := map[string]string{"./": "*.yaml"}
+		for dir, pattern := range extraResourcesFromDir {
+			resourceDirs[dir] = pattern
+		}
 		setupFuncs = append(
 			setupFuncs,
 			func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
-				return applyResourcesFromDir(ctx, cfg, dir, pattern, 1)
+				objects, err := decodeDirs(ctx, resourceDirs)
+				if err != nil {
+					return ctx, err
+				}
+
+				// Type check the policies like the API server would
+				warnings, err := TypeCheckPolicies(objects)
+				if err != nil {
+					return ctx, err
+				}
+				if err := typeCheckingErrors(warnings); err != nil {
+					return ctx, err
+				}
+
+				evaluator, err := NewPolicyEvaluator(ctx, objects...)
+				if err != nil {
+					return ctx, err
+				}
+				return context.WithValue(ctx, evaluatorCtxKey{}, evaluator), nil
 			},
 		)
+	} else {
+		if existingCluster {
+			// Use the existing cluster, the per-test namespaces isolate the tests from the other packages
+			testEnv.EnvConf().WithKubeconfigFile(kubeconfig)
+		} else {
+			// Create cluster, with an audit log if it is enabled
+			kindConfig := "../../testutils/kind-config.yaml"
+			if useAuditLog() {
+				auditLogDir, err = os.MkdirTemp("", kindClusterName+"-audit")
+				if err != nil {
+					return nil, err
+				}
+				kindConfig, err = writeAuditKindConfig(kindConfig, "../../testutils/audit-policy.yaml", auditLogDir)
+				if err != nil {
+					return nil, err
+				}
+			}
+			cluster = &kindCluster{name: kindClusterName}
+			if !keepLogs {
+				cluster.auditLogDir = auditLogDir
+			}
+			setupFuncs = append(
+				setupFuncs,
+				cluster.setupFunc(runID),
+				envfuncs.CreateClusterWithConfig(kind.NewProvider(), kindClusterName, kindConfig, kind.WithImage(image)),
+				cluster.createdFunc(),
+			)
+
+			if auditLogDir != "" {
+				setupFuncs = append(
+					setupFuncs,
+					func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+						return context.WithValue(ctx, auditLogCtxKey{}, &auditLog{hostDir: auditLogDir, nodeName: kindClusterName + "-control-plane"}), nil
+					},
+				)
+			}
+		}
+
+		// Apply all yaml from the policy directory
+		setupFuncs = append(
+			setupFuncs,
+			func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+				return applyResourcesFromDir(ctx, cfg, "./", "*.yaml", readinessTimeout)
+			},
+		)
+
+		// Apply extra resources
+		for dir, pattern := range extraResourcesFromDir {
+			setupFuncs = append(
+				setupFuncs,
+				func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+					return applyResourcesFromDir(ctx, cfg, dir, pattern, readinessTimeout)
+				},
+			)
+		}
 	}
 
-	// Create and apply bindings
+	// Apply the bindings of the release and create the deny, warn and audit bindings that the release does not define
 	for name, paramExists := range policyNameForBindingGeneration {
 		setupFuncs = append(
 			setupFuncs,
 			func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
-				return GenerateDenyBindingForTesting(ctx, cfg, name, paramExists, 2)
+				bindings, err := testBindings(name, paramExists)
+				if err != nil {
+					return ctx, err
+				}
+				return ctx, applyBindings(ctx, cfg, bindings, readinessTimeout)
 			},
 		)
 	}
 
+	// Record the environment of the test report and write the report at the end
+	if reportDir := os.Getenv(ReportDirEnvVar); reportDir != "" {
+		backend := BackendKind
+		if inProcess {
+			backend = BackendInProcess
+		} else if existingCluster {
+			backend = existingClusterBackend
+		}
+		setupFuncs = append(setupFuncs, reportSetupFunc(backend))
+		finishFuncs = append(finishFuncs, reportFinishFunc(reportDir))
+	}
+
 	testEnv.Setup(setupFuncs...)
 
-	// Remove the applied resources
-	finishFuncs = append(
-		finishFuncs,
-		func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
-			return deleteResourcesFromDir(ctx, cfg, "./", "*.yaml")
-		},
-	)
+	if inProcess {
+		// Stop the in-process evaluator
+		finishFuncs = append(
+			finishFuncs,
+			func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+				evaluator := PolicyEvaluatorFromContext(ctx)
+				if evaluator == nil {
+					return ctx, nil
+				}
+				defer evaluator.Close()
+
+				// Write the CEL coverage of the policies if it is requested
+				if dir := os.Getenv(CoverageDirEnvVar); dir != "" {
+					// the tests run in the directory of the policy
+					wd, err := os.Getwd()
+					if err != nil {
+						return ctx, err
+					}
+					return ctx, WriteCoverage(dir, filepath.Base(wd), evaluator.Coverage())
+				}
+				return ctx, nil
+			},
+		)
+	} else {
+		// Remove the applied resources
+		finishFuncs = append(
+			finishFuncs,
+			func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+				return deleteResourcesFromDir(ctx, cfg, "./", "*.yaml")
+			},
+		)
 
-	// Keep the logs if the flag is set
-	if keepLogs {
-		finishFuncs = append(finishFuncs, envfuncs.ExportClusterLogs(kindClusterName, "./test-logs"))
+		if existingCluster {
+			// Remove the generated bindings, the cluster is shared with the other packages
+			for name, paramExists := range policyNameForBindingGeneration {
+				finishFuncs = append(
+					finishFuncs,
+					func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+						return ctx, deleteBindingsForTesting(ctx, cfg, name, paramExists)
+					},
+				)
+			}
+		} else {
+			// Keep the logs if the flag is set
+			if keepLogs {
+				finishFuncs = append(finishFuncs, envfuncs.ExportClusterLogs(kindClusterName, "./test-logs"))
+			}
+
+			// Destroy the cluster, its run ID file and the audit log unless the logs are kept
+			finishFuncs = append(finishFuncs, cluster.finishFunc())
+		}
 	}
 
-	// Destroy the cluster
-	finishFuncs = append(finishFuncs, envfuncs.DestroyCluster(kindClusterName))
-
 	testEnv.Finish(finishFuncs...)
 
-	// Set the BeforeEachTest and AfterEachTest functions that creates and deletes a namespace for each test
+	// Set the BeforeEachTest and AfterEachTest functions that creates and deletes a namespace for each test, the
+	// cluster-scoped objects that a test persisted are deleted before its namespace
 	testEnv.BeforeEachTest(func(ctx context.Context, cfg *envconf.Config, t *testing.T) (context.Context, error) {
-		return createNSForTest(ctx, cfg, t, runID, namespaceLabels)
+		return createNSForTest(createClusterObjectsForTest(ctx), cfg, t, runID, namespaceLabels)
 	})
 	testEnv.AfterEachTest(func(ctx context.Context, cfg *envconf.Config, t *testing.T) (context.Context, error) {
+		if err := deleteClusterObjectsForTest(ctx, cfg, t); err != nil {
+			return ctx, err
+		}
 		return deleteNSForTest(ctx, cfg, t, runID)
 	})
 
+	if cluster != nil {
+		// Destroy the cluster if a test panics
+		return &kindEnvironment{Environment: testEnv, cluster: cluster}, nil
+	}
 	return testEnv, nil
 }
 
-// applyResourcesFromDir applies all the resources from the given directory
-func applyResourcesFromDir(ctx context.Context, cfg *envconf.Config, dir string, pattern string, waitSec time.Duration) (context.Context, error) {
+// applyResourcesFromDir applies all the resources from the given directory and waits until they become effective
+func applyResourcesFromDir(ctx context.Context, cfg *envconf.Config, dir string, pattern string, timeout time.Duration) (context.Context, error) {
 	r, err := resources.New(cfg.Client().RESTConfig())
 	if err != nil {
 		return ctx, err
 	}
-	err = decoder.ApplyWithManifestDir(ctx, r, dir, pattern, []resources.CreateOption{})
+	err = decoder.DecodeEachFile(ctx, os.DirFS(dir), pattern, createOrUpdateHandler(r))
 	if err != nil {
 		return ctx, err
 	}
 
 	// Wait for the resources to be registered properly
-	time.Sleep(waitSec * time.Second)
-
-	return ctx, nil
+	objects, err := decoder.DecodeAllFiles(ctx, os.DirFS(dir), pattern)
+	if err != nil {
+		return ctx, err
+	}
+	return ctx, WaitForResources(ctx, cfg, objects, timeout)
 }
 
 // deleteResourcesFromDir removes all the resources from the given directory
@@ -131,7 +287,7 @@ func deleteResourcesFromDir(ctx context.Context, cfg *envconf.Config, dir string
 	if err != nil {
 		return ctx, err
 	}
-	err = decoder.DeleteWithManifestDir(ctx, r, dir, pattern, []resources.DeleteOption{})
+	err = decoder.DecodeEachFile(ctx, os.DirFS(dir), pattern, decoder.DeleteIgnoreNotFound(r))
 	if err != nil {
 		return ctx, err
 	}
@@ -144,22 +300,79 @@ func deleteResourcesFromDir(ctx context.Context, cfg *envconf.Config, dir string
 func createNSForTest(ctx context.Context, cfg *envconf.Config, t *testing.T, runID string, namespaceLabels map[string]string) (context.Context, error) {
 	ns := envconf.RandomName(runID, 20)
 	ctx = context.WithValue(ctx, GetNamespaceKey(t), ns)
+	testReport.setNamespace(t, ns)
 
 	t.Logf("Creating NS %v for test %v", ns, t.Name())
-	nsObj := v1.Namespace{}
-	nsObj.Name = ns
-	nsObj.Labels = namespaceLabels
-	return ctx, cfg.Client().Resources().Create(ctx, &nsObj)
+	return ctx, createNamespace(ctx, cfg, ns, namespaceLabels)
 }
 
 // deleteNSForTest looks up the namespace corresponding to the given test and deletes it.
 func deleteNSForTest(ctx context.Context, cfg *envconf.Config, t *testing.T, _ string) (context.Context, error) {
 	ns := fmt.Sprint(ctx.Value(GetNamespaceKey(t)))
 	t.Logf("Deleting NS %v for test %v", ns, t.Name())
+	return ctx, deleteNamespace(ctx, cfg, ns)
+}
+
+// createNamespace creates the namespace on the cluster or in the in-process evaluator
+func createNamespace(ctx context.Context, cfg *envconf.Config, name string, labels map[string]string) error {
+	nsObj := v1.Namespace{}
+	nsObj.Name = name
+	nsObj.Labels = labels
+	if evaluator := PolicyEvaluatorFromContext(ctx); evaluator != nil {
+		nsObj.SetGroupVersionKind(v1.SchemeGroupVersion.WithKind("Namespace"))
+		return evaluator.Apply(ctx, &nsObj)
+	}
+	return cfg.Client().Resources().Create(ctx, &nsObj)
+}
 
+// deleteNamespace deletes the namespace from the cluster or from the in-process evaluator
+func deleteNamespace(ctx context.Context, cfg *envconf.Config, name string) error {
 	nsObj := v1.Namespace{}
-	nsObj.Name = ns
-	return ctx, cfg.Client().Resources().Delete(ctx, &nsObj)
+	nsObj.Name = name
+	if evaluator := PolicyEvaluatorFromContext(ctx); evaluator != nil {
+		nsObj.SetGroupVersionKind(v1.SchemeGroupVersion.WithKind("Namespace"))
+		return evaluator.Delete(ctx, &nsObj)
+	}
+	return cfg.Client().Resources().Delete(ctx, &nsObj)
+}
+
+// AddNamespaceLabels adds the labels to the given namespace (e.g. vap-library.com/POLICYNAME: warn to switch the
+// enforcement mode of a policy) and waits until the admission plugin sees them
+func AddNamespaceLabels(ctx context.Context, cfg *envconf.Config, namespace string, labels map[string]string) error {
+	if evaluator := PolicyEvaluatorFromContext(ctx); evaluator != nil {
+		nsObj, err := evaluator.Get(v1.SchemeGroupVersion.WithKind("Namespace"), "", namespace)
+		if err != nil {
+			return err
+		}
+		nsObj.SetLabels(mergeLabels(nsObj.GetLabels(), labels))
+		return evaluator.Update(ctx, nsObj)
+	}
+
+	nsObj := v1.Namespace{}
+	if err := cfg.Client().Resources().Get(ctx, namespace, "", &nsObj); err != nil {
+		return err
+	}
+	nsObj.Labels = mergeLabels(nsObj.Labels, labels)
+	if err := cfg.Client().Resources().Update(ctx, &nsObj); err != nil {
+		return err
+	}
+
+	timeout, err := ReadinessTimeout()
+	if err != nil {
+		return err
+	}
+	return WaitForNamespaceLabels(ctx, cfg, namespace, labels, timeout)
+}
+
+func mergeLabels(labels map[string]string, extra map[string]string) map[string]string {
+	merged := map[string]string{}
+	for k, v := range labels {
+		merged[k] = v
+	}
+	for k, v := range extra {
+		merged[k] = v
+	}
+	return merged
 }
 
 // GetNamespaceKey returns the context key for a given test
@@ -173,59 +386,90 @@ func GetNamespaceKey(t *testing.T) NamespaceCtxKey {
 	return NamespaceCtxKey(t.Name())
 }
 
-// ApplyK8sResourceFromYAML applies a k8s resource from a yaml string
-func ApplyK8sResourceFromYAML(ctx context.Context, cfg *envconf.Config, yaml string) error {
-	r, err := resources.New(cfg.Client().RESTConfig())
-	if err != nil {
-		return err
-	}
-
+// ApplyK8sResourceFromYAML applies a k8s resource from a yaml string. With the in-process backend the resource is
+// sent to the policy evaluator instead of the cluster. The request is sent with server-side dry-run unless it is
+// disabled with DryRunEnvVar or WithoutDryRun, so the admission policies run but nothing is persisted.
+func ApplyK8sResourceFromYAML(ctx context.Context, cfg *envconf.Config, yaml string, opts ...resources.CreateOption) error {
 	obj, err := decoder.DecodeAny(strings.NewReader(yaml))
 	if err != nil {
 		return err
 	}
-	handler := decoder.CreateHandler(r)
-	return handler(ctx, obj)
+
+	return applyK8sResource(ctx, cfg, obj, testObjectOptions(opts)...)
 }
 
-func GenerateDenyBindingForTesting(ctx context.Context, cfg *envconf.Config, policyName string, paramRef bool, waitSec time.Duration) (context.Context, error) {
+// WithDryRun sends the request with server-side dry-run (DryRun: All)
+func WithDryRun(opts *metav1.CreateOptions) {
+	opts.DryRun = []string{metav1.DryRunAll}
+}
 
-	var sb strings.Builder
+// WithoutDryRun persists the object even if dry-run is the default, e.g. for a pod that is patched later by the test
+func WithoutDryRun(opts *metav1.CreateOptions) {
+	opts.DryRun = nil
+}
 
-	var binding string = `
-apiVersion: admissionregistration.k8s.io/v1
-kind: ValidatingAdmissionPolicyBinding
-metadata:
-  name: %s-deny.vap-library.com
-spec:
-  matchResources:
-    matchPolicy: Equivalent
-    namespaceSelector:
-      matchLabels:
-        vap-library.com/%s: deny
-    objectSelector: {}
-  policyName: %s.vap-library.com
-  validationActions:
-  - Deny
-  - Audit`
+// testObjectOptions returns the create options of a test object: the default dry-run option and the given options
+func testObjectOptions(opts []resources.CreateOption) []resources.CreateOption {
+	if !useDryRun() {
+		return opts
+	}
+	return append([]resources.CreateOption{WithDryRun}, opts...)
+}
 
-	sb.WriteString(fmt.Sprintf(binding, policyName, policyName, policyName))
+// useDryRun returns true unless the dry-run of the test objects is disabled with DryRunEnvVar
+func useDryRun() bool {
+	enabled, err := strconv.ParseBool(os.Getenv(DryRunEnvVar))
+	return err != nil || enabled
+}
 
-	if paramRef {
-		sb.WriteString(fmt.Sprintf(`
-  paramRef:
-    name: %s.vap-library.com
-    parameterNotFoundAction: Deny`, policyName))
+// applyK8sResource creates the object on the cluster or in the in-process evaluator. Persisted cluster-scoped objects
+// are deleted after the test.
+func applyK8sResource(ctx context.Context, cfg *envconf.Config, obj k8s.Object, opts ...resources.CreateOption) error {
+	testReport.recordObject(obj)
+	if evaluator := PolicyEvaluatorFromContext(ctx); evaluator != nil {
+		if err := evaluator.Apply(ctx, obj, opts...); err != nil {
+			return err
+		}
+		trackClusterObject(ctx, cfg, obj, opts)
+		return nil
 	}
 
-	err := ApplyK8sResourceFromYAML(ctx, cfg, sb.String())
-
+	r, err := resources.New(cfg.Client().RESTConfig())
 	if err != nil {
-		return ctx, err
-	} else {
-		// wait for the resources to be registered properly
-		time.Sleep(waitSec * time.Second)
-		return ctx, nil
+		return err
+	}
+	handler := decoder.CreateHandler(r, opts...)
+	if err := handler(ctx, obj); err != nil {
+		return err
 	}
+	trackClusterObject(ctx, cfg, obj, opts)
+	return nil
+}
+
+// GenerateDenyBindingForTesting creates a Deny binding for the policy that is enforced in namespaces labelled with
+// vap-library.com/<policyName>: deny and waits until the binding becomes effective
+func GenerateDenyBindingForTesting(ctx context.Context, cfg *envconf.Config, policyName string, paramRef bool, timeout time.Duration) (context.Context, error) {
+	return generateBindingForTesting(ctx, cfg, policyName, ModeDeny, paramRef, timeout)
+}
+
+// GenerateWarnBindingForTesting creates a Warn binding for the policy that is enforced in namespaces labelled with
+// vap-library.com/<policyName>: warn and waits until the binding becomes effective
+func GenerateWarnBindingForTesting(ctx context.Context, cfg *envconf.Config, policyName string, paramRef bool, timeout time.Duration) (context.Context, error) {
+	return generateBindingForTesting(ctx, cfg, policyName, ModeWarn, paramRef, timeout)
+}
+
+// GenerateAuditBindingForTesting creates an Audit binding for the policy that is enforced in namespaces labelled with
+// vap-library.com/<policyName>: audit and waits until the binding becomes effective
+func GenerateAuditBindingForTesting(ctx context.Context, cfg *envconf.Config, policyName string, paramRef bool, timeout time.Duration) (context.Context, error) {
+	return generateBindingForTesting(ctx, cfg, policyName, ModeAudit, paramRef, timeout)
+}
 
+// generateBindingForTesting creates a binding like the ones in the release with the given enforcement mode (the value
+// of the namespace label)
+func generateBindingForTesting(ctx context.Context, cfg *envconf.Config, policyName string, mode string, paramRef bool, timeout time.Duration) (context.Context, error) {
+	builder := NewBindingBuilder(policyName, mode)
+	if paramRef {
+		builder.WithParamRef()
+	}
+	return ctx, builder.Apply(ctx, cfg, timeout)
 }
@@ -0,0 +1,419 @@
+package testutils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/admission/plugin/policy/validating"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+)
+
+// CheckPolicyTypeChecking returns an error if the API server found problems in the CEL expressions of the
+// ValidatingAdmissionPolicy (status.typeChecking.expressionWarnings). Call it after WaitForPolicy, the warnings are
+// written together with the observed generation.
+func CheckPolicyTypeChecking(ctx context.Context, cfg *envconf.Config, name string) error {
+	policy := &admissionregistrationv1.ValidatingAdmissionPolicy{}
+	if err := cfg.Client().Resources().Get(ctx, name, "", policy); err != nil {
+		return err
+	}
+	if policy.Status.TypeChecking == nil {
+		return nil
+	}
+	mapper := cfg.Client().Resources().GetControllerRuntimeClient().RESTMapper()
+	return typeCheckingError(name, unguardedWarnings(policy.Status.TypeChecking.ExpressionWarnings, typeCheckedKinds(policy, mapper)))
+}
+
+// TypeCheckPolicies type checks the CEL expressions of the ValidatingAdmissionPolicies among the objects the same way
+// the API server does and returns the warnings per policy. The schemas of the custom resources and parameters are
+// taken from the CustomResourceDefinitions among the objects and the schemas of the built-in kinds are derived from
+// their Go types, so misspelled field paths are reported like on a cluster (see CheckPolicyTypeChecking). The warnings
+// of the expressions that compile against one of the kinds the policy matches are dropped (see unguardedWarnings).
+func TypeCheckPolicies(objects []k8s.Object) (map[string][]admissionregistrationv1.ExpressionWarning, error) {
+	mapper, resolver, err := policyTypes(objects)
+	if err != nil {
+		return nil, err
+	}
+
+	checker := &validating.TypeChecker{SchemaResolver: resolver, RestMapper: mapper}
+	warnings := map[string][]admissionregistrationv1.ExpressionWarning{}
+	for _, obj := range objects {
+		if obj.GetObjectKind().GroupVersionKind().Kind != "ValidatingAdmissionPolicy" {
+			continue
+		}
+		policy := &admissionregistrationv1.ValidatingAdmissionPolicy{}
+		if err := toTyped(obj, policy); err != nil {
+			return nil, err
+		}
+		if w := unguardedWarnings(checker.Check(policy), typeCheckedKinds(policy, mapper)); len(w) > 0 {
+			warnings[policy.Name] = w
+		}
+	}
+	return warnings, nil
+}
+
+// TypeCheckPoliciesOnCluster creates the CustomResourceDefinitions and a copy of every ValidatingAdmissionPolicy among
+// the objects on the cluster and returns the type checking warnings of the API server per policy. The copies have a
+// random name suffix so they do not replace the policies of the tests running on the same cluster, and they are
+// removed at the end. The CustomResourceDefinitions are left on the cluster. Like TypeCheckPolicies, the warnings of
+// the expressions that compile against one of the kinds the policy matches are dropped.
+func TypeCheckPoliciesOnCluster(ctx context.Context, cfg *envconf.Config, objects []k8s.Object, timeout time.Duration) (map[string][]admissionregistrationv1.ExpressionWarning, error) {
+	r, err := resources.New(cfg.Client().RESTConfig())
+	if err != nil {
+		return nil, err
+	}
+
+	var crds []k8s.Object
+	var policies []*admissionregistrationv1.ValidatingAdmissionPolicy
+	for _, obj := range objects {
+		switch obj.GetObjectKind().GroupVersionKind().Kind {
+		case "CustomResourceDefinition":
+			crds = append(crds, obj)
+		case "ValidatingAdmissionPolicy":
+			policy := &admissionregistrationv1.ValidatingAdmissionPolicy{}
+			if err := toTyped(obj, policy); err != nil {
+				return nil, err
+			}
+			policies = append(policies, policy)
+		}
+	}
+
+	for _, crd := range crds {
+		if err := createOrUpdate(ctx, r, crd); err != nil {
+			return nil, err
+		}
+	}
+	if err := WaitForResources(ctx, cfg, crds, timeout); err != nil {
+		return nil, err
+	}
+
+	suffix := envconf.RandomName("-typecheck", 16)
+	warnings := map[string][]admissionregistrationv1.ExpressionWarning{}
+	for _, policy := range policies {
+		name := policy.Name
+		policy.Name += suffix
+		if err := r.Create(ctx, policy); err != nil {
+			return nil, err
+		}
+		err := WaitForPolicy(ctx, cfg, policy.Name, timeout)
+		if err == nil {
+			err = r.Get(ctx, policy.Name, "", policy)
+		}
+		_ = r.Delete(ctx, policy)
+		if err != nil {
+			return nil, err
+		}
+		if policy.Status.TypeChecking == nil {
+			continue
+		}
+		if w := unguardedWarnings(policy.Status.TypeChecking.ExpressionWarnings, typeCheckedKinds(policy, r.GetControllerRuntimeClient().RESTMapper())); len(w) > 0 {
+			warnings[name] = w
+		}
+	}
+	return warnings, nil
+}
+
+// kindResult matches the start of the result of a kind in an expression warning, e.g. "apps/v1, Kind=Deployment: "
+var kindResult = regexp.MustCompile(`(?m)^(\S*), Kind=(\S+): `)
+
+// unguardedWarnings drops the warnings of the expressions that compile against at least one of the kinds. The
+// expressions of the policies that match several kinds are guarded by the kind (e.g. object.kind != 'Pod' ||
+// object.spec...), and the API server type checks them against every kind, so they have warnings for the kinds they
+// exclude. A misspelled field path fails against every kind and is kept.
+func unguardedWarnings(warnings []admissionregistrationv1.ExpressionWarning, kinds []schema.GroupVersionKind) []admissionregistrationv1.ExpressionWarning {
+	var unguarded []admissionregistrationv1.ExpressionWarning
+	for _, w := range warnings {
+		failed := map[schema.GroupVersionKind]bool{}
+		for _, match := range kindResult.FindAllStringSubmatch(w.Warning, -1) {
+			gv, err := schema.ParseGroupVersion(match[1])
+			if err == nil {
+				failed[gv.WithKind(match[2])] = true
+			}
+		}
+		if !slices.ContainsFunc(kinds, func(kind schema.GroupVersionKind) bool { return !failed[kind] }) {
+			unguarded = append(unguarded, w)
+		}
+	}
+	return unguarded
+}
+
+// maxTypesToCheck is the number of kinds of a resource rule after which the API server stops collecting kinds to type
+// check a policy against
+const maxTypesToCheck = 10
+
+// typeCheckedKinds returns the kinds the API server type checks the policy against: the kinds of the resource rules
+// without wildcards and subresources, until a rule has maxTypesToCheck kinds
+func typeCheckedKinds(policy *admissionregistrationv1.ValidatingAdmissionPolicy, mapper meta.RESTMapper) []schema.GroupVersionKind {
+	if policy.Spec.MatchConstraints == nil {
+		return nil
+	}
+
+	var kinds []schema.GroupVersionKind
+	for _, rule := range policy.Spec.MatchConstraints.ResourceRules {
+		if slices.ContainsFunc(rule.APIGroups, isWildcard) || slices.ContainsFunc(rule.APIVersions, isWildcard) {
+			continue
+		}
+		groups, versions := slices.Sorted(slices.Values(rule.APIGroups)), slices.Sorted(slices.Values(rule.APIVersions))
+		resources := slices.Sorted(slices.Values(rule.Resources))
+		count := 0
+		for _, group := range groups {
+			for _, version := range versions {
+				for _, resource := range resources {
+					if strings.ContainsAny(resource, "*/") {
+						continue
+					}
+					resolved, err := mapper.KindsFor(schema.GroupVersionResource{Group: group, Version: version, Resource: resource})
+					if err != nil {
+						continue
+					}
+					for _, kind := range resolved {
+						if !slices.Contains(kinds, kind) {
+							kinds = append(kinds, kind)
+						}
+						if count++; count == maxTypesToCheck {
+							return kinds
+						}
+					}
+				}
+			}
+		}
+	}
+	return kinds
+}
+
+// isWildcard reports whether the group or version of a resource rule is a wildcard
+func isWildcard(s string) bool {
+	return strings.Contains(s, "*")
+}
+
+// typeCheckingError returns an error that lists the expression warnings of the policy, or nil if there are none
+func typeCheckingError(name string, warnings []admissionregistrationv1.ExpressionWarning) error {
+	if len(warnings) == 0 {
+		return nil
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "ValidatingAdmissionPolicy %s has CEL type checking warnings:", name)
+	for _, w := range warnings {
+		fmt.Fprintf(&sb, "\n%s: %s", w.FieldRef, w.Warning)
+	}
+	return fmt.Errorf("%s", sb.String())
+}
+
+// typeCheckingErrors returns an error that lists the expression warnings of all the policies, or nil if there are none
+func typeCheckingErrors(warnings map[string][]admissionregistrationv1.ExpressionWarning) error {
+	names := make([]string, 0, len(warnings))
+	for name := range warnings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var messages []string
+	for _, name := range names {
+		if err := typeCheckingError(name, warnings[name]); err != nil {
+			messages = append(messages, err.Error())
+		}
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s", strings.Join(messages, "\n"))
+}
+
+// policyTypes returns a REST mapper of the built-in kinds and the custom resources, and a resolver of the schemas of
+// the built-in kinds and of the custom resources from the CustomResourceDefinitions among the objects
+func policyTypes(objects []k8s.Object) (*meta.DefaultRESTMapper, *goTypeSchemaResolver, error) {
+	resolver := &goTypeSchemaResolver{crds: map[schema.GroupVersionKind]*spec.Schema{}, scheme: clientgoscheme.Scheme}
+	mapper := meta.NewDefaultRESTMapper(nil)
+	for _, mapping := range builtinMappings {
+		mapper.AddSpecific(mapping.GroupVersionKind, mapping.Resource, mapping.Resource.GroupVersion().WithResource(strings.ToLower(mapping.GroupVersionKind.Kind)), mapping.Scope)
+	}
+	for _, obj := range objects {
+		if obj.GetObjectKind().GroupVersionKind().Kind != "CustomResourceDefinition" {
+			continue
+		}
+		mappings, err := mappingsFromCRD(obj)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, mapping := range mappings {
+			mapper.AddSpecific(mapping.GroupVersionKind, mapping.Resource, mapping.Resource.GroupVersion().WithResource(strings.ToLower(mapping.GroupVersionKind.Kind)), mapping.Scope)
+		}
+		if err := resolver.addCRD(obj); err != nil {
+			return nil, nil, err
+		}
+	}
+	return mapper, resolver, nil
+}
+
+// goTypeSchemaResolver resolves the schemas of custom resources from their CustomResourceDefinitions and the schemas
+// of the built-in kinds from their Go types
+type goTypeSchemaResolver struct {
+	crds   map[schema.GroupVersionKind]*spec.Schema
+	scheme *runtime.Scheme
+}
+
+// ResolveSchema implements resolver.SchemaResolver. Kinds that are neither built-in nor custom resources of the
+// objects have no schema, the API server skips them when it type checks a policy.
+func (r *goTypeSchemaResolver) ResolveSchema(gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	if s, ok := r.crds[gvk]; ok {
+		return s, nil
+	}
+	obj, err := r.scheme.New(gvk)
+	if err != nil {
+		return nil, fmt.Errorf("no schema for %s: %w", gvk, err)
+	}
+	return schemaFromType(reflect.TypeOf(obj).Elem(), map[reflect.Type]bool{}), nil
+}
+
+// schemaFromType derives the OpenAPI schema of a type of the Kubernetes API from its fields and json tags. The
+// schema has no required fields, limits or enums, so the sizes are at least as large as the ones of the published
+// schema of the API server.
+func schemaFromType(t reflect.Type, seen map[reflect.Type]bool) *spec.Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	// the types with a custom json format declare their schema type, e.g. Quantity, IntOrString and Time
+	value := reflect.New(t).Interface()
+	if _, ok := value.(interface{ OpenAPIV3OneOfTypes() []string }); ok {
+		return dynamicSchema()
+	}
+	if typer, ok := value.(interface{ OpenAPISchemaType() []string }); ok {
+		s := &spec.Schema{SchemaProps: spec.SchemaProps{Type: typer.OpenAPISchemaType()}}
+		if formatter, ok := value.(interface{ OpenAPISchemaFormat() string }); ok {
+			s.Format = formatter.OpenAPISchemaFormat()
+		}
+		if s.Type.Contains("object") {
+			s.Extensions = spec.Extensions{"x-kubernetes-preserve-unknown-fields": true}
+		}
+		return s
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return spec.StringProperty()
+	case reflect.Bool:
+		return spec.BooleanProperty()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return spec.Int64Property()
+	case reflect.Float32, reflect.Float64:
+		return spec.Float64Property()
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return spec.StrFmtProperty("byte")
+		}
+		return spec.ArrayProperty(schemaFromType(t.Elem(), seen))
+	case reflect.Map:
+		return spec.MapProperty(schemaFromType(t.Elem(), seen))
+	case reflect.Struct:
+		if seen[t] {
+			// recursive types are not expanded
+			return dynamicSchema()
+		}
+		seen[t] = true
+		defer delete(seen, t)
+
+		s := &spec.Schema{SchemaProps: spec.SchemaProps{Type: spec.StringOrArray{"object"}, Properties: map[string]spec.Schema{}}}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" || !field.IsExported() && !field.Anonymous {
+				continue
+			}
+			if field.Anonymous && name == "" || strings.Contains(options, "inline") {
+				for property, propertySchema := range schemaFromType(field.Type, seen).Properties {
+					s.Properties[property] = propertySchema
+				}
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			s.Properties[name] = *schemaFromType(field.Type, seen)
+		}
+		return s
+	default:
+		return dynamicSchema()
+	}
+}
+
+// addCRD adds the schemas of every version of the CustomResourceDefinition. The API server publishes the schemas with
+// the complete ObjectMeta, so the common metadata fields are added like it does.
+func (r *goTypeSchemaResolver) addCRD(obj k8s.Object) error {
+	u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return err
+	}
+	group, _, _ := unstructured.NestedString(u, "spec", "group")
+	kind, _, _ := unstructured.NestedString(u, "spec", "names", "kind")
+	versions, _, _ := unstructured.NestedSlice(u, "spec", "versions")
+
+	for _, v := range versions {
+		version, _ := v.(map[string]interface{})
+		name, _, _ := unstructured.NestedString(version, "name")
+		openAPIV3Schema, found, _ := unstructured.NestedMap(version, "schema", "openAPIV3Schema")
+		if !found {
+			continue
+		}
+
+		raw, err := json.Marshal(openAPIV3Schema)
+		if err != nil {
+			return err
+		}
+		s := &spec.Schema{}
+		if err := json.Unmarshal(raw, s); err != nil {
+			return fmt.Errorf("failed to parse the schema of %s version %s: %w", obj.GetName(), name, err)
+		}
+		if s.Properties == nil {
+			s.Properties = map[string]spec.Schema{}
+		}
+		s.Properties["metadata"] = *objectMetaSchema()
+		r.crds[schema.GroupVersionKind{Group: group, Version: name, Kind: kind}] = s
+	}
+	return nil
+}
+
+// objectMetaSchema returns the schema of the ObjectMeta fields that policies commonly use
+func objectMetaSchema() *spec.Schema {
+	stringMap := spec.MapProperty(spec.StringProperty())
+	return &spec.Schema{
+		SchemaProps: spec.SchemaProps{
+			Type: spec.StringOrArray{"object"},
+			Properties: map[string]spec.Schema{
+				"name":              *spec.StringProperty(),
+				"generateName":      *spec.StringProperty(),
+				"namespace":         *spec.StringProperty(),
+				"uid":               *spec.StringProperty(),
+				"resourceVersion":   *spec.StringProperty(),
+				"creationTimestamp": *spec.StringProperty(),
+				"labels":            *stringMap,
+				"annotations":       *stringMap,
+				"finalizers":        *spec.ArrayProperty(spec.StringProperty()),
+			},
+		},
+	}
+}
+
+// dynamicSchema returns a schema that CEL declares as a dynamic type (x-kubernetes-int-or-string is the only schema
+// that is mapped to dyn without looking at its properties)
+func dynamicSchema() *spec.Schema {
+	return &spec.Schema{
+		VendorExtensible: spec.VendorExtensible{Extensions: spec.Extensions{"x-kubernetes-int-or-string": true}},
+	}
+}
@@ -0,0 +1,123 @@
+package testutils
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/e2e-framework/klient/decoder"
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+)
+
+// unenforcedMode is the value of the vap-library.com/<policy> namespace label that no binding selects
+const unenforcedMode = "unenforced"
+
+// ApplyK8sResourceFromYAMLBeforeEnforcement creates a k8s resource from a yaml string while the policy is not
+// enforced in the namespace of the resource: the vap-library.com/<policyName> label of the namespace is switched off,
+// the resource is persisted and the label is restored, also when the resource cannot be created. Use it to create a
+// non-compliant object that existed before the policy was enforced and then test its updates.
+func ApplyK8sResourceFromYAMLBeforeEnforcement(ctx context.Context, cfg *envconf.Config, policyName string, yaml string) (err error) {
+	obj, err := decoder.DecodeAny(strings.NewReader(yaml))
+	if err != nil {
+		return err
+	}
+
+	label := "vap-library.com/" + strings.TrimSuffix(policyName, policyNameSuffix)
+	mode, err := namespaceLabel(ctx, cfg, obj.GetNamespace(), label)
+	if err != nil {
+		return err
+	}
+
+	if mode == "" {
+		// the policy is not enforced in the namespace anyway
+		return applyK8sResource(ctx, cfg, obj)
+	}
+
+	if err := AddNamespaceLabels(ctx, cfg, obj.GetNamespace(), map[string]string{label: unenforcedMode}); err != nil {
+		return err
+	}
+	defer func() {
+		// restore the mode, the following tests of the namespace expect the policy to be enforced
+		err = errors.Join(err, AddNamespaceLabels(ctx, cfg, obj.GetNamespace(), map[string]string{label: mode}))
+	}()
+
+	return applyK8sResource(ctx, cfg, obj)
+}
+
+// UpdateK8sResourceFromYAML updates an existing k8s resource from a yaml string. The policies see the stored resource
+// as oldObject and the resource from the yaml as object. Like ApplyK8sResourceFromYAML, the request is sent with
+// server-side dry-run unless it is disabled with DryRunEnvVar.
+func UpdateK8sResourceFromYAML(ctx context.Context, cfg *envconf.Config, yaml string) error {
+	obj, err := decoder.DecodeAny(strings.NewReader(yaml))
+	if err != nil {
+		return err
+	}
+
+	testReport.recordObject(obj)
+	var opts []resources.UpdateOption
+	if useDryRun() {
+		opts = append(opts, func(o *metav1.UpdateOptions) { o.DryRun = []string{metav1.DryRunAll} })
+	}
+
+	if evaluator := PolicyEvaluatorFromContext(ctx); evaluator != nil {
+		return evaluator.Update(ctx, obj, opts...)
+	}
+
+	r, err := resources.New(cfg.Client().RESTConfig())
+	if err != nil {
+		return err
+	}
+	existing := obj.DeepCopyObject().(k8s.Object)
+	if err := r.Get(ctx, obj.GetName(), obj.GetNamespace(), existing); err != nil {
+		return err
+	}
+	obj.SetResourceVersion(existing.GetResourceVersion())
+	return r.Update(ctx, obj, opts...)
+}
+
+// PatchK8sResourceFromYAML patches the existing k8s resource that the yaml string identifies (only the apiVersion,
+// kind, name and namespace are used). Like ApplyK8sResourceFromYAML, the request is sent with server-side dry-run
+// unless it is disabled with DryRunEnvVar.
+func PatchK8sResourceFromYAML(ctx context.Context, cfg *envconf.Config, yaml string, patch k8s.Patch) error {
+	obj, err := decoder.DecodeAny(strings.NewReader(yaml))
+	if err != nil {
+		return err
+	}
+
+	testReport.recordObject(obj)
+	var opts []resources.PatchOption
+	if useDryRun() {
+		opts = append(opts, func(o *metav1.PatchOptions) { o.DryRun = []string{metav1.DryRunAll} })
+	}
+
+	if evaluator := PolicyEvaluatorFromContext(ctx); evaluator != nil {
+		return evaluator.Patch(ctx, obj, patch, opts...)
+	}
+
+	r, err := resources.New(cfg.Client().RESTConfig())
+	if err != nil {
+		return err
+	}
+	return r.Patch(ctx, obj, patch, opts...)
+}
+
+// namespaceLabel returns the value of a label of the namespace
+func namespaceLabel(ctx context.Context, cfg *envconf.Config, namespace string, key string) (string, error) {
+	if evaluator := PolicyEvaluatorFromContext(ctx); evaluator != nil {
+		nsObj, err := evaluator.Get(v1.SchemeGroupVersion.WithKind("Namespace"), "", namespace)
+		if err != nil {
+			return "", err
+		}
+		return nsObj.GetLabels()[key], nil
+	}
+
+	nsObj := v1.Namespace{}
+	if err := cfg.Client().Resources().Get(ctx, namespace, "", &nsObj); err != nil {
+		return "", err
+	}
+	return nsObj.Labels[key], nil
+}
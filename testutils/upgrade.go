@@ -0,0 +1,271 @@
+package testutils
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/e2e-framework/klient/decoder"
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/yaml"
+)
+
+// UpgradeFromEnvVar selects the release that the upgrade tests install before the current bundle: a tag or any other
+// git revision. By default it is the latest v* tag that is not the current version.
+const UpgradeFromEnvVar = "VAPLIB_UPGRADE_FROM"
+
+// ReleaseBundleDir is the generated release bundle, relative to the directory of a test package
+const ReleaseBundleDir = "../../release-process/release"
+
+// releaseBundlePath and versionPath are relative to the root of the git repository
+const (
+	releaseBundlePath = "release-process/release"
+	versionPath       = "release-process/version"
+)
+
+// PreviousRelease returns the git revision of the release to upgrade from: the value of UpgradeFromEnvVar or the
+// latest v* tag reachable from HEAD that is not the version in release-process/version. It returns an empty string
+// if there is no such tag, e.g. in a shallow clone without tags.
+func PreviousRelease() (string, error) {
+	if revision := os.Getenv(UpgradeFromEnvVar); revision != "" {
+		return revision, nil
+	}
+
+	version, err := git("show", "HEAD:"+versionPath)
+	if err != nil {
+		return "", err
+	}
+	tag, err := git("describe", "--tags", "--abbrev=0", "--match", "v*", "--exclude", strings.TrimSpace(string(version)), "HEAD")
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			// no matching tag
+			return "", nil
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(tag)), nil
+}
+
+// ExtractRelease writes the files of the release bundle at the git revision to dir, as they are listed in its
+// kustomization.yaml. The kustomization.yaml is left out, so every yaml file in dir is a part of the bundle.
+func ExtractRelease(revision string, dir string) error {
+	kustomization, err := git("show", revision+":"+path.Join(releaseBundlePath, "kustomization.yaml"))
+	if err != nil {
+		return fmt.Errorf("failed to read the release bundle at %s: %w", revision, err)
+	}
+	files, err := kustomizationResources(kustomization)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		data, err := git("show", revision+":"+path.Join(releaseBundlePath, file))
+		if err != nil {
+			return fmt.Errorf("failed to read %s of the release bundle at %s: %w", file, revision, err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, file), data, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DecodeReleaseBundle decodes the objects of the release bundle in dir (e.g. ReleaseBundleDir) in the order of the
+// files in its kustomization.yaml
+func DecodeReleaseBundle(ctx context.Context, dir string) ([]k8s.Object, error) {
+	kustomization, err := os.ReadFile(filepath.Join(dir, "kustomization.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	files, err := kustomizationResources(kustomization)
+	if err != nil {
+		return nil, err
+	}
+
+	var objects []k8s.Object
+	for _, file := range files {
+		objs, err := decoder.DecodeAllFiles(ctx, os.DirFS(dir), file)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, objs...)
+	}
+	return objects, nil
+}
+
+// ApplyReleaseBundle applies the objects of a release bundle on top of the installed one, like `kubectl apply -k`
+// does: objects are created or updated and nothing is deleted. It waits until the bundle becomes effective. With the
+// in-process backend, a CustomResourceDefinition of a kind that the evaluator was not started with is stored but its
+// objects cannot be created.
+func ApplyReleaseBundle(ctx context.Context, cfg *envconf.Config, objects []k8s.Object, timeout time.Duration) error {
+	if evaluator := PolicyEvaluatorFromContext(ctx); evaluator != nil {
+		for _, obj := range objects {
+			err := evaluator.Apply(ctx, obj)
+			if apierrors.IsAlreadyExists(err) {
+				err = evaluator.Update(ctx, obj)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to apply %s %s: %w", obj.GetObjectKind().GroupVersionKind().Kind, obj.GetName(), err)
+			}
+		}
+		return nil
+	}
+
+	r, err := resources.New(cfg.Client().RESTConfig())
+	if err != nil {
+		return err
+	}
+	for _, obj := range objects {
+		if err := createOrUpdate(ctx, r, obj.DeepCopyObject().(k8s.Object)); err != nil {
+			return fmt.Errorf("failed to apply %s %s: %w", obj.GetObjectKind().GroupVersionKind().Kind, obj.GetName(), err)
+		}
+	}
+	return WaitForResources(ctx, cfg, objects, timeout)
+}
+
+// CompareReleases returns the problems of an upgrade from the old release bundle to the new one that `kubectl apply`
+// does not report: CustomResourceDefinitions, policies and bindings that were removed or renamed are left behind and
+// keep being enforced, parameter kinds and versions that are no longer served make the existing parameters
+// unreadable, and bindings that reference another parameter no longer find the existing ones.
+func CompareReleases(oldObjects []k8s.Object, newObjects []k8s.Object) ([]error, error) {
+	oldBundle, err := indexBundle(oldObjects)
+	if err != nil {
+		return nil, err
+	}
+	newBundle, err := indexBundle(newObjects)
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []error
+	for name, oldCRD := range oldBundle.crds {
+		newCRD, ok := newBundle.crds[name]
+		if !ok {
+			problems = append(problems, fmt.Errorf("CustomResourceDefinition %s was removed, the existing parameters are left behind", name))
+			continue
+		}
+		if oldCRD.Spec.Names.Kind != newCRD.Spec.Names.Kind {
+			problems = append(problems, fmt.Errorf("CustomResourceDefinition %s renamed kind %s to %s", name, oldCRD.Spec.Names.Kind, newCRD.Spec.Names.Kind))
+		}
+		if oldCRD.Spec.Scope != newCRD.Spec.Scope {
+			problems = append(problems, fmt.Errorf("CustomResourceDefinition %s changed its scope from %s to %s, the API server rejects the update", name, oldCRD.Spec.Scope, newCRD.Spec.Scope))
+		}
+		for _, oldVersion := range oldCRD.Spec.Versions {
+			if !oldVersion.Served {
+				continue
+			}
+			if !servesVersion(newCRD, oldVersion.Name) {
+				problems = append(problems, fmt.Errorf("CustomResourceDefinition %s no longer serves version %s, the existing parameters of that version cannot be read", name, oldVersion.Name))
+			}
+		}
+	}
+
+	for name, oldPolicy := range oldBundle.policies {
+		newPolicy, ok := newBundle.policies[name]
+		if !ok {
+			problems = append(problems, fmt.Errorf("ValidatingAdmissionPolicy %s was removed or renamed, kubectl apply leaves it in the cluster", name))
+			continue
+		}
+		oldParamKind, newParamKind := oldPolicy.Spec.ParamKind, newPolicy.Spec.ParamKind
+		if oldParamKind != nil && (newParamKind == nil || *oldParamKind != *newParamKind) {
+			problems = append(problems, fmt.Errorf("ValidatingAdmissionPolicy %s changed its paramKind from %v to %v, the existing parameters are ignored", name, *oldParamKind, newParamKind))
+		}
+	}
+
+	for name, oldBinding := range oldBundle.bindings {
+		newBinding, ok := newBundle.bindings[name]
+		if !ok {
+			problems = append(problems, fmt.Errorf("ValidatingAdmissionPolicyBinding %s was removed or renamed, kubectl apply leaves it in force", name))
+			continue
+		}
+		oldParamRef, newParamRef := oldBinding.Spec.ParamRef, newBinding.Spec.ParamRef
+		if oldParamRef != nil && newParamRef != nil && (oldParamRef.Name != newParamRef.Name || oldParamRef.Namespace != newParamRef.Namespace) {
+			problems = append(problems, fmt.Errorf("ValidatingAdmissionPolicyBinding %s references the parameter %q instead of %q, the existing parameters are not found", name, newParamRef.Name, oldParamRef.Name))
+		}
+	}
+	return problems, nil
+}
+
+// releaseBundle are the objects of a release bundle by name
+type releaseBundle struct {
+	crds     map[string]*apiextensionsv1.CustomResourceDefinition
+	policies map[string]*admissionregistrationv1.ValidatingAdmissionPolicy
+	bindings map[string]*admissionregistrationv1.ValidatingAdmissionPolicyBinding
+}
+
+func indexBundle(objects []k8s.Object) (*releaseBundle, error) {
+	bundle := &releaseBundle{
+		crds:     map[string]*apiextensionsv1.CustomResourceDefinition{},
+		policies: map[string]*admissionregistrationv1.ValidatingAdmissionPolicy{},
+		bindings: map[string]*admissionregistrationv1.ValidatingAdmissionPolicyBinding{},
+	}
+	for _, obj := range objects {
+		var err error
+		switch obj.GetObjectKind().GroupVersionKind().Kind {
+		case "CustomResourceDefinition":
+			crd := &apiextensionsv1.CustomResourceDefinition{}
+			err = toTyped(obj, crd)
+			bundle.crds[crd.Name] = crd
+		case "ValidatingAdmissionPolicy":
+			policy := &admissionregistrationv1.ValidatingAdmissionPolicy{}
+			err = toTyped(obj, policy)
+			bundle.policies[policy.Name] = policy
+		case "ValidatingAdmissionPolicyBinding":
+			binding := &admissionregistrationv1.ValidatingAdmissionPolicyBinding{}
+			err = toTyped(obj, binding)
+			bundle.bindings[binding.Name] = binding
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return bundle, nil
+}
+
+func servesVersion(crd *apiextensionsv1.CustomResourceDefinition, name string) bool {
+	for _, version := range crd.Spec.Versions {
+		if version.Name == name && version.Served {
+			return true
+		}
+	}
+	return false
+}
+
+// kustomizationResources returns the resources of a kustomization.yaml
+func kustomizationResources(data []byte) ([]string, error) {
+	var kustomization struct {
+		Resources []string `json:"resources"`
+	}
+	if err := yaml.Unmarshal(data, &kustomization); err != nil {
+		return nil, err
+	}
+	if len(kustomization.Resources) == 0 {
+		return nil, errors.New("the kustomization.yaml of the release bundle has no resources")
+	}
+	return kustomization.Resources, nil
+}
+
+// git runs git in the working directory and returns its output
+func git(args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
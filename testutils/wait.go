@@ -0,0 +1,383 @@
+package testutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/e2e-framework/klient/decoder"
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+	"sigs.k8s.io/e2e-framework/klient/wait"
+	"sigs.k8s.io/e2e-framework/klient/wait/conditions"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+)
+
+const (
+	// ReadinessTimeoutEnvVar sets how long testutils waits for policies, bindings and parameters to become effective
+	// (a Go duration, e.g. 90s)
+	ReadinessTimeoutEnvVar = "VAPLIB_READINESS_TIMEOUT"
+
+	defaultReadinessTimeout = 60 * time.Second
+	readinessPollInterval   = 250 * time.Millisecond
+
+	// sentinelLabel selects the dry-run sentinel ConfigMap of a readiness check
+	sentinelLabel = "vap-library.com/readiness-sentinel"
+	// sentinelNamespace is the namespace of the sentinel ConfigMap, it has no policy labels
+	sentinelNamespace = "default"
+	sentinelMessage   = "readiness sentinel"
+)
+
+// ReadinessTimeout returns the time testutils waits for policies, bindings and parameters to become effective
+func ReadinessTimeout() (time.Duration, error) {
+	value := os.Getenv(ReadinessTimeoutEnvVar)
+	if value == "" {
+		return defaultReadinessTimeout, nil
+	}
+
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout <= 0 {
+		return 0, fmt.Errorf("%s must be a positive duration (e.g. 90s), got %q", ReadinessTimeoutEnvVar, value)
+	}
+	return timeout, nil
+}
+
+// WaitForResources waits until the CustomResourceDefinitions are established, the ValidatingAdmissionPolicies are type
+// checked and the ValidatingAdmissionPolicyBindings are enforced. Other objects are ignored.
+func WaitForResources(ctx context.Context, cfg *envconf.Config, objects []k8s.Object, timeout time.Duration) error {
+	if PolicyEvaluatorFromContext(ctx) != nil {
+		// the in-process evaluator waits for the resources itself
+		return nil
+	}
+
+	hasBinding := false
+	for _, obj := range objects {
+		switch obj.GetObjectKind().GroupVersionKind().Kind {
+		case "CustomResourceDefinition":
+			if err := waitForCRD(ctx, cfg, obj.GetName(), timeout); err != nil {
+				return err
+			}
+		case "ValidatingAdmissionPolicy":
+			if err := WaitForPolicy(ctx, cfg, obj.GetName(), timeout); err != nil {
+				return err
+			}
+			if err := CheckPolicyTypeChecking(ctx, cfg, obj.GetName()); err != nil {
+				return err
+			}
+		case "ValidatingAdmissionPolicyBinding":
+			hasBinding = true
+		}
+	}
+
+	if hasBinding {
+		return WaitForBindings(ctx, cfg, timeout)
+	}
+	return nil
+}
+
+// WaitForPolicy waits until the API server has observed the latest generation of the ValidatingAdmissionPolicy
+func WaitForPolicy(ctx context.Context, cfg *envconf.Config, name string, timeout time.Duration) error {
+	if PolicyEvaluatorFromContext(ctx) != nil {
+		return nil
+	}
+
+	policy := &admissionregistrationv1.ValidatingAdmissionPolicy{}
+	policy.Name = name
+	err := wait.For(
+		conditions.New(cfg.Client().Resources()).ResourceMatch(policy, func(obj k8s.Object) bool {
+			p := obj.(*admissionregistrationv1.ValidatingAdmissionPolicy)
+			return p.Status.ObservedGeneration == p.Generation
+		}),
+		wait.WithContext(ctx), wait.WithTimeout(timeout), wait.WithInterval(readinessPollInterval),
+	)
+	if err != nil {
+		return fmt.Errorf("ValidatingAdmissionPolicy %s was not observed by the API server within %s (generation %d, observed generation %d): %w",
+			name, timeout, policy.Generation, policy.Status.ObservedGeneration, err)
+	}
+	return nil
+}
+
+// WaitForBindings waits until the admission plugin enforces every ValidatingAdmissionPolicyBinding that was created
+// before the call. It creates a sentinel policy and binding that deny a labelled ConfigMap and sends the ConfigMap
+// with server-side dry-run until it is denied. Bindings are delivered to the admission plugin in order, so once the
+// sentinel binding is enforced the earlier bindings are enforced as well.
+func WaitForBindings(ctx context.Context, cfg *envconf.Config, timeout time.Duration) error {
+	if PolicyEvaluatorFromContext(ctx) != nil {
+		return nil
+	}
+
+	s, err := newSentinel(nil)
+	if err != nil {
+		return err
+	}
+	if err := s.create(ctx, cfg); err != nil {
+		return err
+	}
+	defer s.delete(ctx, cfg)
+
+	if err := s.waitForDenial(ctx, cfg, timeout); err != nil {
+		return fmt.Errorf("ValidatingAdmissionPolicyBindings did not become effective within %s: %w", timeout, err)
+	}
+	return nil
+}
+
+// WaitForParameter waits until the admission plugin sees the given policy parameter. It creates a copy of the
+// parameter together with a sentinel policy that uses the parameter kind and sends a ConfigMap with server-side
+// dry-run until the sentinel policy finds the copy and denies it. Parameters of the same kind are delivered to the
+// admission plugin in order, so once the copy is seen the parameter is seen as well.
+func WaitForParameter(ctx context.Context, cfg *envconf.Config, param k8s.Object, timeout time.Duration) error {
+	if PolicyEvaluatorFromContext(ctx) != nil {
+		return nil
+	}
+
+	s, err := newSentinel(param)
+	if err != nil {
+		return err
+	}
+	if err := s.create(ctx, cfg); err != nil {
+		return err
+	}
+	defer s.delete(ctx, cfg)
+
+	if err := s.waitForDenial(ctx, cfg, timeout); err != nil {
+		gvk := param.GetObjectKind().GroupVersionKind()
+		return fmt.Errorf("%s %s/%s did not become effective within %s: %w", gvk.Kind, param.GetNamespace(), param.GetName(), timeout, err)
+	}
+	return nil
+}
+
+// WaitForNamespaceLabels waits until the admission plugin sees the given labels on the namespace. It creates a sentinel
+// policy and binding that deny a labelled ConfigMap in namespaces with the given labels and sends the ConfigMap to the
+// namespace with server-side dry-run until it is denied.
+func WaitForNamespaceLabels(ctx context.Context, cfg *envconf.Config, namespace string, labels map[string]string, timeout time.Duration) error {
+	if PolicyEvaluatorFromContext(ctx) != nil {
+		return nil
+	}
+
+	s, err := newNamespaceSentinel(namespace, labels)
+	if err != nil {
+		return err
+	}
+	if err := s.create(ctx, cfg); err != nil {
+		return err
+	}
+	defer s.delete(ctx, cfg)
+
+	if err := s.waitForDenial(ctx, cfg, timeout); err != nil {
+		return fmt.Errorf("the labels %v of namespace %s did not become effective within %s: %w", labels, namespace, timeout, err)
+	}
+	return nil
+}
+
+// WaitForObject waits until the object can be read from the API server, e.g. a Pod that the following steps of a test
+// read and update. The object is filled with the read object.
+func WaitForObject(ctx context.Context, cfg *envconf.Config, obj k8s.Object, timeout time.Duration) error {
+	if PolicyEvaluatorFromContext(ctx) != nil {
+		// the in-process evaluator stores the object before the request returns
+		return nil
+	}
+
+	err := wait.For(
+		conditions.New(cfg.Client().Resources()).ResourceMatch(obj, func(k8s.Object) bool { return true }),
+		wait.WithContext(ctx), wait.WithTimeout(timeout), wait.WithInterval(readinessPollInterval),
+	)
+	if err != nil {
+		return fmt.Errorf("%s/%s could not be read within %s: %w", obj.GetNamespace(), obj.GetName(), timeout, err)
+	}
+	return nil
+}
+
+// ApplyParameterFromYAML applies a policy parameter from a yaml string and waits until it becomes effective
+func ApplyParameterFromYAML(ctx context.Context, cfg *envconf.Config, yaml string) error {
+	param, err := decoder.DecodeAny(strings.NewReader(yaml))
+	if err != nil {
+		return err
+	}
+	if err := applyK8sResource(ctx, cfg, param); err != nil {
+		return err
+	}
+
+	timeout, err := ReadinessTimeout()
+	if err != nil {
+		return err
+	}
+	return WaitForParameter(ctx, cfg, param, timeout)
+}
+
+// waitForCRD waits until the CustomResourceDefinition is established
+func waitForCRD(ctx context.Context, cfg *envconf.Config, name string, timeout time.Duration) error {
+	crd := &unstructured.Unstructured{}
+	crd.SetAPIVersion("apiextensions.k8s.io/v1")
+	crd.SetKind("CustomResourceDefinition")
+	crd.SetName(name)
+
+	err := wait.For(
+		conditions.New(cfg.Client().Resources()).ResourceMatch(crd, func(obj k8s.Object) bool {
+			crdConditions, _, _ := unstructured.NestedSlice(obj.(*unstructured.Unstructured).Object, "status", "conditions")
+			for _, c := range crdConditions {
+				condition, ok := c.(map[string]interface{})
+				if ok && condition["type"] == "Established" && condition["status"] == "True" {
+					return true
+				}
+			}
+			return false
+		}),
+		wait.WithContext(ctx), wait.WithTimeout(timeout), wait.WithInterval(readinessPollInterval),
+	)
+	if err != nil {
+		return fmt.Errorf("CustomResourceDefinition %s was not established within %s: %w", name, timeout, err)
+	}
+	return nil
+}
+
+// sentinel is a policy and binding (and optionally a parameter) that deny a labelled ConfigMap once they are enforced
+type sentinel struct {
+	id string
+	// namespace is the namespace of the sentinel ConfigMap
+	namespace string
+	policy    *admissionregistrationv1.ValidatingAdmissionPolicy
+	binding   *admissionregistrationv1.ValidatingAdmissionPolicyBinding
+	param     *unstructured.Unstructured
+}
+
+// newSentinel returns a sentinel. If param is set the sentinel policy uses the kind of param and the sentinel binding
+// refers to a copy of param.
+func newSentinel(param k8s.Object) (*sentinel, error) {
+	id := envconf.RandomName("readiness-sentinel", 26)
+	name := id + ".vap-library.com"
+
+	s := &sentinel{id: id, namespace: sentinelNamespace}
+	s.policy = &admissionregistrationv1.ValidatingAdmissionPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: admissionregistrationv1.ValidatingAdmissionPolicySpec{
+			FailurePolicy: ptr.To(admissionregistrationv1.Fail),
+			MatchConstraints: &admissionregistrationv1.MatchResources{
+				ResourceRules: []admissionregistrationv1.NamedRuleWithOperations{{
+					RuleWithOperations: admissionregistrationv1.RuleWithOperations{
+						Operations: []admissionregistrationv1.OperationType{admissionregistrationv1.Create},
+						Rule: admissionregistrationv1.Rule{
+							APIGroups:   []string{""},
+							APIVersions: []string{"v1"},
+							Resources:   []string{"configmaps"},
+						},
+					},
+				}},
+			},
+			Validations: []admissionregistrationv1.Validation{{Expression: "false", Message: sentinelMessage}},
+		},
+	}
+	s.binding = &admissionregistrationv1.ValidatingAdmissionPolicyBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: admissionregistrationv1.ValidatingAdmissionPolicyBindingSpec{
+			PolicyName: name,
+			MatchResources: &admissionregistrationv1.MatchResources{
+				ObjectSelector: &metav1.LabelSelector{MatchLabels: map[string]string{sentinelLabel: id}},
+			},
+			ValidationActions: []admissionregistrationv1.ValidationAction{admissionregistrationv1.Deny},
+		},
+	}
+
+	if param != nil {
+		gvk := param.GetObjectKind().GroupVersionKind()
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(param)
+		if err != nil {
+			return nil, err
+		}
+		s.param = &unstructured.Unstructured{Object: content}
+		s.param.SetGroupVersionKind(gvk)
+		// only keep the identity of the copy from the metadata
+		delete(s.param.Object, "metadata")
+		delete(s.param.Object, "status")
+		s.param.SetName(name)
+		s.param.SetNamespace(param.GetNamespace())
+
+		s.policy.Spec.ParamKind = &admissionregistrationv1.ParamKind{APIVersion: gvk.GroupVersion().String(), Kind: gvk.Kind}
+		s.binding.Spec.ParamRef = &admissionregistrationv1.ParamRef{
+			Name:                    name,
+			Namespace:               param.GetNamespace(),
+			ParameterNotFoundAction: ptr.To(admissionregistrationv1.AllowAction),
+		}
+	}
+
+	return s, nil
+}
+
+// newNamespaceSentinel returns a sentinel whose binding only matches the given namespace if it has the given labels
+func newNamespaceSentinel(namespace string, labels map[string]string) (*sentinel, error) {
+	s, err := newSentinel(nil)
+	if err != nil {
+		return nil, err
+	}
+	s.namespace = namespace
+	s.binding.Spec.MatchResources.NamespaceSelector = &metav1.LabelSelector{MatchLabels: labels}
+	return s, nil
+}
+
+// create creates the sentinel resources. The parameter copy is created last so that the sentinel only denies once the
+// copy is seen.
+func (s *sentinel) create(ctx context.Context, cfg *envconf.Config) error {
+	r := cfg.Client().Resources()
+	if err := r.Create(ctx, s.policy); err != nil {
+		return fmt.Errorf("failed to create the readiness sentinel policy: %w", err)
+	}
+	if err := r.Create(ctx, s.binding); err != nil {
+		return fmt.Errorf("failed to create the readiness sentinel binding: %w", err)
+	}
+	if s.param != nil {
+		if err := r.Create(ctx, s.param); err != nil {
+			return fmt.Errorf("failed to create the readiness sentinel parameter: %w", err)
+		}
+	}
+	return nil
+}
+
+// delete removes the sentinel resources
+func (s *sentinel) delete(ctx context.Context, cfg *envconf.Config) {
+	r := cfg.Client().Resources()
+	objects := []k8s.Object{s.binding, s.policy}
+	if s.param != nil {
+		objects = append(objects, s.param)
+	}
+	for _, obj := range objects {
+		_ = r.Delete(ctx, obj)
+	}
+}
+
+// waitForDenial sends the sentinel ConfigMap with server-side dry-run until the sentinel policy denies it
+func (s *sentinel) waitForDenial(ctx context.Context, cfg *envconf.Config, timeout time.Duration) error {
+	r := cfg.Client().Resources()
+	lastErr := errors.New("the sentinel ConfigMap was admitted")
+
+	err := wait.For(
+		func(ctx context.Context) (bool, error) {
+			cm := &v1.ConfigMap{}
+			cm.Name = s.id
+			cm.Namespace = s.namespace
+			cm.Labels = map[string]string{sentinelLabel: s.id}
+
+			err := r.Create(ctx, cm, WithDryRun)
+			if err == nil {
+				lastErr = errors.New("the sentinel ConfigMap was admitted")
+				return false, nil
+			}
+			if denial, parseErr := ParseDenial(err); parseErr == nil && denial.Policy == s.policy.Name {
+				return true, nil
+			}
+			lastErr = err
+			return false, nil
+		},
+		wait.WithContext(ctx), wait.WithTimeout(timeout), wait.WithInterval(readinessPollInterval),
+	)
+	if err != nil {
+		return fmt.Errorf("%w (last result: %s)", err, lastErr)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package testutils
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"k8s.io/apiserver/pkg/warning"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/e2e-framework/klient/decoder"
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+)
+
+// ApplyK8sResourceFromYAMLWithWarnings applies a k8s resource from a yaml string and returns the warnings that were
+// sent back for the request (e.g. by a binding with the Warn validation action). Like ApplyK8sResourceFromYAML, the
+// request is sent with server-side dry-run by default.
+func ApplyK8sResourceFromYAMLWithWarnings(ctx context.Context, cfg *envconf.Config, yaml string, opts ...resources.CreateOption) ([]string, error) {
+	obj, err := decoder.DecodeAny(strings.NewReader(yaml))
+	if err != nil {
+		return nil, err
+	}
+
+	return applyK8sResourceWithWarnings(ctx, cfg, obj, testObjectOptions(opts)...)
+}
+
+// ExpectWarned fails the test unless one of the warnings contains messageSubstring
+func ExpectWarned(t *testing.T, warnings []string, messageSubstring string) {
+	t.Helper()
+	testReport.recordWarnings(t, ResultWarned, warnings)
+
+	for _, w := range warnings {
+		if strings.Contains(w, messageSubstring) {
+			return
+		}
+	}
+	t.Fatalf("expected a warning containing %q, got: %q", messageSubstring, warnings)
+}
+
+// ExpectNoWarnings fails the test if the request got any warnings
+func ExpectNoWarnings(t *testing.T, warnings []string) {
+	t.Helper()
+	testReport.recordWarnings(t, ResultAllowed, warnings)
+
+	if len(warnings) > 0 {
+		t.Fatalf("expected no warnings, got: %q", warnings)
+	}
+}
+
+// applyK8sResourceWithWarnings creates the object and returns the warnings of the request. On a cluster the warnings
+// are recorded by a warning handler on a copy of the client's rest.Config, so every request gets its own recorder.
+func applyK8sResourceWithWarnings(ctx context.Context, cfg *envconf.Config, obj k8s.Object, opts ...resources.CreateOption) ([]string, error) {
+	testReport.recordObject(obj)
+	recorder := &warningRecorder{}
+
+	if evaluator := PolicyEvaluatorFromContext(ctx); evaluator != nil {
+		err := evaluator.Apply(warning.WithWarningRecorder(ctx, recorder), obj, opts...)
+		if err == nil {
+			trackClusterObject(ctx, cfg, obj, opts)
+		}
+		return recorder.warnings(), err
+	}
+
+	restConfig := rest.CopyConfig(cfg.Client().RESTConfig())
+	restConfig.WarningHandler = recorder
+	r, err := resources.New(restConfig)
+	if err != nil {
+		return nil, err
+	}
+	err = r.Create(ctx, obj, opts...)
+	if err == nil {
+		trackClusterObject(ctx, cfg, obj, opts)
+	}
+	return recorder.warnings(), err
+}
+
+// warningRecorder records the warnings of the requests. It is used both as a client-go warning handler and as an
+// apiserver warning recorder for the in-process backend.
+type warningRecorder struct {
+	mu   sync.Mutex
+	list []string
+}
+
+// HandleWarningHeader implements rest.WarningHandler
+func (w *warningRecorder) HandleWarningHeader(_ int, _ string, text string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.list = append(w.list, text)
+}
+
+// AddWarning implements warning.Recorder
+func (w *warningRecorder) AddWarning(_, text string) {
+	w.HandleWarningHeader(299, "", text)
+}
+
+func (w *warningRecorder) warnings() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]string(nil), w.list...)
+}
@@ -0,0 +1,208 @@
+package testutils
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+	"sigs.k8s.io/e2e-framework/pkg/env"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/features"
+	sigsyaml "sigs.k8s.io/yaml"
+)
+
+// WorkloadKinds are the kinds that wrap a PodSpec, in the order Workloads returns them
+var WorkloadKinds = []string{"Pod", "Deployment", "ReplicaSet", "DaemonSet", "StatefulSet", "Job", "CronJob", "ReplicationController", "PodTemplate"}
+
+// Workloads wraps the PodSpec into an object of every kind of WorkloadKinds, at the path where the kind keeps its
+// PodSpec (spec, spec.template.spec, spec.jobTemplate.spec.template.spec or template.spec). The objects are named
+// <name>-<kind in lower case> and their selectors and template labels match. The restartPolicy is set to Never for
+// Jobs and CronJobs and to Always for the other controllers if the PodSpec sets a value the kind does not accept.
+func Workloads(name string, namespace string, podSpec corev1.PodSpec) []k8s.Object {
+	labels := map[string]string{"app": name}
+	meta := func(kind string) metav1.ObjectMeta {
+		return metav1.ObjectMeta{Name: name + "-" + strings.ToLower(kind), Namespace: namespace, Labels: labels}
+	}
+	template := func(restartPolicies ...corev1.RestartPolicy) corev1.PodTemplateSpec {
+		spec := *podSpec.DeepCopy()
+		if !containsRestartPolicy(restartPolicies, spec.RestartPolicy) {
+			spec.RestartPolicy = restartPolicies[0]
+		}
+		return corev1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: labels}, Spec: spec}
+	}
+	controllerTemplate := func() corev1.PodTemplateSpec {
+		return template(corev1.RestartPolicyAlways, "")
+	}
+	jobTemplate := func() corev1.PodTemplateSpec {
+		return template(corev1.RestartPolicyNever, corev1.RestartPolicyOnFailure)
+	}
+	selector := &metav1.LabelSelector{MatchLabels: labels}
+	replicas := int32(1)
+	rcTemplate := controllerTemplate()
+
+	return []k8s.Object{
+		&corev1.Pod{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+			ObjectMeta: meta("Pod"),
+			Spec:       *podSpec.DeepCopy(),
+		},
+		&appsv1.Deployment{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+			ObjectMeta: meta("Deployment"),
+			Spec:       appsv1.DeploymentSpec{Replicas: &replicas, Selector: selector, Template: controllerTemplate()},
+		},
+		&appsv1.ReplicaSet{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "ReplicaSet"},
+			ObjectMeta: meta("ReplicaSet"),
+			Spec:       appsv1.ReplicaSetSpec{Replicas: &replicas, Selector: selector, Template: controllerTemplate()},
+		},
+		&appsv1.DaemonSet{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "DaemonSet"},
+			ObjectMeta: meta("DaemonSet"),
+			Spec:       appsv1.DaemonSetSpec{Selector: selector, Template: controllerTemplate()},
+		},
+		&appsv1.StatefulSet{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "StatefulSet"},
+			ObjectMeta: meta("StatefulSet"),
+			Spec:       appsv1.StatefulSetSpec{Replicas: &replicas, Selector: selector, ServiceName: name, Template: controllerTemplate()},
+		},
+		&batchv1.Job{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "batch/v1", Kind: "Job"},
+			ObjectMeta: meta("Job"),
+			Spec:       batchv1.JobSpec{Template: jobTemplate()},
+		},
+		&batchv1.CronJob{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "batch/v1", Kind: "CronJob"},
+			ObjectMeta: meta("CronJob"),
+			Spec: batchv1.CronJobSpec{
+				Schedule:    "* * * * *",
+				JobTemplate: batchv1.JobTemplateSpec{Spec: batchv1.JobSpec{Template: jobTemplate()}},
+			},
+		},
+		&corev1.ReplicationController{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ReplicationController"},
+			ObjectMeta: meta("ReplicationController"),
+			Spec: corev1.ReplicationControllerSpec{
+				Replicas: &replicas,
+				Selector: labels,
+				Template: &rcTemplate,
+			},
+		},
+		&corev1.PodTemplate{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "PodTemplate"},
+			ObjectMeta: meta("PodTemplate"),
+			Template:   template(corev1.RestartPolicyAlways, "", corev1.RestartPolicyNever, corev1.RestartPolicyOnFailure),
+		},
+	}
+}
+
+// WorkloadsFromYAML is like Workloads with the PodSpec as a yaml string
+func WorkloadsFromYAML(name string, namespace string, podSpecYAML string) ([]k8s.Object, error) {
+	podSpec := corev1.PodSpec{}
+	if err := sigsyaml.UnmarshalStrict([]byte(podSpecYAML), &podSpec); err != nil {
+		return nil, fmt.Errorf("failed to decode the PodSpec: %w", err)
+	}
+	return Workloads(name, namespace, podSpec), nil
+}
+
+// WorkloadMessages returns the expected messages per kind of the policies that have one validation for Pods, one for
+// the controllers with spec.template, one for CronJobs and one for PodTemplates, like the PSS policies
+func WorkloadMessages(pods, workloads, cronJobs, podTemplates string) map[string]string {
+	return map[string]string{
+		"Pod":                   pods,
+		"Deployment":            workloads,
+		"ReplicaSet":            workloads,
+		"DaemonSet":             workloads,
+		"StatefulSet":           workloads,
+		"Job":                   workloads,
+		"CronJob":               cronJobs,
+		"ReplicationController": workloads,
+		"PodTemplate":           podTemplates,
+	}
+}
+
+// WorkloadTestCase is a PodSpec that is tested wrapped into every kind of WorkloadKinds, every kind must have the
+// same result
+type WorkloadTestCase struct {
+	// Name is the name of the test case, it is also used as the name prefix of the objects
+	Name string
+	// PodSpec is the PodSpec as a yaml string
+	PodSpec string
+	// Expect is the expected outcome for every kind
+	Expect Expectation
+	// Messages overrides Expect.Message per kind, see WorkloadMessages
+	Messages map[string]string
+}
+
+// RunWorkloadTestCases runs every test case as a separate e2e feature. Every test case gets its own namespace.
+func RunWorkloadTestCases(t *testing.T, testEnv env.Environment, testCases ...WorkloadTestCase) {
+	// every call of testEnv.Test creates a new namespace
+	for _, tc := range testCases {
+		_ = testEnv.Test(t, tc.Feature())
+	}
+}
+
+// Feature turns the test case into an e2e feature with one assessment per kind
+func (tc WorkloadTestCase) Feature() features.Feature {
+	f := features.New(tc.Name)
+	for i, kind := range WorkloadKinds {
+		f = f.Assess(fmt.Sprintf("A %s is %s", kind, tc.Expect.Result), func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+			// get namespace
+			namespace := ctx.Value(GetNamespaceKey(t)).(string)
+
+			workloads, err := WorkloadsFromYAML(workloadName(tc.Name), namespace, tc.PodSpec)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expect := tc.Expect
+			if message, ok := tc.Messages[kind]; ok {
+				expect.Message = message
+			}
+
+			warnings, err := applyK8sResourceWithWarnings(ctx, cfg, workloads[i], testObjectOptions(nil)...)
+			expectResult(t, expect, err, warnings)
+
+			return ctx
+		})
+	}
+	return f.Feature()
+}
+
+// workloadName turns the name of a test case into a valid object name
+func workloadName(name string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			sb.WriteRune(r)
+		case sb.Len() > 0 && !strings.HasSuffix(sb.String(), "-"):
+			sb.WriteRune('-')
+		}
+	}
+	result := strings.TrimSuffix(sb.String(), "-")
+	// leave room for the kind suffix
+	if len(result) > 40 {
+		result = strings.TrimSuffix(result[:40], "-")
+	}
+	if result == "" {
+		result = "workload"
+	}
+	return result
+}
+
+// containsRestartPolicy returns true if the restart policy is in the list
+func containsRestartPolicy(restartPolicies []corev1.RestartPolicy, restartPolicy corev1.RestartPolicy) bool {
+	for _, p := range restartPolicies {
+		if p == restartPolicy {
+			return true
+		}
+	}
+	return false
+}